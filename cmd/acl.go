@@ -0,0 +1,52 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/massdriver-cloud/cola/pkg/cidr"
+	"github.com/spf13/cobra"
+)
+
+var aclBase string
+var aclUsed []string
+var aclUsedFile string
+
+var aclCmd = &cobra.Command{
+	Use:   "acl",
+	Short: "Print the fewest allow and deny entries covering a base CIDR",
+	Long: `Print the fewest CIDRs to allow, covering the used CIDRs, and to deny, covering the free space
+of the base CIDR, as a tight ruleset for a firewall or router ACL. Each line is "allow" or "deny"
+and a CIDR, allow entries first.`,
+	RunE: runACL,
+}
+
+func init() {
+	rootCmd.AddCommand(aclCmd)
+
+	aclCmd.Flags().StringVar(&aclBase, "base", "", "Base CIDR the used CIDRs are allocated from")
+	aclCmd.Flags().StringArrayVar(&aclUsed, "used", []string{}, "Used CIDRs (repeatable, separated by commas, spaces or newlines)")
+	aclCmd.Flags().StringVar(&aclUsedFile, "used-file", "", "File of used CIDRs, one per line")
+}
+
+func runACL(cmd *cobra.Command, args []string) error {
+	base, err := parseBaseCIDR(cmd, aclBase)
+	if err != nil {
+		return err
+	}
+	used, err := parseUsedCIDRs(cmd, aclUsed, aclUsedFile)
+	if err != nil {
+		return err
+	}
+
+	allow, deny, err := cidr.ACLEntries(base, used)
+	if err != nil {
+		return err
+	}
+	for _, n := range allow {
+		fmt.Fprintf(cmd.OutOrStdout(), "allow\t%s\n", n.String())
+	}
+	for _, n := range deny {
+		fmt.Fprintf(cmd.OutOrStdout(), "deny\t%s\n", n.String())
+	}
+	return nil
+}
@@ -0,0 +1,16 @@
+package cmd
+
+import (
+	"testing"
+)
+
+func TestACL(t *testing.T) {
+	got, err := executeCommand("acl", "--base", "10.0.0.0/21", "--used", "10.0.0.0/24,10.0.1.0/24,10.0.4.0/23")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err.Error())
+	}
+	want := "allow\t10.0.0.0/23\nallow\t10.0.4.0/23\ndeny\t10.0.2.0/23\ndeny\t10.0.6.0/23\n"
+	if got != want {
+		t.Fatalf("want: %v, got: %v", want, got)
+	}
+}
@@ -0,0 +1,61 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/massdriver-cloud/cola/pkg/cidr"
+	"github.com/spf13/cobra"
+)
+
+var aggregateUsed []string
+var aggregateUsedFile string
+
+var aggregateCmd = &cobra.Command{
+	Use:   "aggregate",
+	Short: "Merge CIDRs into the fewest CIDRs covering the same addresses",
+	Long: `Merge overlapping and adjacent CIDRs into the fewest CIDRs covering the same addresses, for
+route summarization.
+
+Metadata in --used-file, either an inline comment (10.0.0.0/24 # web) or the remaining columns
+of a CSV line (10.0.0.0/24,web), is printed after each CIDR. A CIDR which isn't merged keeps its
+metadata, and a merged CIDR lists the metadata of everything merged into it.`,
+	RunE: runAggregate,
+}
+
+func init() {
+	rootCmd.AddCommand(aggregateCmd)
+
+	aggregateCmd.Flags().StringArrayVar(&aggregateUsed, "used", []string{}, "CIDRs to merge (repeatable, separated by commas, spaces or newlines)")
+	aggregateCmd.Flags().StringVar(&aggregateUsedFile, "used-file", "", "File of CIDRs to merge, one per line")
+}
+
+func runAggregate(cmd *cobra.Command, args []string) error {
+	used, err := parseCIDRList(configStringSlice(cmd, "used", aggregateUsed))
+	if err != nil {
+		return err
+	}
+	cidrs := make([]cidr.CIDRWithMeta, 0, len(used))
+	for _, n := range used {
+		cidrs = append(cidrs, cidr.CIDRWithMeta{CIDR: n})
+	}
+	if aggregateUsedFile != "" {
+		fileCIDRs, fileErr := readCIDRFileWithMeta(aggregateUsedFile)
+		if fileErr != nil {
+			return fileErr
+		}
+		cidrs = append(cidrs, fileCIDRs...)
+	}
+
+	aggregated, err := cidr.AggregateWithMeta(cidrs)
+	if err != nil {
+		return err
+	}
+	for _, n := range aggregated {
+		if n.Meta == "" {
+			fmt.Fprintln(cmd.OutOrStdout(), n.CIDR.String())
+			continue
+		}
+		fmt.Fprintf(cmd.OutOrStdout(), "%s\t%s\n", n.CIDR.String(), n.Meta)
+	}
+	return nil
+}
@@ -0,0 +1,32 @@
+package cmd
+
+import (
+	"testing"
+)
+
+func TestAggregate(t *testing.T) {
+	got, err := executeCommand("aggregate", "--used-file", "testdata/aggregate.txt", "--used", "10.0.9.0/24")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err.Error())
+	}
+	want := "10.0.0.0/23\tweb, platform + api\n10.0.4.0/24\tbatch\n10.0.8.0/23\n"
+	if got != want {
+		t.Fatalf("want: %v, got: %v", want, got)
+	}
+}
+
+func TestReadCIDRFileIgnoresMeta(t *testing.T) {
+	got, err := readCIDRFile("testdata/aggregate.txt")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err.Error())
+	}
+	want := []string{"10.0.0.0/24", "10.0.1.0/24", "10.0.4.0/24", "10.0.8.0/24"}
+	if len(got) != len(want) {
+		t.Fatalf("want: %v, got: %v", want, got)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("want: %v, got: %v", want[i], got[i])
+		}
+	}
+}
@@ -0,0 +1,30 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"net"
+
+	"github.com/massdriver-cloud/cola/pkg/awsvpc"
+)
+
+// newAWSClient builds a client for reading VPCs in a region. It's only set when cola is built with
+// the aws build tag, so the AWS SDK isn't a dependency of the default build.
+var newAWSClient func(ctx context.Context, region string) (awsvpc.Client, error)
+
+var errNoAWSSupport = errors.New("cola was built without AWS support, rebuild with -tags aws")
+
+// loadAWSVPC returns the primary CIDR block of the VPC and the CIDR blocks of its subnets
+func loadAWSVPC(ctx context.Context, region string, vpcID string) (*net.IPNet, []*net.IPNet, error) {
+	if newAWSClient == nil {
+		return nil, nil, invalidInput(errNoAWSSupport)
+	}
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	client, err := newAWSClient(ctx, region)
+	if err != nil {
+		return nil, nil, err
+	}
+	return awsvpc.LoadVPC(ctx, client, vpcID)
+}
@@ -0,0 +1,10 @@
+//go:build aws
+// +build aws
+
+package cmd
+
+import "github.com/massdriver-cloud/cola/pkg/awsvpc"
+
+func init() {
+	newAWSClient = awsvpc.NewClient
+}
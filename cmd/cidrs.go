@@ -0,0 +1,137 @@
+package cmd
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"strings"
+
+	"github.com/massdriver-cloud/cola/pkg/cidr"
+)
+
+// parseCIDRs parses each value as a CIDR, normalizing away any host bits.
+func parseCIDRs(values []string) ([]*net.IPNet, error) {
+	cidrs := make([]*net.IPNet, 0, len(values))
+	for _, value := range values {
+		_, n, err := net.ParseCIDR(strings.TrimSpace(value))
+		if err != nil {
+			return nil, invalidInput(fmt.Errorf("invalid CIDR %q: %w", value, err))
+		}
+		cidrs = append(cidrs, n)
+	}
+	return cidrs, nil
+}
+
+// parseRawCIDRs parses each value as a CIDR, keeping any host bits so they can be reported.
+func parseRawCIDRs(values []string) ([]*net.IPNet, error) {
+	cidrs := make([]*net.IPNet, 0, len(values))
+	for _, value := range values {
+		ip, n, err := net.ParseCIDR(strings.TrimSpace(value))
+		if err != nil {
+			return nil, invalidInput(fmt.Errorf("invalid CIDR %q: %w", value, err))
+		}
+		if len(n.IP) == net.IPv4len {
+			ip = ip.To4()
+		}
+		cidrs = append(cidrs, &net.IPNet{IP: ip, Mask: n.Mask})
+	}
+	return cidrs, nil
+}
+
+// parseCIDRList parses the values of a multi-CIDR flag, each of which can hold several CIDRs
+// separated by commas, whitespace or newlines (see cidr.ParseCIDRList), normalizing away any host bits.
+func parseCIDRList(values []string) ([]*net.IPNet, error) {
+	cidrs := make([]*net.IPNet, 0, len(values))
+	for _, value := range values {
+		parsed, err := cidr.ParseCIDRList(value)
+		if err != nil {
+			return nil, invalidInput(err)
+		}
+		cidrs = append(cidrs, parsed...)
+	}
+	return cidrs, nil
+}
+
+// parseRawCIDRList is parseCIDRList keeping any host bits, so they can be reported.
+func parseRawCIDRList(values []string) ([]*net.IPNet, error) {
+	entries := []string{}
+	for _, value := range values {
+		entries = append(entries, cidr.SplitCIDRList(value)...)
+	}
+	return parseRawCIDRs(entries)
+}
+
+// readCIDRFile reads CIDRs from a file with one CIDR per line. Blank lines and lines starting with #
+// are ignored, as is any metadata after the CIDR (see readCIDRFileWithMeta).
+func readCIDRFile(path string) ([]string, error) {
+	lines, err := readCIDRLines(path)
+	if err != nil {
+		return nil, err
+	}
+	values := make([]string, len(lines))
+	for i, line := range lines {
+		values[i], _ = splitCIDRLine(line)
+	}
+	return values, nil
+}
+
+// readCIDRFileWithMeta reads CIDRs and their metadata from a file with one CIDR per line. The metadata
+// is either an inline comment (10.0.0.0/24 # web) or the remaining columns of a CSV line
+// (10.0.0.0/24,web,platform).
+func readCIDRFileWithMeta(path string) ([]cidr.CIDRWithMeta, error) {
+	lines, err := readCIDRLines(path)
+	if err != nil {
+		return nil, err
+	}
+	cidrs := make([]cidr.CIDRWithMeta, 0, len(lines))
+	for _, line := range lines {
+		value, meta := splitCIDRLine(line)
+		parsed, parseErr := parseCIDRs([]string{value})
+		if parseErr != nil {
+			return nil, parseErr
+		}
+		cidrs = append(cidrs, cidr.CIDRWithMeta{CIDR: parsed[0], Meta: meta})
+	}
+	return cidrs, nil
+}
+
+// readCIDRLines returns the lines of a file, skipping blank lines and lines starting with #
+func readCIDRLines(path string) ([]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, invalidInput(err)
+	}
+	lines := []string{}
+	for _, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		lines = append(lines, line)
+	}
+	return lines, nil
+}
+
+// splitCIDRLine splits a line of a CIDR file into the CIDR and its metadata
+func splitCIDRLine(line string) (string, string) {
+	if i := strings.Index(line, "#"); i >= 0 {
+		return strings.TrimSpace(line[:i]), strings.TrimSpace(line[i+1:])
+	}
+	columns := strings.Split(line, ",")
+	meta := []string{}
+	for _, column := range columns[1:] {
+		if column = strings.TrimSpace(column); column != "" {
+			meta = append(meta, column)
+		}
+	}
+	return strings.TrimSpace(columns[0]), strings.Join(meta, ", ")
+}
+
+// joinCIDRs formats the CIDRs as a comma separated list
+func joinCIDRs(cidrs []*net.IPNet) string {
+	values := make([]string, len(cidrs))
+	for i, n := range cidrs {
+		values[i] = n.String()
+	}
+	return strings.Join(values, ", ")
+}
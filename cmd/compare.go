@@ -0,0 +1,72 @@
+package cmd
+
+import (
+	"fmt"
+	"net"
+
+	"github.com/massdriver-cloud/cola/pkg/cidr"
+	"github.com/spf13/cobra"
+)
+
+var compareBase string
+var comparePlan string
+var compareUsed []string
+var compareUsedFile string
+
+var compareCmd = &cobra.Command{
+	Use:   "compare",
+	Short: "Compare allocation strategies by replaying a plan",
+	Long: `Replay the subnets of a plan against a base CIDR with each allocation strategy, and report
+how each one leaves the base:
+
+  first-fit  each subnet at the lowest available address
+  best-fit   each subnet in the smallest free block it fits in
+  spread     each subnet in the largest free block
+
+The plan is the vars file written by plan --output ansible, and only the size of each subnet is
+used, in the order they're listed. The fragmentation is 1 minus the largest free block over the
+free addresses, so lower is better.`,
+	RunE: runCompare,
+}
+
+func init() {
+	rootCmd.AddCommand(compareCmd)
+
+	compareCmd.Flags().StringVar(&compareBase, "base", "", "Base CIDR to allocate from")
+	compareCmd.Flags().StringVar(&comparePlan, "plan", "", "Plan file written by plan --output ansible")
+	compareCmd.Flags().StringArrayVar(&compareUsed, "used", []string{}, "Used CIDRs (repeatable, separated by commas, spaces or newlines)")
+	compareCmd.Flags().StringVar(&compareUsedFile, "used-file", "", "File of used CIDRs, one per line")
+	_ = compareCmd.MarkFlagRequired("plan")
+}
+
+func runCompare(cmd *cobra.Command, args []string) error {
+	base, err := parseBaseCIDR(cmd, compareBase)
+	if err != nil {
+		return err
+	}
+	used, err := parseUsedCIDRs(cmd, compareUsed, compareUsedFile)
+	if err != nil {
+		return err
+	}
+	planned, _, err := readPlanFile(comparePlan)
+	if err != nil {
+		return err
+	}
+	requests := make([]*net.IPMask, len(planned))
+	for i, n := range planned {
+		requests[i] = &n.Mask
+	}
+
+	strategies := []cidr.Strategy{cidr.FirstFit, cidr.BestFit, cidr.Spread}
+	results := cidr.CompareStrategies(base, requests, strategies, used)
+	fmt.Fprintln(cmd.OutOrStdout(), "strategy\tfragmentation\tlargest\tfailures")
+	for _, strategy := range strategies {
+		result := results[strategy]
+		largest := "-"
+		if result.LargestBlock != nil {
+			largest = result.LargestBlock.String()
+		}
+		fmt.Fprintf(cmd.OutOrStdout(), "%s\t%.2f\t%s\t%d\n", strategy, result.Fragmentation, largest, result.Failures)
+	}
+	return nil
+}
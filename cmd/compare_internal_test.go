@@ -0,0 +1,21 @@
+package cmd
+
+import (
+	"testing"
+)
+
+func TestCompare(t *testing.T) {
+	got, err := executeCommand("compare", "--base", "10.0.0.0/24", "--used", "10.0.0.64/26,10.0.0.128/26,10.0.0.224/27", "--plan", "testdata/compare_plan.yaml")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	want := `strategy	fragmentation	largest	failures
+first-fit	0.50	10.0.0.32/27	1
+best-fit	0.00	-	0
+spread	0.50	10.0.0.32/27	1
+`
+	if got != want {
+		t.Fatalf("want: %v, got: %v", want, got)
+	}
+}
@@ -0,0 +1,85 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+var errBaseRequired = errors.New(`required flag "base" not set, set --base, COLA_BASE or base in the config file`)
+
+// configString returns the value of the flag if it was set on the command line, and otherwise the
+// value of the same key from the environment (COLA_<KEY>) or the config file, in that order.
+func configString(cmd *cobra.Command, flag string, value string) string {
+	if cmd.Flags().Changed(flag) {
+		return value
+	}
+	if configured := viper.GetString(flag); configured != "" {
+		return configured
+	}
+	return value
+}
+
+// configStringSlice is configString for repeatable flags. Values from the environment can be comma
+// or space separated.
+func configStringSlice(cmd *cobra.Command, flag string, values []string) []string {
+	if cmd.Flags().Changed(flag) {
+		return values
+	}
+	configured := []string{}
+	for _, value := range viper.GetStringSlice(flag) {
+		for _, part := range strings.Split(value, ",") {
+			if part = strings.TrimSpace(part); part != "" {
+				configured = append(configured, part)
+			}
+		}
+	}
+	if len(configured) > 0 {
+		return configured
+	}
+	return values
+}
+
+// parseBaseCIDR parses the --base flag of the command, falling back to COLA_BASE or the config file.
+func parseBaseCIDR(cmd *cobra.Command, value string) (*net.IPNet, error) {
+	value = configString(cmd, "base", value)
+	if value == "" {
+		return nil, invalidInput(errBaseRequired)
+	}
+	_, base, err := net.ParseCIDR(value)
+	if err != nil {
+		return nil, invalidInput(fmt.Errorf("invalid base CIDR %q: %w", value, err))
+	}
+	return base, nil
+}
+
+// parseRawBaseCIDR is parseBaseCIDR keeping any host bits set in the base, so they can be reported.
+func parseRawBaseCIDR(cmd *cobra.Command, value string) (*net.IPNet, error) {
+	value = configString(cmd, "base", value)
+	if value == "" {
+		return nil, invalidInput(errBaseRequired)
+	}
+	base, err := parseRawCIDRs([]string{value})
+	if err != nil {
+		return nil, err
+	}
+	return base[0], nil
+}
+
+// parseUsedCIDRs parses the --used flag of the command, falling back to COLA_USED or the config file,
+// along with the CIDRs listed in usedFile if it's set.
+func parseUsedCIDRs(cmd *cobra.Command, values []string, usedFile string) ([]*net.IPNet, error) {
+	values = append([]string{}, configStringSlice(cmd, "used", values)...)
+	if usedFile != "" {
+		fileValues, err := readCIDRFile(usedFile)
+		if err != nil {
+			return nil, err
+		}
+		values = append(values, fileValues...)
+	}
+	return parseCIDRList(values)
+}
@@ -0,0 +1,66 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"net"
+
+	"github.com/massdriver-cloud/cola/pkg/cidr"
+	"github.com/spf13/cobra"
+)
+
+var drainBase string
+var drainMask int
+var drainUsed []string
+var drainUsedFile string
+
+var drainCmd = &cobra.Command{
+	Use:   "drain",
+	Short: "Allocate CIDRs of one size until the base is exhausted",
+	Long: `Repeatedly allocate --mask sized CIDRs from the base, printing each one, until no more are
+available, then print how many were allocated. This answers "how many /24s fit here" by
+enumeration, as a cross-check of the count report computes from the free space.`,
+	RunE: runDrain,
+}
+
+func init() {
+	rootCmd.AddCommand(drainCmd)
+
+	drainCmd.Flags().StringVar(&drainBase, "base", "", "Base CIDR to allocate from")
+	drainCmd.Flags().IntVar(&drainMask, "mask", 0, "Prefix length of the CIDRs to allocate")
+	drainCmd.Flags().StringArrayVar(&drainUsed, "used", []string{}, "Used CIDRs (repeatable, separated by commas, spaces or newlines)")
+	drainCmd.Flags().StringVar(&drainUsedFile, "used-file", "", "File of used CIDRs, one per line")
+	_ = drainCmd.MarkFlagRequired("mask")
+}
+
+func runDrain(cmd *cobra.Command, args []string) error {
+	base, err := parseBaseCIDR(cmd, drainBase)
+	if err != nil {
+		return err
+	}
+	used, err := parseUsedCIDRs(cmd, drainUsed, drainUsedFile)
+	if err != nil {
+		return err
+	}
+	_, bits := base.Mask.Size()
+	mask := net.CIDRMask(drainMask, bits)
+	if mask == nil {
+		return invalidInput(fmt.Errorf("invalid mask /%d for base %s", drainMask, base.String()))
+	}
+
+	allocator := cidr.NewAllocator(base, used)
+	count := 0
+	for {
+		reserved, reserveErr := allocator.Reserve(&mask)
+		if errors.Is(reserveErr, cidr.ErrNoAvailableCIDR) {
+			break
+		}
+		if reserveErr != nil {
+			return reserveErr
+		}
+		fmt.Fprintln(cmd.OutOrStdout(), reserved.String())
+		count++
+	}
+	fmt.Fprintf(cmd.OutOrStdout(), "%d allocated\n", count)
+	return nil
+}
@@ -0,0 +1,48 @@
+package cmd
+
+import (
+	"net"
+	"strings"
+	"testing"
+
+	"github.com/massdriver-cloud/cola/pkg/cidr"
+)
+
+func TestDrain(t *testing.T) {
+	got, err := executeCommand("drain", "--base", "10.0.0.0/22", "--mask", "25", "--used-file", "testdata/drain.txt")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	want := `10.0.0.0/25
+10.0.0.128/25
+10.0.1.128/25
+10.0.2.0/25
+10.0.2.128/25
+10.0.3.0/25
+6 allocated
+`
+	if got != want {
+		t.Fatalf("want: %v, got: %v", want, got)
+	}
+
+	_, base, _ := net.ParseCIDR("10.0.0.0/22")
+	_, used1, _ := net.ParseCIDR("10.0.1.0/25")
+	_, used2, _ := net.ParseCIDR("10.0.3.128/26")
+	mask := net.CIDRMask(25, 32)
+	remaining, err := cidr.RemainingCapacity(base, &mask, []*net.IPNet{used1, used2})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	lines := strings.Split(strings.TrimSpace(got), "\n")
+	if drained := int64(len(lines) - 1); drained != remaining.Int64() {
+		t.Fatalf("want: %v, got: %v", remaining, drained)
+	}
+}
+
+func TestDrainInvalidMask(t *testing.T) {
+	_, err := executeCommand("drain", "--base", "10.0.0.0/22", "--mask", "33")
+	if ExitCode(err) != ExitInvalidInput {
+		t.Fatalf("want: %v, got: %v", ExitInvalidInput, ExitCode(err))
+	}
+}
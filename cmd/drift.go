@@ -0,0 +1,97 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"os"
+
+	"github.com/massdriver-cloud/cola/pkg/cidr"
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+)
+
+var driftExpected string
+var driftActualFile string
+
+var driftCmd = &cobra.Command{
+	Use:   "drift",
+	Short: "Compare a committed plan against the CIDRs in use",
+	Long: `Compare a committed address plan against the CIDRs actually in use, and report the drift:
+
+  missing     planned subnets with nothing in use at their address
+  unexpected  CIDRs in use which aren't in the plan
+  resized     planned subnets in use with a different size
+
+The plan is the vars file written by plan --output ansible, and the actual CIDRs are read from
+a file with one CIDR per line. Exits with code 5 if there is any drift.`,
+	RunE: runDrift,
+}
+
+func init() {
+	rootCmd.AddCommand(driftCmd)
+
+	driftCmd.Flags().StringVar(&driftExpected, "expected", "", "Plan file written by plan --output ansible")
+	driftCmd.Flags().StringVar(&driftActualFile, "actual-file", "", "File of the CIDRs in use, one per line")
+	_ = driftCmd.MarkFlagRequired("expected")
+	_ = driftCmd.MarkFlagRequired("actual-file")
+}
+
+func runDrift(cmd *cobra.Command, args []string) error {
+	expected, names, err := readPlanFile(driftExpected)
+	if err != nil {
+		return err
+	}
+	actualValues, err := readCIDRFile(driftActualFile)
+	if err != nil {
+		return err
+	}
+	actual, err := parseCIDRs(actualValues)
+	if err != nil {
+		return err
+	}
+
+	diff := cidr.Diff(expected, actual)
+	for _, n := range diff.Missing {
+		fmt.Fprintf(cmd.OutOrStdout(), "missing\t%s\t%s\n", names[cidr.CanonicalKey(n)], n.String())
+	}
+	for _, n := range diff.Unexpected {
+		fmt.Fprintf(cmd.OutOrStdout(), "unexpected\t\t%s\n", n.String())
+	}
+	for _, r := range diff.Resized {
+		fmt.Fprintf(cmd.OutOrStdout(), "resized\t%s\t%s is %s\n", names[cidr.CanonicalKey(r.Expected)], r.Expected.String(), r.Actual.String())
+	}
+	if diff.Empty() {
+		fmt.Fprintln(cmd.OutOrStdout(), "no drift")
+		return nil
+	}
+	return withExitCode(ExitDrift, fmt.Errorf("found %d differences from the plan", len(diff.Missing)+len(diff.Unexpected)+len(diff.Resized)))
+}
+
+// readPlanFile reads the subnets from an Ansible vars file written by plan, returning the CIDRs and
+// the subnet names by canonical key
+func readPlanFile(path string) ([]*net.IPNet, map[string]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, nil, invalidInput(err)
+	}
+	var vars ansibleVars
+	if unmarshalErr := yaml.Unmarshal(data, &vars); unmarshalErr != nil {
+		return nil, nil, invalidInput(fmt.Errorf("invalid plan file %q: %w", path, unmarshalErr))
+	}
+	if len(vars.Subnets) == 0 {
+		return nil, nil, invalidInput(errors.New("the plan file has no subnets"))
+	}
+
+	cidrs := make([]*net.IPNet, 0, len(vars.Subnets))
+	names := make(map[string]string, len(vars.Subnets))
+	for _, subnet := range vars.Subnets {
+		_, n, parseErr := net.ParseCIDR(subnet.CIDR)
+		if parseErr != nil {
+			return nil, nil, invalidInput(fmt.Errorf("invalid CIDR %q for subnet %q: %w", subnet.CIDR, subnet.Name, parseErr))
+		}
+		cidrs = append(cidrs, n)
+		names[cidr.CanonicalKey(n)] = subnet.Name
+	}
+	return cidrs, names, nil
+}
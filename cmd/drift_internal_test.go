@@ -0,0 +1,27 @@
+package cmd
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestDrift(t *testing.T) {
+	got, err := executeCommand("drift", "--expected", "testdata/drift_plan.yaml", "--actual-file", "testdata/drift_live.txt")
+	if ExitCode(err) != ExitDrift {
+		t.Fatalf("want: %v, got: %v (%v)", ExitDrift, ExitCode(err), err)
+	}
+	want := "missing\tcache\t10.0.2.0/26\nunexpected\t\t10.0.9.0/24\nresized\tdata\t10.0.4.0/22 is 10.0.4.0/23\n"
+	if !strings.HasPrefix(got, want) {
+		t.Fatalf("want: %v, got: %v", want, got)
+	}
+}
+
+func TestDriftNone(t *testing.T) {
+	got, err := executeCommand("drift", "--expected", "testdata/drift_plan.yaml", "--actual-file", "testdata/drift_matching.txt")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err.Error())
+	}
+	if got != "no drift\n" {
+		t.Fatalf("want: %v, got: %v", "no drift\n", got)
+	}
+}
@@ -0,0 +1,94 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/massdriver-cloud/cola/pkg/cidr"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+// Exit codes returned by cola, so scripts can branch on the kind of failure without parsing the output.
+const (
+	ExitSuccess          = 0
+	ExitNoAvailableCIDR  = 1
+	ExitInvalidInput     = 2
+	ExitOverlappingCIDRs = 3
+	ExitInternalError    = 4
+	ExitDrift            = 5
+	ExitLowCapacity      = 6
+)
+
+// exitCodeError attaches an explicit exit code to an error
+type exitCodeError struct {
+	code int
+	err  error
+}
+
+func (e *exitCodeError) Error() string {
+	return e.err.Error()
+}
+
+func (e *exitCodeError) Unwrap() error {
+	return e.err
+}
+
+// withExitCode returns err with the exit code attached, or nil if err is nil
+func withExitCode(code int, err error) error {
+	if err == nil {
+		return nil
+	}
+	return &exitCodeError{code: code, err: err}
+}
+
+// invalidInput marks err as a problem with the command line input
+func invalidInput(err error) error {
+	return withExitCode(ExitInvalidInput, err)
+}
+
+// exactArgs is cobra.ExactArgs with the error marked as invalid input
+func exactArgs(n int) cobra.PositionalArgs {
+	return func(cmd *cobra.Command, args []string) error {
+		return invalidInput(cobra.ExactArgs(n)(cmd, args))
+	}
+}
+
+// checkRequiredFlags returns an error naming any flag marked as required which wasn't set. Cobra runs
+// the same check itself, but its error bypasses the flag error func, so it's run here first to exit
+// with ExitInvalidInput.
+func checkRequiredFlags(cmd *cobra.Command) error {
+	missing := []string{}
+	cmd.Flags().VisitAll(func(f *pflag.Flag) {
+		if required, ok := f.Annotations[cobra.BashCompOneRequiredFlag]; ok && required[0] == "true" && !f.Changed {
+			missing = append(missing, f.Name)
+		}
+	})
+	if len(missing) > 0 {
+		return fmt.Errorf(`required flag(s) "%s" not set`, strings.Join(missing, `", "`))
+	}
+	return nil
+}
+
+// ExitCode returns the exit code for an error returned by Execute. Errors with an explicit exit code
+// use it, errors wrapping the cidr package's sentinel errors are mapped to the matching exit code, and
+// any other error is an internal error. A search which gave up at its depth limit is treated as no
+// available CIDR.
+func ExitCode(err error) int {
+	var codeErr *exitCodeError
+	switch {
+	case err == nil:
+		return ExitSuccess
+	case errors.As(err, &codeErr):
+		return codeErr.code
+	case errors.Is(err, cidr.ErrNoAvailableCIDR), errors.Is(err, cidr.ErrSearchDepthExceeded):
+		return ExitNoAvailableCIDR
+	case errors.Is(err, cidr.ErrOverlappingCIDRs):
+		return ExitOverlappingCIDRs
+	case errors.Is(err, cidr.ErrInvalidInputRanges):
+		return ExitInvalidInput
+	default:
+		return ExitInternalError
+	}
+}
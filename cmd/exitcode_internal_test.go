@@ -0,0 +1,150 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/massdriver-cloud/cola/pkg/cidr"
+)
+
+func TestExitCode(t *testing.T) {
+	type testData struct {
+		name string
+		args []string
+		want int
+	}
+	tests := []testData{
+		{
+			name: "find success",
+			args: []string{"find", "--base", "10.0.0.0/16", "--mask", "24"},
+			want: ExitSuccess,
+		},
+		{
+			name: "find no available CIDR",
+			args: []string{"find", "--base", "10.0.0.0/16", "--mask", "24", "--used", "10.0.0.0/16"},
+			want: ExitNoAvailableCIDR,
+		},
+		{
+			name: "find invalid base",
+			args: []string{"find", "--base", "10.0.0.0/33", "--mask", "24"},
+			want: ExitInvalidInput,
+		},
+		{
+			name: "find root within used",
+			args: []string{"find", "--base", "10.0.0.0/16", "--mask", "24", "--used", "10.0.0.0/8"},
+			want: ExitInvalidInput,
+		},
+		{
+			name: "find unknown flag",
+			args: []string{"find", "--bogus"},
+			want: ExitInvalidInput,
+		},
+		{
+			name: "validate overlapping",
+			args: []string{"validate", "--base", "10.0.0.0/16", "--used", "10.0.0.0/24,10.0.0.0/23"},
+			want: ExitOverlappingCIDRs,
+		},
+		{
+			name: "validate outside base",
+			args: []string{"validate", "--base", "10.0.0.0/16", "--used", "10.1.0.0/24"},
+			want: ExitInvalidInput,
+		},
+		{
+			name: "reserve taken",
+			args: []string{"reserve", "10.0.0.0/24", "--base", "10.0.0.0/16", "--used", "10.0.0.0/23"},
+			want: ExitNoAvailableCIDR,
+		},
+		{
+			name: "reserve outside base",
+			args: []string{"reserve", "10.1.0.0/24", "--base", "10.0.0.0/16"},
+			want: ExitInvalidInput,
+		},
+		{
+			name: "reserve missing CIDR",
+			args: []string{"reserve", "--base", "10.0.0.0/16"},
+			want: ExitInvalidInput,
+		},
+		{
+			name: "no subcommand",
+			args: []string{},
+			want: ExitInvalidInput,
+		},
+		{
+			name: "find without AWS support",
+			args: []string{"find", "--aws-vpc", "vpc-123", "--mask", "24"},
+			want: ExitInvalidInput,
+		},
+		{
+			name: "plan missing prefixes",
+			args: []string{"plan", "--base", "10.0.0.0/16"},
+			want: ExitInvalidInput,
+		},
+		{
+			name: "plan duplicate name",
+			args: []string{"plan", "--base", "10.0.0.0/16", "--prefixes", "web=24,web=23"},
+			want: ExitInvalidInput,
+		},
+		{
+			name: "plan invalid request",
+			args: []string{"plan", "--base", "10.0.0.0/16", "--prefixes", "web"},
+			want: ExitInvalidInput,
+		},
+		{
+			name: "plan unsupported output",
+			args: []string{"plan", "--base", "10.0.0.0/16", "--prefixes", "web=24", "--output", "bogus"},
+			want: ExitInvalidInput,
+		},
+		{
+			name: "plan unsupported provider",
+			args: []string{"plan", "--base", "10.0.0.0/16", "--prefixes", "web=24", "--output", "tf", "--provider", "bogus"},
+			want: ExitInvalidInput,
+		},
+		{
+			name: "report unsupported output",
+			args: []string{"report", "--base", "10.0.0.0/16", "--output", "bogus"},
+			want: ExitInvalidInput,
+		},
+		{
+			name: "simulate missing script flag",
+			args: []string{"simulate", "--base", "10.0.0.0/16"},
+			want: ExitInvalidInput,
+		},
+		{
+			name: "simulate unreadable script",
+			args: []string{"simulate", "--base", "10.0.0.0/16", "--script", "testdata/missing.txt"},
+			want: ExitInvalidInput,
+		},
+		{
+			name: "toint invalid CIDR",
+			args: []string{"toint", "nope"},
+			want: ExitInvalidInput,
+		},
+		{
+			name: "fromint invalid integer",
+			args: []string{"fromint", "a", "b"},
+			want: ExitInvalidInput,
+		},
+		{
+			name: "fromint missing argument",
+			args: []string{"fromint", "1"},
+			want: ExitInvalidInput,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := executeCommand(tc.args...)
+			if got := ExitCode(err); got != tc.want {
+				t.Fatalf("want: %v, got: %v (%v)", tc.want, got, err)
+			}
+		})
+	}
+
+	if got := ExitCode(errors.New("unexpected")); got != ExitInternalError {
+		t.Fatalf("want: %v, got: %v", ExitInternalError, got)
+	}
+	if got := ExitCode(fmt.Errorf("%w: exceeded maximum depth of 4", cidr.ErrSearchDepthExceeded)); got != ExitNoAvailableCIDR {
+		t.Fatalf("want: %v, got: %v", ExitNoAvailableCIDR, got)
+	}
+}
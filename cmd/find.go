@@ -0,0 +1,299 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+
+	"github.com/massdriver-cloud/cola/pkg/cidr"
+	"github.com/spf13/cobra"
+)
+
+var findBase string
+var findBaseHosts int
+var findMask int
+var findFallbackMask int
+var findHosts int
+var findUsed []string
+var findReserved []string
+var findExplainResult bool
+var findAWSVPC string
+var findAWSRegion string
+var findShowRemaining bool
+var findOutput string
+
+var findCmd = &cobra.Command{
+	Use:   "find",
+	Short: "Find an available CIDR within a base CIDR",
+	Long: `Find an available CIDR of the requested size within a base CIDR, avoiding any used CIDRs.
+
+The requested size can be given as a prefix length (--mask 24) or as a number of hosts
+(--hosts 300). The base can also be sized by host count with --base-hosts, in which case
+--base only needs to provide the network address (e.g. --base 10.0.0.0 --base-hosts 60000).
+With --fallback-mask, if no CIDR of the requested size is available each smaller size is tried in
+turn down to the fallback prefix length, and the output says which size was found instead.
+With --hosts the text output also reports, on stderr, how many usable addresses rounding up to
+the block size leaves unused.
+
+CIDRs given with --reserved, such as space set aside for future peering, are never allocated even
+though nothing uses them yet. Unlike --used they may lie outside the base.
+
+With --aws-vpc the base and used CIDRs are read from the VPC and its subnets using the standard
+AWS credential chain. --base can still be given to search a smaller range of the VPC.
+
+With --output json the result is printed as a JSON object with the CIDR, base and mask, e.g.
+{"cidr":"10.0.88.0/21","base":"10.0.0.0/16","mask":21}, and a failure as {"error":"..."} along
+with the non-zero exit code.`,
+	RunE: runFind,
+}
+
+func init() {
+	rootCmd.AddCommand(findCmd)
+
+	findCmd.Flags().StringVar(&findBase, "base", "", "Base CIDR to search within")
+	findCmd.Flags().IntVar(&findBaseHosts, "base-hosts", 0, "Size the base to fit this many hosts")
+	findCmd.Flags().IntVar(&findMask, "mask", 0, "Prefix length of the CIDR to find")
+	findCmd.Flags().IntVar(&findFallbackMask, "fallback-mask", 0, "Longest prefix length to fall back to if no CIDR of the requested size is available")
+	findCmd.Flags().IntVar(&findHosts, "hosts", 0, "Find a CIDR large enough for this many hosts")
+	findCmd.Flags().StringArrayVar(&findUsed, "used", []string{}, "Used CIDRs (repeatable, separated by commas, spaces or newlines)")
+	findCmd.Flags().StringArrayVar(&findReserved, "reserved", []string{}, "CIDRs reserved for future use, which are never allocated (repeatable, separated by commas, spaces or newlines)")
+	findCmd.Flags().BoolVar(&findExplainResult, "explain-result", false, "Print the sibling and parent of the result and whether they are free or used (text output only)")
+	findCmd.Flags().BoolVar(&findShowRemaining, "show-remaining", false, "Print the free space left in the base after the allocation")
+	findCmd.Flags().StringVar(&findOutput, "output", "text", "Output format (text, json)")
+	findCmd.Flags().StringVar(&findAWSVPC, "aws-vpc", "", "Read the base and used CIDRs from this AWS VPC")
+	findCmd.Flags().StringVar(&findAWSRegion, "aws-region", "", "AWS region of the VPC")
+}
+
+func runFind(cmd *cobra.Command, args []string) error {
+	err := findCIDR(cmd)
+	if err != nil && findOutput == "json" {
+		// scripts reading the JSON output get the error on stdout too, alongside the exit code
+		if encodeErr := writeFindErrorJSON(cmd, err); encodeErr != nil {
+			return encodeErr
+		}
+	}
+	return err
+}
+
+// findCIDR finds and prints a CIDR from the flags
+func findCIDR(cmd *cobra.Command) error {
+	findBase = configString(cmd, "base", findBase)
+	findUsed = configStringSlice(cmd, "used", findUsed)
+	base, used, err := findInputs(cmd.Context())
+	if err != nil {
+		return err
+	}
+
+	_, bits := base.Mask.Size()
+	ones := findMask
+	switch {
+	case findMask != 0 && findHosts != 0:
+		return invalidInput(errors.New("only one of --mask or --hosts may be set"))
+	case findHosts != 0:
+		ones, err = cidr.PrefixForHosts(findHosts, bits)
+		if err != nil {
+			return err
+		}
+	case findMask == 0:
+		return invalidInput(errors.New("one of --mask or --hosts is required"))
+	}
+	mask := net.CIDRMask(ones, bits)
+	if mask == nil {
+		return invalidInput(fmt.Errorf("invalid mask /%d", ones))
+	}
+	fallback := ones
+	if findFallbackMask != 0 {
+		if findFallbackMask < ones || findFallbackMask > bits {
+			return invalidInput(fmt.Errorf("invalid fallback mask /%d, it must be between /%d and /%d", findFallbackMask, ones, bits))
+		}
+		fallback = findFallbackMask
+	}
+
+	reserved, err := parseCIDRList(findReserved)
+	if err != nil {
+		return err
+	}
+	opts := []cidr.FindOption{cidr.WithConflictReport(), cidr.WithRejectSpecialUse(), cidr.WithDenyList(reserved)}
+
+	requested := ones
+	result, err := cidr.FindAvailableCIDR(base, &mask, used, opts...)
+	for err != nil && errors.Is(err, cidr.ErrNoAvailableCIDR) && ones < fallback {
+		ones++
+		mask = net.CIDRMask(ones, bits)
+		result, err = cidr.FindAvailableCIDR(base, &mask, used, opts...)
+	}
+	if err != nil {
+		var conflictErr *cidr.ConflictError
+		if findOutput != "json" && errors.As(err, &conflictErr) && len(conflictErr.Blockers) > 0 {
+			fmt.Fprintf(cmd.OutOrStdout(), "blocked by: %s\n", joinCIDRs(conflictErr.Blockers))
+		}
+		return err
+	}
+
+	var remaining []*net.IPNet
+	if findShowRemaining {
+		remaining, err = cidr.FreeSpace(base, append(used, result))
+		if err != nil {
+			return err
+		}
+	}
+
+	switch findOutput {
+	case "text":
+		fmt.Fprintln(cmd.OutOrStdout(), result.String())
+		if ones != requested {
+			fmt.Fprintf(cmd.ErrOrStderr(), "no /%d available, fell back to /%d\n", requested, ones)
+		}
+		if findHosts != 0 {
+			unused, fraction := cidr.Waste(findHosts, result)
+			fmt.Fprintf(cmd.ErrOrStderr(), "allocated /%d for %d hosts; %d usable addresses unused, %.0f%% waste\n", ones, findHosts, unused, fraction*100)
+		}
+		if findShowRemaining {
+			fmt.Fprintln(cmd.OutOrStdout(), "remaining:")
+			for _, free := range remaining {
+				fmt.Fprintf(cmd.OutOrStdout(), "  %s\n", free.String())
+			}
+		}
+		if findExplainResult {
+			return explainResult(cmd, result, used)
+		}
+		return nil
+	case "json":
+		return writeFindJSON(cmd, base, result, requested, remaining)
+	default:
+		return invalidInput(fmt.Errorf("unsupported output format %q", findOutput))
+	}
+}
+
+// findResponse is the JSON output of the find command
+type findResponse struct {
+	CIDR      string   `json:"cidr"`
+	Base      string   `json:"base"`
+	Mask      int      `json:"mask"`
+	Fallback  bool     `json:"fallback,omitempty"`
+	Requested *int     `json:"requested_mask,omitempty"`
+	Remaining []string `json:"remaining,omitempty"`
+}
+
+// findErrorResponse is the JSON output of the find command when it fails
+type findErrorResponse struct {
+	Error     string   `json:"error"`
+	BlockedBy []string `json:"blocked_by,omitempty"`
+}
+
+func writeFindJSON(cmd *cobra.Command, base *net.IPNet, result *net.IPNet, requested int, remaining []*net.IPNet) error {
+	response := findResponse{CIDR: result.String(), Base: base.String(), Mask: cidr.BlockSizePrefix(&result.Mask)}
+	if cidr.BlockSizePrefix(&result.Mask) != requested {
+		// the requested mask is only included when it differs from the result's
+		response.Fallback = true
+		response.Requested = &requested
+	}
+	if findShowRemaining {
+		response.Remaining = make([]string, len(remaining))
+		for i, free := range remaining {
+			response.Remaining[i] = free.String()
+		}
+	}
+	encoder := json.NewEncoder(cmd.OutOrStdout())
+	return encoder.Encode(response)
+}
+
+// writeFindErrorJSON prints the error, and the used CIDRs which blocked the search if there are any
+func writeFindErrorJSON(cmd *cobra.Command, err error) error {
+	response := findErrorResponse{Error: err.Error()}
+	var conflictErr *cidr.ConflictError
+	if errors.As(err, &conflictErr) {
+		for _, blocker := range conflictErr.Blockers {
+			response.BlockedBy = append(response.BlockedBy, blocker.String())
+		}
+	}
+	return json.NewEncoder(cmd.OutOrStdout()).Encode(response)
+}
+
+// explainResult prints the sibling and parent of the result, and how much of each is used
+func explainResult(cmd *cobra.Command, result *net.IPNet, used []*net.IPNet) error {
+	sibling, err := cidr.SiblingCIDR(result)
+	if err != nil {
+		return err
+	}
+	parent, err := cidr.ParentCIDR(result)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(cmd.OutOrStdout(), "  sibling: %s (%s)\n", sibling.String(), usageStatus(sibling, used))
+	fmt.Fprintf(cmd.OutOrStdout(), "  parent:  %s (%s)\n", parent.String(), usageStatus(parent, used))
+	return nil
+}
+
+// usageStatus describes whether n is entirely used, partially used or free
+func usageStatus(n *net.IPNet, used []*net.IPNet) string {
+	for _, u := range used {
+		if cidr.ContainsCIDR(u, n) {
+			return "used"
+		}
+	}
+	if cidr.ContainsExistingCIDR(n, used) {
+		return "partially used"
+	}
+	return "free"
+}
+
+// findInputs returns the base and used CIDRs from the flags, adding the CIDRs of the AWS VPC if one is given
+func findInputs(ctx context.Context) (*net.IPNet, []*net.IPNet, error) {
+	used, err := parseCIDRList(findUsed)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if findAWSVPC == "" {
+		if findBase == "" {
+			return nil, nil, invalidInput(errors.New("one of --base or --aws-vpc is required"))
+		}
+		base, baseErr := findBaseCIDR()
+		return base, used, baseErr
+	}
+
+	base, vpcUsed, err := loadAWSVPC(ctx, findAWSRegion, findAWSVPC)
+	if err != nil {
+		return nil, nil, err
+	}
+	used = append(used, vpcUsed...)
+	if findBase != "" {
+		base, err = findBaseCIDR()
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+	return base, used, nil
+}
+
+// findBaseCIDR parses the --base flag, sizing it with --base-hosts if set
+func findBaseCIDR() (*net.IPNet, error) {
+	if findBaseHosts == 0 {
+		_, base, err := net.ParseCIDR(findBase)
+		if err != nil {
+			return nil, invalidInput(fmt.Errorf("invalid base CIDR %q: %w", findBase, err))
+		}
+		return base, nil
+	}
+
+	address := strings.SplitN(findBase, "/", 2)[0]
+	ip := net.ParseIP(address)
+	if ip == nil {
+		return nil, invalidInput(fmt.Errorf("invalid base address %q", address))
+	}
+	bits := 8 * net.IPv6len
+	if v4 := ip.To4(); v4 != nil {
+		ip = v4
+		bits = 8 * net.IPv4len
+	}
+	ones, err := cidr.PrefixForHosts(findBaseHosts, bits)
+	if err != nil {
+		return nil, err
+	}
+	mask := net.CIDRMask(ones, bits)
+	return &net.IPNet{IP: ip.Mask(mask), Mask: mask}, nil
+}
@@ -0,0 +1,326 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/massdriver-cloud/cola/pkg/awsvpc"
+	"github.com/massdriver-cloud/cola/pkg/cidr"
+)
+
+func TestFindHosts(t *testing.T) {
+	type testData struct {
+		name string
+		args []string
+		want string
+	}
+	tests := []testData{
+		{
+			name: "Mask",
+			args: []string{"find", "--base", "10.0.0.0/16", "--mask", "24", "--used", "10.0.0.0/24"},
+			want: "10.0.1.0/24",
+		},
+		{
+			name: "Hosts",
+			args: []string{"find", "--base", "10.0.0.0/16", "--hosts", "300"},
+			want: "10.0.0.0/23\nallocated /23 for 300 hosts; 210 usable addresses unused, 41% waste",
+		},
+		{
+			name: "Base hosts",
+			args: []string{"find", "--base", "10.0.0.0", "--base-hosts", "60000", "--hosts", "254", "--used", "10.0.0.0/24"},
+			want: "10.0.1.0/24\nallocated /24 for 254 hosts; 0 usable addresses unused, 0% waste",
+		},
+		{
+			name: "Base hosts larger than a /16",
+			args: []string{"find", "--base", "10.0.0.0", "--base-hosts", "65535", "--mask", "16", "--used", "10.0.0.0/16"},
+			want: "10.1.0.0/16",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := executeCommand(tc.args...)
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err.Error())
+			}
+			if strings.TrimSpace(got) != tc.want {
+				t.Fatalf("want: %v, got: %v", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestFindExplainResult(t *testing.T) {
+	got, err := executeCommand("find", "--base", "10.0.0.0/16", "--mask", "21",
+		"--used", "10.0.0.0/18,10.0.64.0/20,10.0.80.0/24", "--explain-result")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err.Error())
+	}
+	want := `10.0.88.0/21
+  sibling: 10.0.80.0/21 (partially used)
+  parent:  10.0.80.0/20 (partially used)
+`
+	if got != want {
+		t.Fatalf("want: %v, got: %v", want, got)
+	}
+
+	got, err = executeCommand("find", "--base", "10.0.0.0/16", "--mask", "24",
+		"--used", "10.0.0.0/24", "--explain-result")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err.Error())
+	}
+	want = `10.0.1.0/24
+  sibling: 10.0.0.0/24 (used)
+  parent:  10.0.0.0/23 (partially used)
+`
+	if got != want {
+		t.Fatalf("want: %v, got: %v", want, got)
+	}
+}
+
+type fakeAWSClient struct{}
+
+func (fakeAWSClient) VPCCIDR(ctx context.Context, vpcID string) (string, error) {
+	return "10.0.0.0/16", nil
+}
+
+func (fakeAWSClient) SubnetCIDRs(ctx context.Context, vpcID string) ([]string, error) {
+	return []string{"10.0.0.0/24", "10.0.1.0/24"}, nil
+}
+
+func TestFindAWSVPC(t *testing.T) {
+	if newAWSClient == nil {
+		_, err := executeCommand("find", "--aws-vpc", "vpc-123", "--aws-region", "us-east-1", "--mask", "24")
+		if !errors.Is(err, errNoAWSSupport) {
+			t.Fatalf("Invalid error, want: %v, got %v,", errNoAWSSupport, err)
+		}
+	}
+
+	original := newAWSClient
+	defer func() { newAWSClient = original }()
+	newAWSClient = func(ctx context.Context, region string) (awsvpc.Client, error) {
+		return fakeAWSClient{}, nil
+	}
+
+	got, err := executeCommand("find", "--aws-vpc", "vpc-123", "--aws-region", "us-east-1", "--mask", "24")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err.Error())
+	}
+	if strings.TrimSpace(got) != "10.0.2.0/24" {
+		t.Fatalf("want: %v, got: %v", "10.0.2.0/24", got)
+	}
+}
+
+func TestFindBlockedBy(t *testing.T) {
+	got, err := executeCommand("find", "--base", "10.0.0.0/23", "--mask", "24", "--used", "10.0.0.0/25,10.0.1.128/25")
+	if err == nil {
+		t.Fatalf("expected error when no CIDR is available")
+	}
+	if !strings.HasPrefix(got, "blocked by: 10.0.0.0/25, 10.0.1.128/25\n") {
+		t.Fatalf("want: %v, got: %v", "blocked by: 10.0.0.0/25, 10.0.1.128/25", got)
+	}
+}
+
+func TestFindShowRemaining(t *testing.T) {
+	got, err := executeCommand("find", "--base", "10.0.0.0/22", "--mask", "24", "--used", "10.0.0.0/24", "--show-remaining")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err.Error())
+	}
+	want := `10.0.1.0/24
+remaining:
+  10.0.2.0/23
+`
+	if got != want {
+		t.Fatalf("want: %v, got: %v", want, got)
+	}
+
+	got, err = executeCommand("find", "--base", "10.0.0.0/22", "--mask", "24", "--used", "10.0.0.0/24", "--show-remaining", "--output", "json")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err.Error())
+	}
+	var response findResponse
+	if err = json.Unmarshal([]byte(got), &response); err != nil {
+		t.Fatalf("unexpected error parsing output: %s", err.Error())
+	}
+	if response.CIDR != "10.0.1.0/24" {
+		t.Fatalf("want: %v, got: %v", "10.0.1.0/24", response.CIDR)
+	}
+	if len(response.Remaining) != 1 || response.Remaining[0] != "10.0.2.0/23" {
+		t.Fatalf("want: %v, got: %v", []string{"10.0.2.0/23"}, response.Remaining)
+	}
+}
+
+func TestFindFallbackMask(t *testing.T) {
+	type testData struct {
+		name     string
+		args     []string
+		want     string
+		wantCode int
+	}
+	tests := []testData{
+		{
+			name: "Primary available",
+			args: []string{"find", "--base", "10.0.0.0/22", "--mask", "24", "--fallback-mask", "26", "--used", "10.0.0.0/24"},
+			want: "10.0.1.0/24\n",
+		},
+		{
+			name: "Falls back one size",
+			args: []string{"find", "--base", "10.0.0.0/23", "--mask", "24", "--fallback-mask", "26", "--used", "10.0.0.0/24,10.0.1.0/25"},
+			want: "10.0.1.128/25\nno /24 available, fell back to /25\n",
+		},
+		{
+			name: "Falls back to the fallback mask",
+			args: []string{"find", "--base", "10.0.0.0/23", "--mask", "24", "--fallback-mask", "26", "--used", "10.0.0.0/24,10.0.1.0/25,10.0.1.128/26"},
+			want: "10.0.1.192/26\nno /24 available, fell back to /26\n",
+		},
+		{
+			name:     "Nothing down to the fallback mask",
+			args:     []string{"find", "--base", "10.0.0.0/23", "--mask", "24", "--fallback-mask", "25", "--used", "10.0.0.0/24,10.0.1.0/25,10.0.1.128/26"},
+			wantCode: ExitNoAvailableCIDR,
+		},
+		{
+			name:     "Fallback mask larger than the mask",
+			args:     []string{"find", "--base", "10.0.0.0/16", "--mask", "24", "--fallback-mask", "22"},
+			wantCode: ExitInvalidInput,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := executeCommand(tc.args...)
+			if tc.wantCode != 0 {
+				if ExitCode(err) != tc.wantCode {
+					t.Fatalf("want: %v, got: %v (%v)", tc.wantCode, ExitCode(err), err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err.Error())
+			}
+			if got != tc.want {
+				t.Fatalf("want: %v, got: %v", tc.want, got)
+			}
+		})
+	}
+
+	got, err := executeCommand("find", "--base", "10.0.0.0/23", "--mask", "24", "--fallback-mask", "26", "--used", "10.0.0.0/24,10.0.1.0/25", "--output", "json")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err.Error())
+	}
+	var response findResponse
+	if err = json.Unmarshal([]byte(got), &response); err != nil {
+		t.Fatalf("unexpected error parsing output: %s", err.Error())
+	}
+	if response.CIDR != "10.0.1.128/25" || !response.Fallback || response.Requested == nil || *response.Requested != 24 {
+		t.Fatalf("want: %v, got: %v", "10.0.1.128/25 falling back from /24", got)
+	}
+}
+
+func TestFindSpecialUseBase(t *testing.T) {
+	got, err := executeCommand("find", "--base", "169.254.0.0/16", "--mask", "24")
+	if ExitCode(err) != ExitInvalidInput {
+		t.Fatalf("want: %v, got: %v (%v)", ExitInvalidInput, ExitCode(err), err)
+	}
+	if !strings.Contains(got, "link-local address space") {
+		t.Fatalf("want: link-local base rejected, got: %v", got)
+	}
+}
+
+func TestFindEnvironment(t *testing.T) {
+	t.Setenv("COLA_BASE", "10.0.0.0/16")
+	t.Setenv("COLA_USED", "10.0.0.0/24,10.0.1.0/24")
+
+	got, err := executeCommand("find", "--mask", "24")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err.Error())
+	}
+	if strings.TrimSpace(got) != "10.0.2.0/24" {
+		t.Fatalf("want: %v, got: %v", "10.0.2.0/24", got)
+	}
+
+	// flags take precedence over the environment
+	got, err = executeCommand("find", "--mask", "24", "--base", "10.1.0.0/16")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err.Error())
+	}
+	if strings.TrimSpace(got) != "10.1.0.0/24" {
+		t.Fatalf("want: %v, got: %v", "10.1.0.0/24", got)
+	}
+}
+
+func TestFindPastedUsedList(t *testing.T) {
+	got, err := executeCommand("find", "--base", "10.0.0.0/16", "--mask", "24", "--used", "10.0.0.0/24 10.0.1.0/24\n10.0.2.0/24", "--used", "10.0.3.0/24")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if got != "10.0.4.0/24\n" {
+		t.Fatalf("want: %v, got: %v", "10.0.4.0/24\n", got)
+	}
+
+	got, err = executeCommand("find", "--base", "10.0.0.0/16", "--mask", "24", "--used", "10.0.0.0/24 10.0.1.0")
+	if ExitCode(err) != ExitInvalidInput {
+		t.Fatalf("want: %v, got: %v (%v)", ExitInvalidInput, ExitCode(err), err)
+	}
+	want := `Error: input ranges invalid: "10.0.1.0" is not a valid CIDR`
+	if !strings.HasPrefix(got, want) {
+		t.Fatalf("want: %v, got: %v", want, got)
+	}
+}
+
+func TestFindJSON(t *testing.T) {
+	got, err := executeCommand("find", "--base", "10.0.0.0/16", "--mask", "21",
+		"--used", "10.0.0.0/18,10.0.64.0/20,10.0.80.0/24", "--output", "json")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err.Error())
+	}
+	var response findResponse
+	if err = json.Unmarshal([]byte(got), &response); err != nil {
+		t.Fatalf("unexpected error parsing output: %s", err.Error())
+	}
+	if response.CIDR != "10.0.88.0/21" || response.Base != "10.0.0.0/16" || response.Mask != 21 {
+		t.Fatalf("want: %v, got: %v", `{"cidr":"10.0.88.0/21","base":"10.0.0.0/16","mask":21}`, got)
+	}
+
+	got, err = executeCommand("find", "--base", "10.0.0.0/23", "--mask", "24",
+		"--used", "10.0.0.0/25,10.0.1.128/25", "--output", "json")
+	if ExitCode(err) != ExitNoAvailableCIDR {
+		t.Fatalf("want: %v, got: %v (%v)", ExitNoAvailableCIDR, ExitCode(err), err)
+	}
+	var errorResponse findErrorResponse
+	if err = json.Unmarshal([]byte(strings.SplitN(got, "\n", 2)[0]), &errorResponse); err != nil {
+		t.Fatalf("unexpected error parsing output: %s", err.Error())
+	}
+	if !strings.HasPrefix(errorResponse.Error, cidr.ErrNoAvailableCIDR.Error()) {
+		t.Fatalf("want: %v, got: %v", cidr.ErrNoAvailableCIDR.Error(), errorResponse.Error)
+	}
+	if strings.Join(errorResponse.BlockedBy, ",") != "10.0.0.0/25,10.0.1.128/25" {
+		t.Fatalf("want: %v, got: %v", "10.0.0.0/25,10.0.1.128/25", errorResponse.BlockedBy)
+	}
+
+	got, err = executeCommand("find", "--base", "10.0.0.0/33", "--mask", "24", "--output", "json")
+	if ExitCode(err) != ExitInvalidInput {
+		t.Fatalf("want: %v, got: %v (%v)", ExitInvalidInput, ExitCode(err), err)
+	}
+	if !strings.HasPrefix(got, `{"error":`) {
+		t.Fatalf("want: %v, got: %v", `{"error":...}`, got)
+	}
+}
+
+func TestFindReserved(t *testing.T) {
+	got, err := executeCommand("find", "--base", "10.0.0.0/22", "--mask", "24", "--used", "10.0.0.0/24",
+		"--reserved", "10.0.1.0/25", "--reserved", "172.16.0.0/12")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err.Error())
+	}
+	if strings.TrimSpace(got) != "10.0.2.0/24" {
+		t.Fatalf("want: %v, got: %v", "10.0.2.0/24", got)
+	}
+
+	_, err = executeCommand("find", "--base", "10.0.0.0/22", "--mask", "24", "--reserved", "not-a-cidr")
+	if ExitCode(err) != ExitInvalidInput {
+		t.Fatalf("want: %v, got: %v (%v)", ExitInvalidInput, ExitCode(err), err)
+	}
+}
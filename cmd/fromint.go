@@ -0,0 +1,60 @@
+package cmd
+
+import (
+	"fmt"
+	"math/big"
+	"net"
+
+	"github.com/massdriver-cloud/cola/pkg/cidr"
+	"github.com/spf13/cobra"
+)
+
+var fromIntIPv6 bool
+
+var fromIntCmd = &cobra.Command{
+	Use:   "fromint START END",
+	Short: "Print the CIDRs covering an integer address range",
+	Long:  `Print the minimal list of CIDRs covering every address from START to END, where both are unsigned integers. Ranges beyond the IPv4 address space are treated as IPv6`,
+	Args:  exactArgs(2),
+	RunE:  runFromInt,
+}
+
+func init() {
+	rootCmd.AddCommand(fromIntCmd)
+
+	fromIntCmd.Flags().BoolVar(&fromIntIPv6, "ipv6", false, "Treat the integers as IPv6 addresses")
+}
+
+func runFromInt(cmd *cobra.Command, args []string) error {
+	start, ok := new(big.Int).SetString(args[0], 10)
+	if !ok {
+		return invalidInput(fmt.Errorf("invalid integer: %s", args[0]))
+	}
+	end, ok := new(big.Int).SetString(args[1], 10)
+	if !ok {
+		return invalidInput(fmt.Errorf("invalid integer: %s", args[1]))
+	}
+
+	bits := 8 * net.IPv4len
+	if fromIntIPv6 || end.BitLen() > bits {
+		bits = 8 * net.IPv6len
+	}
+
+	first, err := cidr.IntToIP(start, bits)
+	if err != nil {
+		return err
+	}
+	last, err := cidr.IntToIP(end, bits)
+	if err != nil {
+		return err
+	}
+
+	cidrs, err := cidr.RangeToCIDRs(first, last)
+	if err != nil {
+		return err
+	}
+	for _, c := range cidrs {
+		fmt.Fprintln(cmd.OutOrStdout(), c.String())
+	}
+	return nil
+}
@@ -0,0 +1,192 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"sort"
+
+	"github.com/massdriver-cloud/cola/pkg/cidr"
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+)
+
+var generateBase string
+var generateOutput string
+
+var generateCmd = &cobra.Command{
+	Use:   "generate SPEC",
+	Short: "Generate a complete address plan from an environment spec",
+	Long: `Generate a complete address plan under a base CIDR from a YAML spec of environments, their
+regions, each region's availability zones and the tier subnets in every zone:
+
+  environments:
+    - name: prod
+      prefix: 16
+      regions:
+        - name: us-east-1
+          prefix: 18
+          azs: [a, b, c]
+          tiers:
+            public: 24
+            private: 22
+
+Environments are allocated from the base and regions from their environment, packed largest
+first like plan. Each region is split into one equal block per AZ, and the tiers are packed into
+every AZ block. Every tier subnet is printed with its name, e.g. prod/us-east-1/a/public, in the
+order of the spec with tiers by name, so the same spec always generates the same plan. The plan
+can be printed as text or as an Ansible vars file (--output ansible) for drift.`,
+	Args: exactArgs(1),
+	RunE: runGenerate,
+}
+
+func init() {
+	rootCmd.AddCommand(generateCmd)
+
+	generateCmd.Flags().StringVar(&generateBase, "base", "", "Base CIDR to allocate the environments from")
+	generateCmd.Flags().StringVar(&generateOutput, "output", "text", "Output format (text, ansible)")
+}
+
+// envSpec is the spec read by generate
+type envSpec struct {
+	Environments []envSpecEnvironment `yaml:"environments"`
+}
+
+type envSpecEnvironment struct {
+	Name    string          `yaml:"name"`
+	Prefix  int             `yaml:"prefix"`
+	Regions []envSpecRegion `yaml:"regions"`
+}
+
+type envSpecRegion struct {
+	Name   string         `yaml:"name"`
+	Prefix int            `yaml:"prefix"`
+	AZs    []string       `yaml:"azs"`
+	Tiers  map[string]int `yaml:"tiers"`
+}
+
+func runGenerate(cmd *cobra.Command, args []string) error {
+	base, err := parseBaseCIDR(cmd, generateBase)
+	if err != nil {
+		return err
+	}
+	spec, err := readEnvSpec(args[0])
+	if err != nil {
+		return err
+	}
+	subnets, err := generatePlan(base, spec)
+	if err != nil {
+		return err
+	}
+
+	switch generateOutput {
+	case "text":
+		for _, subnet := range subnets {
+			fmt.Fprintf(cmd.OutOrStdout(), "%s\t%s\n", subnet.Name, subnet.CIDR.String())
+		}
+		return nil
+	case "ansible":
+		return renderAnsible(cmd.OutOrStdout(), subnets)
+	default:
+		return invalidInput(fmt.Errorf("unsupported output format %q", generateOutput))
+	}
+}
+
+// readEnvSpec reads the spec file, checking every level is named and names are unique within it
+func readEnvSpec(path string) (*envSpec, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, invalidInput(err)
+	}
+	spec := &envSpec{}
+	if unmarshalErr := yaml.Unmarshal(data, spec); unmarshalErr != nil {
+		return nil, invalidInput(fmt.Errorf("invalid spec file %q: %w", path, unmarshalErr))
+	}
+	if len(spec.Environments) == 0 {
+		return nil, invalidInput(errors.New("the spec has no environments"))
+	}
+
+	envNames := make([]string, len(spec.Environments))
+	for i, env := range spec.Environments {
+		envNames[i] = env.Name
+		regionNames := make([]string, len(env.Regions))
+		for j, region := range env.Regions {
+			regionNames[j] = region.Name
+			if nameErr := checkSpecNames(fmt.Sprintf("environment %q region %q AZ", env.Name, region.Name), region.AZs); nameErr != nil {
+				return nil, nameErr
+			}
+		}
+		if nameErr := checkSpecNames(fmt.Sprintf("environment %q region", env.Name), regionNames); nameErr != nil {
+			return nil, nameErr
+		}
+	}
+	if nameErr := checkSpecNames("environment", envNames); nameErr != nil {
+		return nil, nameErr
+	}
+	return spec, nil
+}
+
+// checkSpecNames returns an error if any of the names at one level of the spec are empty or repeated
+func checkSpecNames(level string, names []string) error {
+	seen := map[string]bool{}
+	for _, name := range names {
+		if name == "" {
+			return invalidInput(fmt.Errorf("every %s needs a name", level))
+		}
+		if seen[name] {
+			return invalidInput(fmt.Errorf("%s %q is declared more than once", level, name))
+		}
+		seen[name] = true
+	}
+	return nil
+}
+
+// generatePlan allocates the environments, regions, AZs and tiers of the spec under the base, returning
+// the tier subnets in the order of the spec
+func generatePlan(base *net.IPNet, spec *envSpec) ([]plannedSubnet, error) {
+	envPrefixes := make(map[string]int, len(spec.Environments))
+	for _, env := range spec.Environments {
+		envPrefixes[env.Name] = env.Prefix
+	}
+	envBlocks, err := cidr.FindTieredPlan(base, envPrefixes, []*net.IPNet{})
+	if err != nil {
+		return nil, err
+	}
+
+	subnets := []plannedSubnet{}
+	for _, env := range spec.Environments {
+		regionPrefixes := make(map[string]int, len(env.Regions))
+		for _, region := range env.Regions {
+			regionPrefixes[region.Name] = region.Prefix
+		}
+		regionBlocks, regionErr := cidr.FindTieredPlan(envBlocks[env.Name], regionPrefixes, []*net.IPNet{})
+		if regionErr != nil {
+			return nil, fmt.Errorf("environment %q: %w", env.Name, regionErr)
+		}
+
+		for _, region := range env.Regions {
+			path := env.Name + "/" + region.Name
+			azBlocks, azErr := cidr.AllocateAZSubnets(regionBlocks[region.Name], len(region.AZs), []*net.IPNet{})
+			if azErr != nil {
+				return nil, fmt.Errorf("region %q: %w", path, azErr)
+			}
+			tierNames := make([]string, 0, len(region.Tiers))
+			for name := range region.Tiers {
+				tierNames = append(tierNames, name)
+			}
+			sort.Strings(tierNames)
+
+			for i, az := range region.AZs {
+				tiers, tierErr := cidr.FindTieredPlan(azBlocks[i], region.Tiers, []*net.IPNet{})
+				if tierErr != nil {
+					return nil, fmt.Errorf("AZ %q: %w", path+"/"+az, tierErr)
+				}
+				for _, name := range tierNames {
+					subnets = append(subnets, plannedSubnet{Name: path + "/" + az + "/" + name, CIDR: tiers[name]})
+				}
+			}
+		}
+	}
+	return subnets, nil
+}
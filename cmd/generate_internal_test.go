@@ -0,0 +1,60 @@
+package cmd
+
+import (
+	"net"
+	"strings"
+	"testing"
+
+	"github.com/massdriver-cloud/cola/pkg/cidr"
+)
+
+func TestGenerate(t *testing.T) {
+	got, err := executeCommand("generate", "testdata/generate_env.yaml", "--base", "10.0.0.0/14")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	want := `prod/us-east-1/a/private	10.0.0.0/22
+prod/us-east-1/a/public	10.0.4.0/24
+prod/us-east-1/b/private	10.0.16.0/22
+prod/us-east-1/b/public	10.0.20.0/24
+prod/us-east-1/c/private	10.0.32.0/22
+prod/us-east-1/c/public	10.0.36.0/24
+prod/eu-west-1/a/private	10.0.64.0/22
+prod/eu-west-1/a/public	10.0.68.0/24
+prod/eu-west-1/b/private	10.0.80.0/22
+prod/eu-west-1/b/public	10.0.84.0/24
+staging/us-east-1/a/private	10.1.0.0/24
+staging/us-east-1/a/public	10.1.1.0/26
+staging/us-east-1/b/private	10.1.8.0/24
+staging/us-east-1/b/public	10.1.9.0/26
+`
+	if got != want {
+		t.Fatalf("want: %v, got: %v", want, got)
+	}
+
+	_, base, _ := net.ParseCIDR("10.0.0.0/14")
+	subnets := []*net.IPNet{}
+	for _, line := range strings.Split(strings.TrimSpace(got), "\n") {
+		_, subnet, _ := net.ParseCIDR(strings.Split(line, "\t")[1])
+		subnets = append(subnets, subnet)
+	}
+	if err = cidr.ValidateUsedCIDRs(base, subnets); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	again, err := executeCommand("generate", "testdata/generate_env.yaml", "--base", "10.0.0.0/14")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if again != got {
+		t.Fatalf("want: %v, got: %v", got, again)
+	}
+}
+
+func TestGenerateTooSmall(t *testing.T) {
+	_, err := executeCommand("generate", "testdata/generate_env.yaml", "--base", "10.0.0.0/16")
+	if ExitCode(err) != ExitNoAvailableCIDR {
+		t.Fatalf("want: %v, got: %v (%v)", ExitNoAvailableCIDR, ExitCode(err), err)
+	}
+}
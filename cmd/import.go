@@ -0,0 +1,182 @@
+package cmd
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/big"
+	"net"
+	"os"
+	"strings"
+
+	"github.com/massdriver-cloud/cola/pkg/cidr"
+	"github.com/spf13/cobra"
+)
+
+var importBase string
+var importUsedFile string
+var importOutput string
+
+var importCmd = &cobra.Command{
+	Use:   "import",
+	Short: "Check an existing IPAM export before migrating it",
+	Long: `Check every entry of an existing IPAM export against a base CIDR in one pass, as a sanity
+check before migrating it. The export is a CSV file with the CIDR in the first column; any other
+columns are ignored, as is a header row whose first column is "cidr" and lines starting with #.
+
+Entries which aren't valid CIDRs, have host bits set, are outside the base, or overlap an earlier
+entry are flagged as rejected. The report also includes how much of the base the accepted entries
+cover and the free space left.`,
+	RunE: runImport,
+}
+
+func init() {
+	rootCmd.AddCommand(importCmd)
+
+	importCmd.Flags().StringVar(&importBase, "base", "", "Base CIDR the entries are allocated from")
+	importCmd.Flags().StringVar(&importUsedFile, "used-file", "", "CSV export with the CIDR in the first column")
+	importCmd.Flags().StringVar(&importOutput, "output", "text", "Output format (text, json)")
+	_ = importCmd.MarkFlagRequired("used-file")
+}
+
+// importEntry is a rejected entry of the export
+type importEntry struct {
+	Line     int      `json:"line"`
+	CIDR     string   `json:"cidr"`
+	Problems []string `json:"problems"`
+}
+
+// importReport is the result of checking an export
+type importReport struct {
+	Entries  int           `json:"entries"`
+	Accepted int           `json:"accepted"`
+	Rejected []importEntry `json:"rejected"`
+	Coverage float64       `json:"coverage_percent"`
+	Free     []string      `json:"free"`
+}
+
+func runImport(cmd *cobra.Command, args []string) error {
+	base, err := parseBaseCIDR(cmd, importBase)
+	if err != nil {
+		return err
+	}
+	file, err := os.Open(importUsedFile)
+	if err != nil {
+		return invalidInput(err)
+	}
+	defer file.Close()
+
+	report, accepted, overlaps, err := checkImport(base, file)
+	if err != nil {
+		return err
+	}
+	free, err := cidr.FreeSpace(base, accepted)
+	if err != nil {
+		return err
+	}
+	report.Free = make([]string, len(free))
+	for i, n := range free {
+		report.Free[i] = n.String()
+	}
+
+	switch importOutput {
+	case "text":
+		for _, entry := range report.Rejected {
+			fmt.Fprintf(cmd.OutOrStdout(), "line %d: %s: %s\n", entry.Line, entry.CIDR, strings.Join(entry.Problems, ", "))
+		}
+		fmt.Fprintf(cmd.OutOrStdout(), "%d of %d entries accepted\n", report.Accepted, report.Entries)
+		fmt.Fprintf(cmd.OutOrStdout(), "coverage: %.2f%%\n", report.Coverage)
+		fmt.Fprintln(cmd.OutOrStdout(), "free:")
+		for _, n := range report.Free {
+			fmt.Fprintf(cmd.OutOrStdout(), "  %s\n", n)
+		}
+	case "json":
+		if encodeErr := json.NewEncoder(cmd.OutOrStdout()).Encode(report); encodeErr != nil {
+			return encodeErr
+		}
+	default:
+		return invalidInput(fmt.Errorf("unsupported output format %q", importOutput))
+	}
+
+	if len(report.Rejected) == 0 {
+		return nil
+	}
+	code := ExitInvalidInput
+	if overlaps {
+		code = ExitOverlappingCIDRs
+	}
+	return withExitCode(code, fmt.Errorf("%d entries would be rejected", len(report.Rejected)))
+}
+
+// checkImport reads the CSV export and checks each entry against the base and the entries accepted
+// before it. It returns the report without free space, the accepted CIDRs, and whether any entry was
+// rejected for overlapping.
+func checkImport(base *net.IPNet, export io.Reader) (*importReport, []*net.IPNet, bool, error) {
+	reader := csv.NewReader(export)
+	reader.FieldsPerRecord = -1
+	reader.Comment = '#'
+	reader.TrimLeadingSpace = true
+
+	report := &importReport{Rejected: []importEntry{}}
+	accepted := []*net.IPNet{}
+	acceptedLines := []int{}
+	overlaps := false
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, nil, false, invalidInput(fmt.Errorf("invalid export: %w", err))
+		}
+		line, _ := reader.FieldPos(0)
+		value := strings.TrimSpace(record[0])
+		if report.Entries == 0 && strings.EqualFold(value, "cidr") {
+			continue
+		}
+		report.Entries++
+
+		entry := importEntry{Line: line, CIDR: value, Problems: []string{}}
+		ip, n, parseErr := net.ParseCIDR(value)
+		if parseErr != nil {
+			entry.Problems = append(entry.Problems, "not a valid CIDR")
+			report.Rejected = append(report.Rejected, entry)
+			continue
+		}
+		if !ip.Equal(n.IP) {
+			entry.Problems = append(entry.Problems, "host bits set")
+		}
+		if !cidr.ContainsCIDR(base, n) {
+			entry.Problems = append(entry.Problems, fmt.Sprintf("not within the base %s", base.String()))
+		}
+		for i, other := range accepted {
+			if cidr.OverlapsCIDR(n, other) {
+				entry.Problems = append(entry.Problems, fmt.Sprintf("overlaps %s (line %d)", other.String(), acceptedLines[i]))
+				overlaps = true
+			}
+		}
+		if len(entry.Problems) > 0 {
+			report.Rejected = append(report.Rejected, entry)
+			continue
+		}
+		accepted = append(accepted, n)
+		acceptedLines = append(acceptedLines, line)
+	}
+	report.Accepted = len(accepted)
+	report.Coverage = coveragePercent(base, accepted)
+	return report, accepted, overlaps, nil
+}
+
+// coveragePercent returns the percentage of the base covered by the non-overlapping CIDRs
+func coveragePercent(base *net.IPNet, cidrs []*net.IPNet) float64 {
+	covered := new(big.Int)
+	for _, n := range cidrs {
+		covered.Add(covered, cidr.BlockSize(&n.Mask))
+	}
+	percent := new(big.Float).SetInt(covered)
+	percent.Mul(percent, big.NewFloat(100))
+	percent.Quo(percent, new(big.Float).SetInt(cidr.BlockSize(&base.Mask)))
+	value, _ := percent.Float64()
+	return value
+}
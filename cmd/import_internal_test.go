@@ -0,0 +1,46 @@
+package cmd
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestImport(t *testing.T) {
+	got, err := executeCommand("import", "--base", "10.0.0.0/16", "--used-file", "testdata/import.csv")
+	if ExitCode(err) != ExitOverlappingCIDRs {
+		t.Fatalf("want: %v, got: %v (%v)", ExitOverlappingCIDRs, ExitCode(err), err)
+	}
+	want := `line 5: 10.0.1.128/25: overlaps 10.0.1.0/24 (line 4)
+line 6: 10.0.2.5/24: host bits set
+line 7: 10.1.0.0/24: not within the base 10.0.0.0/16
+line 8: not-a-cidr: not a valid CIDR
+3 of 7 entries accepted
+coverage: 2.34%
+free:
+  10.0.2.0/23
+  10.0.8.0/21
+  10.0.16.0/20
+  10.0.32.0/19
+  10.0.64.0/18
+  10.0.128.0/17
+`
+	if !strings.HasPrefix(got, want) {
+		t.Fatalf("want: %v, got: %v", want, got)
+	}
+
+	got, err = executeCommand("import", "--base", "10.0.0.0/16", "--used-file", "testdata/import.csv", "--output", "json")
+	if ExitCode(err) != ExitOverlappingCIDRs {
+		t.Fatalf("want: %v, got: %v (%v)", ExitOverlappingCIDRs, ExitCode(err), err)
+	}
+	var report importReport
+	if unmarshalErr := json.NewDecoder(strings.NewReader(got)).Decode(&report); unmarshalErr != nil {
+		t.Fatalf("unexpected error parsing output: %s", unmarshalErr.Error())
+	}
+	if report.Entries != 7 || report.Accepted != 3 || len(report.Rejected) != 4 || len(report.Free) != 6 {
+		t.Fatalf("want 7 entries with 3 accepted, 4 rejected and 6 free blocks, got: %+v", report)
+	}
+	if report.Rejected[0].Line != 5 || report.Rejected[0].Problems[0] != "overlaps 10.0.1.0/24 (line 4)" {
+		t.Fatalf("want the overlap on line 5 rejected, got: %+v", report.Rejected[0])
+	}
+}
@@ -0,0 +1,111 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/massdriver-cloud/cola/pkg/cidr"
+	"github.com/spf13/cobra"
+)
+
+var monitorAWSVPC string
+var monitorAWSRegion string
+var monitorThreshold float64
+var monitorInterval time.Duration
+
+var monitorCmd = &cobra.Command{
+	Use:   "monitor",
+	Short: "Check how full an AWS VPC is and alert on low capacity",
+	Long: `Check how much of an AWS VPC's primary CIDR block is used by its subnets, reading them with the
+standard AWS credential chain. Each check prints one JSON object on a line, for ingestion by
+monitoring, with the percentage of addresses used and whether it's over --threshold.
+
+By default the VPC is checked once, exiting with code 6 if it's over the threshold, to run from
+cron or an alerting job. With --interval the VPC is checked again after each interval until
+interrupted, and a warning is printed on stderr for every check over the threshold.`,
+	RunE: runMonitor,
+}
+
+func init() {
+	rootCmd.AddCommand(monitorCmd)
+
+	monitorCmd.Flags().StringVar(&monitorAWSVPC, "aws-vpc", "", "AWS VPC to check")
+	monitorCmd.Flags().StringVar(&monitorAWSRegion, "aws-region", "", "AWS region of the VPC")
+	monitorCmd.Flags().Float64Var(&monitorThreshold, "threshold", 80, "Percentage of the VPC used above which capacity is low")
+	monitorCmd.Flags().DurationVar(&monitorInterval, "interval", 0, "Check again after this long, until interrupted (default check once)")
+	_ = monitorCmd.MarkFlagRequired("aws-vpc")
+}
+
+// monitorCheck is the result of a single check of the VPC
+type monitorCheck struct {
+	Time          string  `json:"time"`
+	VPC           string  `json:"vpc"`
+	Base          string  `json:"base"`
+	Subnets       int     `json:"subnets"`
+	Utilization   float64 `json:"utilization_percent"`
+	Threshold     float64 `json:"threshold_percent"`
+	OverThreshold bool    `json:"over_threshold"`
+}
+
+func runMonitor(cmd *cobra.Command, args []string) error {
+	if monitorThreshold <= 0 || monitorThreshold > 100 {
+		return invalidInput(fmt.Errorf("--threshold must be a percentage above 0 and at most 100, got %v", monitorThreshold))
+	}
+	if monitorInterval < 0 {
+		return invalidInput(errors.New("--interval can't be negative"))
+	}
+	ctx := cmd.Context()
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	for {
+		check, err := checkVPCCapacity(ctx)
+		if err != nil {
+			return err
+		}
+		if encodeErr := json.NewEncoder(cmd.OutOrStdout()).Encode(check); encodeErr != nil {
+			return encodeErr
+		}
+
+		if monitorInterval == 0 {
+			if check.OverThreshold {
+				return withExitCode(ExitLowCapacity, fmt.Errorf("VPC %s is %.2f%% used, over the threshold of %.2f%%", check.VPC, check.Utilization, check.Threshold))
+			}
+			return nil
+		}
+		if check.OverThreshold {
+			fmt.Fprintf(cmd.ErrOrStderr(), "warning: VPC %s is %.2f%% used, over the threshold of %.2f%%\n", check.VPC, check.Utilization, check.Threshold)
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(monitorInterval):
+		}
+	}
+}
+
+// checkVPCCapacity reads the VPC and its subnets and works out how much of the VPC is used. Subnets
+// outside the primary CIDR block, such as those in a secondary block, aren't counted.
+func checkVPCCapacity(ctx context.Context) (*monitorCheck, error) {
+	base, subnets, err := loadAWSVPC(ctx, monitorAWSRegion, monitorAWSVPC)
+	if err != nil {
+		return nil, err
+	}
+	// overlapping subnets would be a bug on AWS's side, but shouldn't count twice
+	used := cidr.Canonicalize(cidr.UsedWithin(base, subnets))
+	utilization := coveragePercent(base, used)
+	return &monitorCheck{
+		Time:          time.Now().UTC().Format(time.RFC3339),
+		VPC:           monitorAWSVPC,
+		Base:          base.String(),
+		Subnets:       len(subnets),
+		Utilization:   utilization,
+		Threshold:     monitorThreshold,
+		OverThreshold: utilization > monitorThreshold,
+	}, nil
+}
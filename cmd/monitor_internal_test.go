@@ -0,0 +1,57 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/massdriver-cloud/cola/pkg/awsvpc"
+)
+
+type monitorAWSClient struct{}
+
+func (monitorAWSClient) VPCCIDR(ctx context.Context, vpcID string) (string, error) {
+	return "10.0.0.0/24", nil
+}
+
+// the subnets use three quarters of the VPC, and the one in a secondary block isn't counted
+func (monitorAWSClient) SubnetCIDRs(ctx context.Context, vpcID string) ([]string, error) {
+	return []string{"10.0.0.0/25", "10.0.0.128/26", "10.1.0.0/24"}, nil
+}
+
+func TestMonitor(t *testing.T) {
+	original := newAWSClient
+	defer func() { newAWSClient = original }()
+	newAWSClient = func(ctx context.Context, region string) (awsvpc.Client, error) {
+		return monitorAWSClient{}, nil
+	}
+
+	got, err := executeCommand("monitor", "--aws-vpc", "vpc-123", "--threshold", "80")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err.Error())
+	}
+	var check monitorCheck
+	if err = json.Unmarshal([]byte(got), &check); err != nil {
+		t.Fatalf("unexpected error parsing output: %s", err.Error())
+	}
+	if check.Utilization != 75 || check.OverThreshold {
+		t.Fatalf("want: %v, got: %v", "75% used, under the threshold", got)
+	}
+
+	got, err = executeCommand("monitor", "--aws-vpc", "vpc-123", "--threshold", "70")
+	if ExitCode(err) != ExitLowCapacity {
+		t.Fatalf("want: %v, got: %v (%v)", ExitLowCapacity, ExitCode(err), err)
+	}
+	if err = json.Unmarshal([]byte(strings.SplitN(got, "\n", 2)[0]), &check); err != nil {
+		t.Fatalf("unexpected error parsing output: %s", err.Error())
+	}
+	if check.VPC != "vpc-123" || check.Base != "10.0.0.0/24" || check.Subnets != 3 || !check.OverThreshold {
+		t.Fatalf("want: %v, got: %v", "vpc-123 over the threshold", got)
+	}
+
+	_, err = executeCommand("monitor", "--aws-vpc", "vpc-123", "--threshold", "120")
+	if ExitCode(err) != ExitInvalidInput {
+		t.Fatalf("want: %v, got: %v (%v)", ExitInvalidInput, ExitCode(err), err)
+	}
+}
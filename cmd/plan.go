@@ -0,0 +1,225 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+
+	"github.com/massdriver-cloud/cola/pkg/cidr"
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+)
+
+var planBase string
+var planUsed []string
+var planPrefixes []string
+var planOutput string
+var planProvider string
+
+var planCmd = &cobra.Command{
+	Use:   "plan",
+	Short: "Allocate a set of named subnets from a base CIDR",
+	Long: `Allocate a set of named subnets from a base CIDR, avoiding any used CIDRs.
+
+Subnets are requested as name=prefix pairs, e.g. --prefixes web=24,data=22, and are packed
+largest first so the same request always produces the same plan. The plan can be
+printed as text, as Terraform subnet resources (--output tf) for the selected --provider,
+or as an Ansible vars file (--output ansible) with a subnets list.`,
+	RunE: runPlan,
+}
+
+func init() {
+	rootCmd.AddCommand(planCmd)
+
+	planCmd.Flags().StringVar(&planBase, "base", "", "Base CIDR to allocate subnets from")
+	planCmd.Flags().StringArrayVar(&planUsed, "used", []string{}, "Used CIDRs (repeatable, separated by commas, spaces or newlines)")
+	planCmd.Flags().StringSliceVar(&planPrefixes, "prefixes", []string{}, "Subnets to allocate as name=prefix pairs (repeatable or comma separated)")
+	planCmd.Flags().StringVar(&planOutput, "output", "text", "Output format (text, tf, ansible)")
+	planCmd.Flags().StringVar(&planProvider, "provider", "aws", "Terraform provider for --output tf (aws, gcp, azure)")
+	_ = planCmd.MarkFlagRequired("prefixes")
+}
+
+// plannedSubnet is a named CIDR allocated by a plan
+type plannedSubnet struct {
+	Name string
+	CIDR *net.IPNet
+}
+
+func runPlan(cmd *cobra.Command, args []string) error {
+	base, err := parseBaseCIDR(cmd, planBase)
+	if err != nil {
+		return err
+	}
+	used, err := parseCIDRList(configStringSlice(cmd, "used", planUsed))
+	if err != nil {
+		return err
+	}
+
+	names := make([]string, 0, len(planPrefixes))
+	prefixes := make(map[string]int, len(planPrefixes))
+	for _, request := range planPrefixes {
+		name, prefix, parseErr := parsePrefixRequest(request)
+		if parseErr != nil {
+			return parseErr
+		}
+		if _, exists := prefixes[name]; exists {
+			return invalidInput(fmt.Errorf("subnet %q is requested more than once", name))
+		}
+		names = append(names, name)
+		prefixes[name] = prefix
+	}
+
+	plan, err := cidr.FindTieredPlan(base, prefixes, used)
+	if err != nil {
+		return err
+	}
+	subnets := make([]plannedSubnet, 0, len(names))
+	for _, name := range names {
+		subnets = append(subnets, plannedSubnet{Name: name, CIDR: plan[name]})
+	}
+
+	switch planOutput {
+	case "text":
+		for _, subnet := range subnets {
+			fmt.Fprintf(cmd.OutOrStdout(), "%s\t%s\n", subnet.Name, subnet.CIDR.String())
+		}
+		return nil
+	case "tf":
+		return renderTerraform(cmd.OutOrStdout(), planProvider, subnets)
+	case "ansible":
+		return renderAnsible(cmd.OutOrStdout(), subnets)
+	default:
+		return invalidInput(fmt.Errorf("unsupported output format %q", planOutput))
+	}
+}
+
+// parsePrefixRequest parses a name=prefix pair such as web=24
+func parsePrefixRequest(request string) (string, int, error) {
+	parts := strings.SplitN(strings.TrimSpace(request), "=", 2)
+	if len(parts) != 2 || parts[0] == "" {
+		return "", 0, invalidInput(fmt.Errorf("invalid subnet request %q, expected name=prefix", request))
+	}
+	prefix, err := strconv.Atoi(strings.TrimPrefix(parts[1], "/"))
+	if err != nil {
+		return "", 0, invalidInput(fmt.Errorf("invalid prefix in subnet request %q: %w", request, err))
+	}
+	return parts[0], prefix, nil
+}
+
+// terraformFormat is how a provider's subnet resource is rendered, including the field the CIDR is
+// set in and whether the provider expects it as a single string or a list.
+type terraformFormat struct {
+	resource  string
+	named     bool
+	variables []string
+	cidrField string
+	cidrList  bool
+}
+
+// terraformFormats maps each supported provider to its subnet resource format
+var terraformFormats = map[string]terraformFormat{
+	"aws": {
+		resource:  "aws_subnet",
+		variables: []string{"vpc_id"},
+		cidrField: "cidr_block",
+	},
+	"gcp": {
+		resource:  "google_compute_subnetwork",
+		named:     true,
+		variables: []string{"network"},
+		cidrField: "ip_cidr_range",
+	},
+	"azure": {
+		resource:  "azurerm_subnet",
+		named:     true,
+		variables: []string{"resource_group_name", "virtual_network_name"},
+		cidrField: "address_prefixes",
+		cidrList:  true,
+	},
+}
+
+// cidrValue returns n in the shape the provider expects, e.g. "10.0.1.0/24" or ["10.0.1.0/24"]
+func (f terraformFormat) cidrValue(n *net.IPNet) string {
+	value := strconv.Quote(n.String())
+	if f.cidrList {
+		return "[" + value + "]"
+	}
+	return value
+}
+
+// render writes the subnet resource block, with the attributes aligned as terraform fmt would
+func (f terraformFormat) render(w io.Writer, subnet plannedSubnet) {
+	attributes := [][2]string{}
+	if f.named {
+		attributes = append(attributes, [2]string{"name", strconv.Quote(subnet.Name)})
+	}
+	for _, variable := range f.variables {
+		attributes = append(attributes, [2]string{variable, "var." + variable})
+	}
+	attributes = append(attributes, [2]string{f.cidrField, f.cidrValue(subnet.CIDR)})
+
+	width := 0
+	for _, attribute := range attributes {
+		if len(attribute[0]) > width {
+			width = len(attribute[0])
+		}
+	}
+	fmt.Fprintf(w, "resource %q %q {\n", f.resource, subnet.Name)
+	for _, attribute := range attributes {
+		fmt.Fprintf(w, "  %-*s = %s\n", width, attribute[0], attribute[1])
+	}
+	fmt.Fprintln(w, "}")
+}
+
+func renderTerraform(w io.Writer, provider string, subnets []plannedSubnet) error {
+	format, ok := terraformFormats[provider]
+	if !ok {
+		return invalidInput(fmt.Errorf("unsupported provider %q", provider))
+	}
+	for i, subnet := range subnets {
+		if i > 0 {
+			fmt.Fprintln(w)
+		}
+		format.render(w, subnet)
+	}
+	return nil
+}
+
+// ansibleVars is the Ansible vars file rendered for a plan. It's also the plan file read by drift, and
+// the schema printed by the schema command is derived from it.
+type ansibleVars struct {
+	Subnets []ansibleSubnet `yaml:"subnets"`
+}
+
+type ansibleSubnet struct {
+	Name        string `yaml:"name"`
+	CIDR        string `yaml:"cidr"`
+	Gateway     string `yaml:"gateway,omitempty"`
+	UsableFirst string `yaml:"usable_first,omitempty"`
+	UsableLast  string `yaml:"usable_last,omitempty"`
+}
+
+// renderAnsible writes the subnets as an Ansible vars file. By convention the gateway is the first
+// usable address of each subnet.
+func renderAnsible(w io.Writer, subnets []plannedSubnet) error {
+	vars := ansibleVars{Subnets: make([]ansibleSubnet, 0, len(subnets))}
+	for _, subnet := range subnets {
+		first, last := cidr.UsableRange(subnet.CIDR)
+		vars.Subnets = append(vars.Subnets, ansibleSubnet{
+			Name:        subnet.Name,
+			CIDR:        subnet.CIDR.String(),
+			Gateway:     first.String(),
+			UsableFirst: first.String(),
+			UsableLast:  last.String(),
+		})
+	}
+
+	encoder := yaml.NewEncoder(w)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(vars); err != nil {
+		return err
+	}
+	return encoder.Close()
+}
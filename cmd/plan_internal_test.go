@@ -0,0 +1,178 @@
+package cmd
+
+import (
+	"bytes"
+	"net"
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+func TestPlanTerraform(t *testing.T) {
+	type testData struct {
+		name     string
+		args     []string
+		contains []string
+	}
+	tests := []testData{
+		{
+			name: "AWS",
+			args: []string{"plan", "--base", "10.0.0.0/16", "--used", "10.0.0.0/24", "--prefixes", "web=24,data=22", "--output", "tf"},
+			contains: []string{
+				`resource "aws_subnet" "web" {`,
+				`cidr_block = "10.0.1.0/24"`,
+				`resource "aws_subnet" "data" {`,
+				`cidr_block = "10.0.4.0/22"`,
+			},
+		},
+		{
+			name: "GCP",
+			args: []string{"plan", "--base", "10.0.0.0/16", "--prefixes", "web=24", "--output", "tf", "--provider", "gcp"},
+			contains: []string{
+				`resource "google_compute_subnetwork" "web" {`,
+				`ip_cidr_range = "10.0.0.0/24"`,
+			},
+		},
+		{
+			name: "Azure",
+			args: []string{"plan", "--base", "10.0.0.0/16", "--prefixes", "web=24", "--output", "tf", "--provider", "azure"},
+			contains: []string{
+				`resource "azurerm_subnet" "web" {`,
+				`address_prefixes     = ["10.0.0.0/24"]`,
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := executeCommand(tc.args...)
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err.Error())
+			}
+			for _, want := range tc.contains {
+				if !strings.Contains(got, want) {
+					t.Fatalf("want output containing: %v, got: %v", want, got)
+				}
+			}
+		})
+	}
+}
+
+func TestTerraformFormats(t *testing.T) {
+	type testData struct {
+		provider  string
+		cidrField string
+		cidrValue string
+		want      string
+	}
+	tests := []testData{
+		{
+			provider:  "aws",
+			cidrField: "cidr_block",
+			cidrValue: `"10.0.1.0/24"`,
+			want: `resource "aws_subnet" "web" {
+  vpc_id     = var.vpc_id
+  cidr_block = "10.0.1.0/24"
+}
+`,
+		},
+		{
+			provider:  "gcp",
+			cidrField: "ip_cidr_range",
+			cidrValue: `"10.0.1.0/24"`,
+			want: `resource "google_compute_subnetwork" "web" {
+  name          = "web"
+  network       = var.network
+  ip_cidr_range = "10.0.1.0/24"
+}
+`,
+		},
+		{
+			provider:  "azure",
+			cidrField: "address_prefixes",
+			cidrValue: `["10.0.1.0/24"]`,
+			want: `resource "azurerm_subnet" "web" {
+  name                 = "web"
+  resource_group_name  = var.resource_group_name
+  virtual_network_name = var.virtual_network_name
+  address_prefixes     = ["10.0.1.0/24"]
+}
+`,
+		},
+	}
+
+	_, subnet, _ := net.ParseCIDR("10.0.1.0/24")
+	for _, tc := range tests {
+		t.Run(tc.provider, func(t *testing.T) {
+			format := terraformFormats[tc.provider]
+			if format.cidrField != tc.cidrField {
+				t.Fatalf("want: %v, got: %v", tc.cidrField, format.cidrField)
+			}
+			if got := format.cidrValue(subnet); got != tc.cidrValue {
+				t.Fatalf("want: %v, got: %v", tc.cidrValue, got)
+			}
+
+			buf := new(bytes.Buffer)
+			format.render(buf, plannedSubnet{Name: "web", CIDR: subnet})
+			if buf.String() != tc.want {
+				t.Fatalf("want: %v, got: %v", tc.want, buf.String())
+			}
+		})
+	}
+}
+
+func TestPlanDeterministic(t *testing.T) {
+	args := []string{"plan", "--base", "10.0.0.0/16", "--prefixes", "web=24,data=22,cache=26", "--output", "tf"}
+	first, err := executeCommand(args...)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err.Error())
+	}
+	second, err := executeCommand(args...)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err.Error())
+	}
+	if first != second {
+		t.Fatalf("output changed between runs, first: %v, second: %v", first, second)
+	}
+}
+
+func TestPlanAnsible(t *testing.T) {
+	got, err := executeCommand("plan", "--base", "10.0.0.0/16", "--used", "10.0.0.0/24", "--prefixes", "web=24,data=22", "--output", "ansible")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err.Error())
+	}
+
+	var vars struct {
+		Subnets []map[string]string `yaml:"subnets"`
+	}
+	if unmarshalErr := yaml.Unmarshal([]byte(got), &vars); unmarshalErr != nil {
+		t.Fatalf("unexpected error parsing output: %s", unmarshalErr.Error())
+	}
+	want := []map[string]string{
+		{
+			"name":         "web",
+			"cidr":         "10.0.1.0/24",
+			"gateway":      "10.0.1.1",
+			"usable_first": "10.0.1.1",
+			"usable_last":  "10.0.1.254",
+		},
+		{
+			"name":         "data",
+			"cidr":         "10.0.4.0/22",
+			"gateway":      "10.0.4.1",
+			"usable_first": "10.0.4.1",
+			"usable_last":  "10.0.7.254",
+		},
+	}
+	if len(vars.Subnets) != len(want) {
+		t.Fatalf("want: %v, got: %v", want, vars.Subnets)
+	}
+	for i := range want {
+		for key, value := range want[i] {
+			if vars.Subnets[i][key] != value {
+				t.Fatalf("want %s: %v, got: %v", key, value, vars.Subnets[i][key])
+			}
+		}
+	}
+}
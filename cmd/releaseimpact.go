@@ -0,0 +1,119 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"net"
+
+	"github.com/massdriver-cloud/cola/pkg/cidr"
+	"github.com/spf13/cobra"
+)
+
+var releaseImpactBase string
+var releaseImpactUsed []string
+var releaseImpactUsedFile string
+
+var releaseImpactCmd = &cobra.Command{
+	Use:   "release-impact CIDR",
+	Short: "Show how releasing a used CIDR would change the free space",
+	Long: `Show how the free space of a base CIDR would change if one of the used CIDRs were released:
+the free blocks and largest available block before and after, the number of addresses freed, and
+whether the release makes a larger block available. This helps decide whether reclaiming a
+subnet is worthwhile.`,
+	Args: exactArgs(1),
+	RunE: runReleaseImpact,
+}
+
+func init() {
+	rootCmd.AddCommand(releaseImpactCmd)
+
+	releaseImpactCmd.Flags().StringVar(&releaseImpactBase, "base", "", "Base CIDR the used CIDRs are allocated from")
+	releaseImpactCmd.Flags().StringArrayVar(&releaseImpactUsed, "used", []string{}, "Used CIDRs (repeatable, separated by commas, spaces or newlines)")
+	releaseImpactCmd.Flags().StringVar(&releaseImpactUsedFile, "used-file", "", "File of used CIDRs, one per line")
+}
+
+func runReleaseImpact(cmd *cobra.Command, args []string) error {
+	base, err := parseBaseCIDR(cmd, releaseImpactBase)
+	if err != nil {
+		return err
+	}
+	released, err := parseCIDRs(args)
+	if err != nil {
+		return err
+	}
+	used, err := parseUsedCIDRs(cmd, releaseImpactUsed, releaseImpactUsedFile)
+	if err != nil {
+		return err
+	}
+
+	after := []*net.IPNet{}
+	for _, n := range used {
+		if !cidr.EqualCIDRs(n, released[0]) {
+			after = append(after, n)
+		}
+	}
+	if len(after) == len(used) {
+		return invalidInput(fmt.Errorf("%s is not one of the used CIDRs", released[0].String()))
+	}
+
+	freeBefore, err := cidr.FreeSpace(base, used)
+	if err != nil {
+		return err
+	}
+	freeAfter, err := cidr.FreeSpace(base, after)
+	if err != nil {
+		return err
+	}
+	largestBefore, err := largestBlockOrNil(base, used)
+	if err != nil {
+		return err
+	}
+	largestAfter, err := largestBlockOrNil(base, after)
+	if err != nil {
+		return err
+	}
+
+	freed := new(big.Int).Sub(addressCount(freeAfter), addressCount(freeBefore))
+	enlarged := largestBefore == nil || cidr.SmallerMask(&largestBefore.Mask, &largestAfter.Mask)
+
+	fmt.Fprintf(cmd.OutOrStdout(), "free before:     %s\n", cidrsOrNone(freeBefore))
+	fmt.Fprintf(cmd.OutOrStdout(), "free after:      %s\n", cidrsOrNone(freeAfter))
+	fmt.Fprintf(cmd.OutOrStdout(), "largest before:  %s\n", blockOrNone(largestBefore))
+	fmt.Fprintf(cmd.OutOrStdout(), "largest after:   %s\n", blockOrNone(largestAfter))
+	fmt.Fprintf(cmd.OutOrStdout(), "addresses freed: %s\n", freed.String())
+	fmt.Fprintf(cmd.OutOrStdout(), "larger block:    %t\n", enlarged)
+	return nil
+}
+
+// largestBlockOrNil returns the largest available block of the base, or nil if the base is full
+func largestBlockOrNil(base *net.IPNet, used []*net.IPNet) (*net.IPNet, error) {
+	block, err := cidr.LargestAvailableBlock(base, used)
+	if errors.Is(err, cidr.ErrNoAvailableCIDR) {
+		return nil, nil
+	}
+	return block, err
+}
+
+func blockOrNone(n *net.IPNet) string {
+	if n == nil {
+		return "none"
+	}
+	return n.String()
+}
+
+func cidrsOrNone(cidrs []*net.IPNet) string {
+	if len(cidrs) == 0 {
+		return "none"
+	}
+	return joinCIDRs(cidrs)
+}
+
+// addressCount returns the total number of addresses in the cidrs
+func addressCount(cidrs []*net.IPNet) *big.Int {
+	count := new(big.Int)
+	for _, n := range cidrs {
+		count.Add(count, cidr.BlockSize(&n.Mask))
+	}
+	return count
+}
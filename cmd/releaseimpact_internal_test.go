@@ -0,0 +1,67 @@
+package cmd
+
+import (
+	"testing"
+)
+
+func TestReleaseImpact(t *testing.T) {
+	type testData struct {
+		name string
+		args []string
+		want string
+	}
+	tests := []testData{
+		{
+			name: "Same size block",
+			args: []string{"release-impact", "10.0.64.0/18", "--base", "10.0.0.0/16", "--used", "10.0.0.0/18,10.0.64.0/18,10.0.192.0/18"},
+			want: `free before:     10.0.128.0/18
+free after:      10.0.64.0/18, 10.0.128.0/18
+largest before:  10.0.128.0/18
+largest after:   10.0.64.0/18
+addresses freed: 16384
+larger block:    false
+`,
+		},
+		{
+			name: "Merges with free neighbour",
+			args: []string{"release-impact", "10.0.128.0/18", "--base", "10.0.0.0/16", "--used", "10.0.0.0/17,10.0.128.0/18"},
+			want: `free before:     10.0.192.0/18
+free after:      10.0.128.0/17
+largest before:  10.0.192.0/18
+largest after:   10.0.128.0/17
+addresses freed: 16384
+larger block:    true
+`,
+		},
+		{
+			name: "Full base",
+			args: []string{"release-impact", "10.0.0.0/25", "--base", "10.0.0.0/24", "--used", "10.0.0.0/25,10.0.0.128/25"},
+			want: `free before:     none
+free after:      10.0.0.0/25
+largest before:  none
+largest after:   10.0.0.0/25
+addresses freed: 128
+larger block:    true
+`,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := executeCommand(tc.args...)
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err.Error())
+			}
+			if got != tc.want {
+				t.Fatalf("want: %v, got: %v", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestReleaseImpactNotUsed(t *testing.T) {
+	_, err := executeCommand("release-impact", "10.0.1.0/24", "--base", "10.0.0.0/16", "--used", "10.0.0.0/24")
+	if ExitCode(err) != ExitInvalidInput {
+		t.Fatalf("want: %v, got: %v (%v)", ExitInvalidInput, ExitCode(err), err)
+	}
+}
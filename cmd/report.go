@@ -0,0 +1,142 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/big"
+	"net"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/massdriver-cloud/cola/pkg/cidr"
+	"github.com/spf13/cobra"
+)
+
+var reportBase string
+var reportUsed []string
+var reportUsedFile string
+var reportOutput string
+var reportHistogram bool
+
+var reportCmd = &cobra.Command{
+	Use:   "report",
+	Short: "Summarize used CIDRs by prefix length",
+	Long: `Summarize how a base CIDR is carved up, grouping the used CIDRs by prefix length. For each
+prefix length the number of used CIDRs and the number of additional CIDRs of that size which
+can still be allocated are printed.
+
+With --histogram only the number of used CIDRs of each prefix length is printed, with a bar chart
+when the output is a terminal, to show which sizes dominate the plan.`,
+	RunE: runReport,
+}
+
+func init() {
+	rootCmd.AddCommand(reportCmd)
+
+	reportCmd.Flags().StringVar(&reportBase, "base", "", "Base CIDR the used CIDRs are allocated from")
+	reportCmd.Flags().StringArrayVar(&reportUsed, "used", []string{}, "Used CIDRs (repeatable, separated by commas, spaces or newlines)")
+	reportCmd.Flags().StringVar(&reportUsedFile, "used-file", "", "File of used CIDRs, one per line")
+	reportCmd.Flags().StringVar(&reportOutput, "output", "text", "Output format (text, json)")
+	reportCmd.Flags().BoolVar(&reportHistogram, "histogram", false, "Print the number of used CIDRs of each prefix length")
+}
+
+// reportGroup summarizes the used CIDRs of a single prefix length
+type reportGroup struct {
+	Count int      `json:"count"`
+	Free  *big.Int `json:"free"`
+}
+
+func runReport(cmd *cobra.Command, args []string) error {
+	base, err := parseBaseCIDR(cmd, reportBase)
+	if err != nil {
+		return err
+	}
+	used, err := parseUsedCIDRs(cmd, reportUsed, reportUsedFile)
+	if err != nil {
+		return err
+	}
+
+	if reportHistogram {
+		return writeReportHistogram(cmd.OutOrStdout(), cidr.MaskDistribution(used))
+	}
+
+	_, bits := base.Mask.Size()
+	groups := map[int]reportGroup{}
+	for prefix, count := range cidr.MaskDistribution(used) {
+		mask := net.CIDRMask(prefix, bits)
+		free, capacityErr := cidr.RemainingCapacity(base, &mask, used)
+		if capacityErr != nil {
+			return capacityErr
+		}
+		groups[prefix] = reportGroup{Count: count, Free: free}
+	}
+
+	switch reportOutput {
+	case "text":
+		prefixes := make([]int, 0, len(groups))
+		for prefix := range groups {
+			prefixes = append(prefixes, prefix)
+		}
+		sort.Ints(prefixes)
+		for _, prefix := range prefixes {
+			fmt.Fprintf(cmd.OutOrStdout(), "/%d\t%d used\t%d free\n", prefix, groups[prefix].Count, groups[prefix].Free)
+		}
+		return nil
+	case "json":
+		return json.NewEncoder(cmd.OutOrStdout()).Encode(groups)
+	default:
+		return invalidInput(fmt.Errorf("unsupported output format %q", reportOutput))
+	}
+}
+
+// histogramBarWidth is the width of the longest bar of the histogram chart
+const histogramBarWidth = 40
+
+// writeReportHistogram prints the histogram of used CIDRs by prefix length in the --output format
+func writeReportHistogram(w io.Writer, histogram map[int]int) error {
+	switch reportOutput {
+	case "text":
+		writeHistogram(w, histogram, isTerminal(w))
+		return nil
+	case "json":
+		return json.NewEncoder(w).Encode(histogram)
+	default:
+		return invalidInput(fmt.Errorf("unsupported output format %q", reportOutput))
+	}
+}
+
+// writeHistogram prints the count of each prefix length in ascending order, followed by a bar scaled
+// to the largest count if bars is set
+func writeHistogram(w io.Writer, histogram map[int]int, bars bool) {
+	prefixes := make([]int, 0, len(histogram))
+	largest := 0
+	for prefix, count := range histogram {
+		prefixes = append(prefixes, prefix)
+		if count > largest {
+			largest = count
+		}
+	}
+	sort.Ints(prefixes)
+	for _, prefix := range prefixes {
+		count := histogram[prefix]
+		if !bars {
+			fmt.Fprintf(w, "/%d\t%d\n", prefix, count)
+			continue
+		}
+		// every prefix with a count gets at least one mark
+		width := (count*histogramBarWidth + largest - 1) / largest
+		fmt.Fprintf(w, "/%-4d %6d %s\n", prefix, count, strings.Repeat("#", width))
+	}
+}
+
+// isTerminal returns true if w is a terminal rather than a file, pipe or buffer
+func isTerminal(w io.Writer) bool {
+	file, ok := w.(*os.File)
+	if !ok {
+		return false
+	}
+	info, err := file.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
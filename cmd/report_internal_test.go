@@ -0,0 +1,73 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"math/big"
+	"strings"
+	"testing"
+)
+
+func TestReport(t *testing.T) {
+	args := []string{"report", "--base", "10.0.0.0/16", "--used", "10.0.0.0/18,10.0.64.0/24,10.0.65.0/24,10.0.66.0/28"}
+	got, err := executeCommand(args...)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err.Error())
+	}
+	want := "/18\t1 used\t2 free\n/24\t2 used\t189 free\n/28\t1 used\t3039 free\n"
+	if got != want {
+		t.Fatalf("want: %v, got: %v", want, got)
+	}
+
+	got, err = executeCommand(append(args, "--output", "json")...)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err.Error())
+	}
+	var groups map[int]reportGroup
+	if err = json.Unmarshal([]byte(got), &groups); err != nil {
+		t.Fatalf("unexpected error parsing output: %s", err.Error())
+	}
+	if len(groups) != 3 || groups[24].Count != 2 || groups[24].Free.Cmp(big.NewInt(189)) != 0 {
+		t.Fatalf("want 3 groups with 2 used /24s and 189 free, got: %v", groups)
+	}
+
+	// IPv6 capacity doesn't fit in an int and can't be counted block by block
+	got, err = executeCommand("report", "--base", "2001:db8::/48", "--used", "2001:db8::1/128")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err.Error())
+	}
+	want = "/128\t1 used\t1208925819614629174706175 free\n"
+	if got != want {
+		t.Fatalf("want: %v, got: %v", want, got)
+	}
+}
+
+func TestReportHistogram(t *testing.T) {
+	args := []string{"report", "--base", "10.0.0.0/16", "--used", "10.0.0.0/18,10.0.64.0/24,10.0.65.0/24,10.0.66.0/28,10.0.66.16/28,10.0.66.32/28,10.0.66.48/28", "--histogram"}
+	got, err := executeCommand(args...)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err.Error())
+	}
+	want := "/18\t1\n/24\t2\n/28\t4\n"
+	if got != want {
+		t.Fatalf("want: %v, got: %v", want, got)
+	}
+
+	got, err = executeCommand(append(args, "--output", "json")...)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err.Error())
+	}
+	want = "{\"18\":1,\"24\":2,\"28\":4}\n"
+	if got != want {
+		t.Fatalf("want: %v, got: %v", want, got)
+	}
+}
+
+func TestWriteHistogramBars(t *testing.T) {
+	buf := new(bytes.Buffer)
+	writeHistogram(buf, map[int]int{18: 1, 24: 2, 28: 80}, true)
+	want := "/18        1 #\n/24        2 #\n/28       80 " + strings.Repeat("#", 40) + "\n"
+	if buf.String() != want {
+		t.Fatalf("want: %v, got: %v", want, buf.String())
+	}
+}
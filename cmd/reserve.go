@@ -0,0 +1,61 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/massdriver-cloud/cola/pkg/cidr"
+	"github.com/spf13/cobra"
+)
+
+var reserveBase string
+var reserveUsed []string
+var reserveUsedFile string
+
+var reserveCmd = &cobra.Command{
+	Use:   "reserve CIDR",
+	Short: "Reserve a specific CIDR if it's available",
+	Long: `Reserve exactly the given CIDR within a base CIDR, failing if it's outside the base or
+overlaps any used CIDR. This is useful when importing or migrating networks whose CIDRs must be kept.
+
+A CIDR which overlaps a used CIDR exits with 1 (no available CIDR), and one outside the base or with
+host bits set exits with 2 (invalid input).`,
+	Args: exactArgs(1),
+	RunE: runReserve,
+}
+
+func init() {
+	rootCmd.AddCommand(reserveCmd)
+
+	reserveCmd.Flags().StringVar(&reserveBase, "base", "", "Base CIDR to reserve within")
+	reserveCmd.Flags().StringArrayVar(&reserveUsed, "used", []string{}, "Used CIDRs (repeatable, separated by commas, spaces or newlines)")
+	reserveCmd.Flags().StringVar(&reserveUsedFile, "used-file", "", "File of used CIDRs, one per line")
+}
+
+func runReserve(cmd *cobra.Command, args []string) error {
+	base, err := parseBaseCIDR(cmd, reserveBase)
+	if err != nil {
+		return err
+	}
+	requested, err := parseRawCIDRs(args)
+	if err != nil {
+		return err
+	}
+	used, err := parseUsedCIDRs(cmd, reserveUsed, reserveUsedFile)
+	if err != nil {
+		return err
+	}
+
+	if verifyErr := cidr.VerifyAllocation(base, requested[0], used); verifyErr != nil {
+		err = fmt.Errorf("cannot reserve %s: %w", requested[0].String(), verifyErr)
+		// a requested CIDR which is already taken means there's no space for it, while overlaps
+		// among the used CIDRs themselves are left to validate
+		if errors.Is(err, cidr.ErrOverlappingCIDRs) {
+			return withExitCode(ExitNoAvailableCIDR, err)
+		}
+		return err
+	}
+
+	fmt.Fprintln(cmd.OutOrStdout(), requested[0].String())
+	return nil
+}
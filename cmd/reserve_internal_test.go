@@ -0,0 +1,67 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestReserve(t *testing.T) {
+	usedFile := filepath.Join(t.TempDir(), "used.txt")
+	if err := os.WriteFile(usedFile, []byte("# existing subnets\n10.0.4.0/24\n\n10.0.8.0/22\n"), 0o600); err != nil {
+		t.Fatalf("unexpected error: %s", err.Error())
+	}
+
+	type testData struct {
+		name      string
+		cidr      string
+		want      string
+		wantError string
+	}
+	tests := []testData{
+		{
+			name: "Available",
+			cidr: "10.0.5.0/24",
+			want: "10.0.5.0/24",
+		},
+		{
+			name:      "Outside base",
+			cidr:      "10.1.0.0/24",
+			wantError: "is not within the root CIDR",
+		},
+		{
+			name:      "Overlaps used",
+			cidr:      "10.0.9.0/24",
+			wantError: "overlaps used CIDR 10.0.8.0/22",
+		},
+		{
+			name:      "Contains used",
+			cidr:      "10.0.4.0/23",
+			wantError: "contains used CIDR 10.0.4.0/24",
+		},
+		{
+			name:      "Host bits set",
+			cidr:      "10.0.5.1/24",
+			wantError: "has host bits set",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := executeCommand("reserve", tc.cidr, "--base", "10.0.0.0/16", "--used-file", usedFile)
+			if tc.wantError != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.wantError) {
+					t.Fatalf("Invalid error, want: %v, got %v,", tc.wantError, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err.Error())
+			}
+			if strings.TrimSpace(got) != tc.want {
+				t.Fatalf("want: %v, got: %v", tc.want, got)
+			}
+		})
+	}
+}
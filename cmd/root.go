@@ -20,9 +20,25 @@ var logLevel string
 var rootCmd = &cobra.Command{
 	Use:   "cola",
 	Short: "CIDR Optimization Lookup & Assignment",
-	Long:  `Utility to recommend CIDR ranges based off of current network usage`,
+	Long: `Utility to recommend CIDR ranges based off of current network usage
+
+The --base and --used flags can also be set with the COLA_BASE and COLA_USED environment
+variables or in the config file. Flags take precedence over the environment, which takes
+precedence over the config file.
+
+Exit codes:
+  0  success
+  1  no available CIDR, or the search limit was reached
+  2  invalid input
+  3  overlapping used CIDRs
+  4  internal error
+  5  the used CIDRs have drifted from the plan (drift only)
+  6  the VPC is over the capacity threshold (monitor only)`,
 	RunE: func(cmd *cobra.Command, args []string) error {
-		return errors.New("a subcommand is required")
+		return invalidInput(errors.New("a subcommand is required"))
+	},
+	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+		return invalidInput(checkRequiredFlags(cmd))
 	},
 	SilenceUsage: true,
 }
@@ -39,6 +55,9 @@ func init() {
 
 	rootCmd.PersistentFlags().StringVar(&cfgFile, "config", "", "config file (default is $HOME/.cola.yaml)")
 	rootCmd.PersistentFlags().Bool("debug", false, "Enable debugging logs")
+	rootCmd.SetFlagErrorFunc(func(cmd *cobra.Command, err error) error {
+		return invalidInput(err)
+	})
 }
 
 // initConfig reads in config file and ENV variables if set.
@@ -59,7 +78,11 @@ func initConfig() {
 		viper.SetConfigName(".cola")
 	}
 
-	viper.AutomaticEnv() // read in environment variables that match
+	// read in environment variables that match, prefixed with COLA_ (e.g. COLA_BASE for --base).
+	// Flags set on the command line take precedence over the environment, which takes precedence
+	// over the config file.
+	viper.SetEnvPrefix("cola")
+	viper.AutomaticEnv()
 
 	// If a config file is found, read it in.
 	if err := viper.ReadInConfig(); err == nil {
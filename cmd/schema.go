@@ -0,0 +1,75 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+var schemaCmd = &cobra.Command{
+	Use:   "schema",
+	Short: "Print the JSON Schema of the plan file format",
+	Long: `Print the JSON Schema of the plan file written by plan --output ansible and read by drift, so
+plan files can be validated in an editor or CI before running cola. The schema is derived from the
+same types used to read and write plan files.`,
+	Args: exactArgs(0),
+	RunE: runSchema,
+}
+
+func init() {
+	rootCmd.AddCommand(schemaCmd)
+}
+
+func runSchema(cmd *cobra.Command, args []string) error {
+	schema := jsonSchema(reflect.TypeOf(ansibleVars{}))
+	schema["$schema"] = "https://json-schema.org/draft/2020-12/schema"
+	schema["title"] = "cola plan"
+
+	encoder := json.NewEncoder(cmd.OutOrStdout())
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(schema)
+}
+
+// jsonSchema returns the JSON Schema for values of type t as read from YAML. Struct fields are named by
+// their yaml tags, and are required unless tagged omitempty.
+func jsonSchema(t reflect.Type) map[string]interface{} {
+	switch t.Kind() {
+	case reflect.Struct:
+		properties := map[string]interface{}{}
+		required := []string{}
+		for i := 0; i < t.NumField(); i++ {
+			field := t.Field(i)
+			tag := strings.Split(field.Tag.Get("yaml"), ",")
+			name := tag[0]
+			if name == "" {
+				name = strings.ToLower(field.Name)
+			}
+			properties[name] = jsonSchema(field.Type)
+			if len(tag) < 2 || tag[1] != "omitempty" {
+				required = append(required, name)
+			}
+		}
+		return map[string]interface{}{
+			"type":                 "object",
+			"properties":           properties,
+			"required":             required,
+			"additionalProperties": false,
+		}
+	case reflect.Slice:
+		return map[string]interface{}{
+			"type":  "array",
+			"items": jsonSchema(t.Elem()),
+		}
+	case reflect.String:
+		return map[string]interface{}{"type": "string"}
+	case reflect.Int:
+		return map[string]interface{}{"type": "integer"}
+	case reflect.Bool:
+		return map[string]interface{}{"type": "boolean"}
+	default:
+		panic(fmt.Sprintf("no JSON Schema for %s", t))
+	}
+}
@@ -0,0 +1,115 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+func TestSchema(t *testing.T) {
+	got, err := executeCommand("schema")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err.Error())
+	}
+	var schema map[string]interface{}
+	if unmarshalErr := json.Unmarshal([]byte(got), &schema); unmarshalErr != nil {
+		t.Fatalf("unexpected error parsing output: %s", unmarshalErr.Error())
+	}
+
+	type testData struct {
+		name      string
+		plan      string
+		wantValid bool
+	}
+	tests := []testData{
+		{
+			name: "Plan output",
+			plan: func() string {
+				out, planErr := executeCommand("plan", "--base", "10.0.0.0/16", "--prefixes", "web=24", "--output", "ansible")
+				if planErr != nil {
+					t.Fatalf("unexpected error: %s", planErr.Error())
+				}
+				return out
+			}(),
+			wantValid: true,
+		},
+		{
+			name:      "Names and CIDRs only",
+			plan:      "subnets:\n  - name: web\n    cidr: 10.0.1.0/24\n",
+			wantValid: true,
+		},
+		{
+			name:      "Missing CIDR",
+			plan:      "subnets:\n  - name: web\n",
+			wantValid: false,
+		},
+		{
+			name:      "Misspelled field",
+			plan:      "subnets:\n  - name: web\n    cidr: 10.0.1.0/24\n    gatway: 10.0.1.1\n",
+			wantValid: false,
+		},
+		{
+			name:      "Subnets not a list",
+			plan:      "subnets: web\n",
+			wantValid: false,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			var plan interface{}
+			if unmarshalErr := yaml.Unmarshal([]byte(tc.plan), &plan); unmarshalErr != nil {
+				t.Fatalf("unexpected error parsing plan: %s", unmarshalErr.Error())
+			}
+			problem := checkSchema(schema, plan)
+			if (problem == nil) != tc.wantValid {
+				t.Fatalf("want valid: %v, got: %v", tc.wantValid, problem)
+			}
+		})
+	}
+}
+
+// checkSchema checks value against the subset of JSON Schema printed by the schema command
+func checkSchema(schema map[string]interface{}, value interface{}) error {
+	switch schema["type"] {
+	case "object":
+		object, ok := value.(map[string]interface{})
+		if !ok {
+			return fmt.Errorf("want object, got %v", value)
+		}
+		properties := schema["properties"].(map[string]interface{})
+		for _, name := range schema["required"].([]interface{}) {
+			if _, exists := object[name.(string)]; !exists {
+				return fmt.Errorf("missing %v", name)
+			}
+		}
+		for name, field := range object {
+			property, exists := properties[name]
+			if !exists {
+				return fmt.Errorf("unknown field %v", name)
+			}
+			if problem := checkSchema(property.(map[string]interface{}), field); problem != nil {
+				return problem
+			}
+		}
+	case "array":
+		items, ok := value.([]interface{})
+		if !ok {
+			return fmt.Errorf("want array, got %v", value)
+		}
+		for _, item := range items {
+			if problem := checkSchema(schema["items"].(map[string]interface{}), item); problem != nil {
+				return problem
+			}
+		}
+	case "string":
+		if _, ok := value.(string); !ok {
+			return fmt.Errorf("want string, got %v", value)
+		}
+	default:
+		return fmt.Errorf("unsupported schema type %v", schema["type"])
+	}
+	return nil
+}
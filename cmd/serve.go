@@ -0,0 +1,84 @@
+package cmd
+
+import (
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/massdriver-cloud/cola/pkg/cidr"
+	"github.com/massdriver-cloud/cola/pkg/grpcapi"
+	"github.com/massdriver-cloud/cola/pkg/server"
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/cobra"
+	"google.golang.org/grpc"
+)
+
+const readHeaderTimeout = 10 * time.Second
+
+var serveBase string
+var serveUsed []string
+var serveListen string
+var serveGRPC string
+
+var serveCmd = &cobra.Command{
+	Use:   "serve",
+	Short: "Serve CIDR queries over HTTP and gRPC",
+	Long: `Serve CIDR queries over HTTP for a base CIDR and its used CIDRs.
+
+Endpoints:
+  GET /free?mask=N[&limit=L][&cursor=C]   list available blocks of size /N, paginated
+  GET /find?mask=N[&alternatives=K]       recommend a block of size /N, with up to K alternatives
+
+With --grpc the cola.v1.Allocator gRPC service (Allocate, Release, FreeSpace, Validate) is also
+served on the given address. Messages are JSON encoded with the cola-json codec
+(application/grpc+cola-json), so clients generated with protoc aren't supported. Both APIs share
+one allocator, so CIDRs allocated over gRPC are no longer listed as free over HTTP.`,
+	RunE: runServe,
+}
+
+func init() {
+	rootCmd.AddCommand(serveCmd)
+
+	serveCmd.Flags().StringVar(&serveBase, "base", "", "Base CIDR to serve queries for")
+	serveCmd.Flags().StringArrayVar(&serveUsed, "used", []string{}, "Used CIDRs (repeatable, separated by commas, spaces or newlines)")
+	serveCmd.Flags().StringVar(&serveListen, "listen", ":8080", "Address to listen on")
+	serveCmd.Flags().StringVar(&serveGRPC, "grpc", "", "Address to serve the gRPC allocator service on, e.g. :9090 (disabled if empty)")
+}
+
+func runServe(cmd *cobra.Command, args []string) error {
+	base, err := parseBaseCIDR(cmd, serveBase)
+	if err != nil {
+		return err
+	}
+	used, err := parseCIDRList(configStringSlice(cmd, "used", serveUsed))
+	if err != nil {
+		return err
+	}
+
+	allocator := cidr.NewAllocator(base, used)
+	errs := make(chan error, 2)
+
+	if serveGRPC != "" {
+		listener, listenErr := net.Listen("tcp", serveGRPC)
+		if listenErr != nil {
+			return listenErr
+		}
+		grpcServer := grpc.NewServer()
+		grpcapi.Register(grpcServer, grpcapi.NewServer(allocator))
+		log.Info().Str("address", serveGRPC).Str("base", base.String()).Msg("serving gRPC")
+		go func() {
+			errs <- grpcServer.Serve(listener)
+		}()
+	}
+
+	srv := &http.Server{
+		Addr:              serveListen,
+		Handler:           server.NewWithAllocator(allocator).Handler(),
+		ReadHeaderTimeout: readHeaderTimeout,
+	}
+	log.Info().Str("address", serveListen).Str("base", base.String()).Msg("serving")
+	go func() {
+		errs <- srv.ListenAndServe()
+	}()
+	return <-errs
+}
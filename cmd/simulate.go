@@ -0,0 +1,133 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/massdriver-cloud/cola/pkg/cidr"
+	"github.com/spf13/cobra"
+)
+
+var simulateBase string
+var simulateUsed []string
+var simulateScript string
+
+var simulateCmd = &cobra.Command{
+	Use:   "simulate",
+	Short: "Replay a script of reservations and releases against a base CIDR",
+	Long: `Replay a script of reservations and releases against a base CIDR to see whether the
+pool survives a workload. Each line of the script is one of:
+
+  reserve /24 web   reserve a /24 labelled web
+  release web       release the CIDR labelled web
+
+Blank lines and lines starting with # are ignored. Failed operations are reported and
+skipped, and the final allocations are printed by label.`,
+	RunE: runSimulate,
+}
+
+func init() {
+	rootCmd.AddCommand(simulateCmd)
+
+	simulateCmd.Flags().StringVar(&simulateBase, "base", "", "Base CIDR to allocate from")
+	simulateCmd.Flags().StringArrayVar(&simulateUsed, "used", []string{}, "Used CIDRs (repeatable, separated by commas, spaces or newlines)")
+	simulateCmd.Flags().StringVar(&simulateScript, "script", "", "File of operations to replay")
+	_ = simulateCmd.MarkFlagRequired("script")
+}
+
+func runSimulate(cmd *cobra.Command, args []string) error {
+	base, err := parseBaseCIDR(cmd, simulateBase)
+	if err != nil {
+		return err
+	}
+	used, err := parseCIDRList(configStringSlice(cmd, "used", simulateUsed))
+	if err != nil {
+		return err
+	}
+	script, err := os.ReadFile(simulateScript)
+	if err != nil {
+		return invalidInput(err)
+	}
+
+	allocator := cidr.NewAllocator(base, used)
+	labels := map[string]*net.IPNet{}
+	failures := 0
+	exitCode := ExitSuccess
+	for i, line := range strings.Split(string(script), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if opErr := simulateOperation(allocator, labels, line); opErr != nil {
+			fmt.Fprintf(cmd.OutOrStdout(), "line %d: %s: %s\n", i+1, line, opErr.Error())
+			if failures == 0 {
+				exitCode = ExitCode(opErr)
+			}
+			failures++
+		}
+	}
+
+	printAllocations(cmd.OutOrStdout(), labels)
+	if failures > 0 {
+		// exit with the code of the first failure, so a script that ran out of space exits like find
+		return withExitCode(exitCode, fmt.Errorf("%d operations failed", failures))
+	}
+	return nil
+}
+
+// simulateOperation applies a single script line to the allocator, tracking reservations by label
+func simulateOperation(allocator *cidr.Allocator, labels map[string]*net.IPNet, line string) error {
+	fields := strings.Fields(line)
+	switch {
+	case len(fields) == 3 && fields[0] == "reserve":
+		label := fields[2]
+		if _, exists := labels[label]; exists {
+			return invalidInput(fmt.Errorf("label %q is already reserved", label))
+		}
+		ones, err := strconv.Atoi(strings.TrimPrefix(fields[1], "/"))
+		if err != nil {
+			return invalidInput(fmt.Errorf("invalid prefix %q", fields[1]))
+		}
+		_, bits := allocator.Root().Mask.Size()
+		mask := net.CIDRMask(ones, bits)
+		if mask == nil {
+			return invalidInput(fmt.Errorf("invalid prefix %q", fields[1]))
+		}
+		reserved, err := allocator.Reserve(&mask)
+		if err != nil {
+			return err
+		}
+		labels[label] = reserved
+		return nil
+	case len(fields) == 2 && fields[0] == "release":
+		label := fields[1]
+		reserved, exists := labels[label]
+		if !exists {
+			return invalidInput(fmt.Errorf("label %q is not reserved", label))
+		}
+		delete(labels, label)
+		return allocator.Release(reserved)
+	default:
+		return invalidInput(errors.New("expected 'reserve /N label' or 'release label'"))
+	}
+}
+
+// printAllocations prints each label and its CIDR in ascending address order
+func printAllocations(w io.Writer, labels map[string]*net.IPNet) {
+	names := make([]string, 0, len(labels))
+	for name := range labels {
+		names = append(names, name)
+	}
+	sort.Slice(names, func(i, j int) bool {
+		return cidr.IPToInt(labels[names[i]].IP).Cmp(cidr.IPToInt(labels[names[j]].IP)) < 0
+	})
+	for _, name := range names {
+		fmt.Fprintf(w, "%s\t%s\n", name, labels[name].String())
+	}
+}
@@ -0,0 +1,24 @@
+package cmd
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSimulate(t *testing.T) {
+	got, err := executeCommand("simulate", "--base", "10.0.0.0/20", "--script", "testdata/simulate.txt")
+	if err == nil || err.Error() != "2 operations failed" {
+		t.Fatalf("Invalid error, want: %v, got %v,", "2 operations failed", err)
+	}
+
+	want := `line 10: reserve /21 overflow: unable to find available CIDR range: searched all available ranges could not find space for requested mask
+line 11: release missing: label "missing" is not reserved
+api	10.0.0.0/24
+batch	10.0.2.0/23
+data	10.0.4.0/22
+analytics	10.0.8.0/21
+`
+	if !strings.HasPrefix(got, want) {
+		t.Fatalf("want: %v, got: %v", want, got)
+	}
+}
@@ -0,0 +1,93 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/massdriver-cloud/cola/pkg/cidr"
+	"github.com/spf13/cobra"
+)
+
+var subnetsPrefix int
+var subnetsSkipUsed bool
+var subnetsUsed []string
+var subnetsUsedFile string
+var subnetsOutput string
+
+var subnetsCmd = &cobra.Command{
+	Use:   "subnets PARENT",
+	Short: "List every subnet of a prefix length within a parent CIDR",
+	Long: `List every --prefix sized subnet of the parent CIDR in ascending order, marking each one which
+overlaps a used CIDR as used and the rest as free, to show the template grid and what's taken.
+
+With --skip-used the used subnets are left out and only the free ones are listed. The json output
+is a list of {"cidr", "used"} objects.`,
+	Args: exactArgs(1),
+	RunE: runSubnets,
+}
+
+func init() {
+	rootCmd.AddCommand(subnetsCmd)
+
+	subnetsCmd.Flags().IntVar(&subnetsPrefix, "prefix", 0, "Prefix length of the subnets to list")
+	subnetsCmd.Flags().BoolVar(&subnetsSkipUsed, "skip-used", false, "Leave out subnets overlapping a used CIDR")
+	subnetsCmd.Flags().StringArrayVar(&subnetsUsed, "used", []string{}, "Used CIDRs (repeatable, separated by commas, spaces or newlines)")
+	subnetsCmd.Flags().StringVar(&subnetsUsedFile, "used-file", "", "File of used CIDRs, one per line")
+	subnetsCmd.Flags().StringVar(&subnetsOutput, "output", "text", "Output format (text, json)")
+	_ = subnetsCmd.MarkFlagRequired("prefix")
+}
+
+// subnetEntry is a subnet listed by the subnets command
+type subnetEntry struct {
+	CIDR string `json:"cidr"`
+	Used bool   `json:"used"`
+}
+
+func runSubnets(cmd *cobra.Command, args []string) error {
+	parent, err := parseCIDRs(args)
+	if err != nil {
+		return err
+	}
+	used, err := parseUsedCIDRs(cmd, subnetsUsed, subnetsUsedFile)
+	if err != nil {
+		return err
+	}
+	subnets, err := cidr.SplitByPrefix(parent[0], subnetsPrefix)
+	if err != nil {
+		return err
+	}
+
+	entries := []subnetEntry{}
+	for _, subnet := range subnets {
+		isUsed := false
+		for _, u := range used {
+			if cidr.OverlapsCIDR(subnet, u) {
+				isUsed = true
+				break
+			}
+		}
+		if isUsed && subnetsSkipUsed {
+			continue
+		}
+		entries = append(entries, subnetEntry{CIDR: subnet.String(), Used: isUsed})
+	}
+
+	switch subnetsOutput {
+	case "text":
+		for _, entry := range entries {
+			switch {
+			case subnetsSkipUsed:
+				fmt.Fprintln(cmd.OutOrStdout(), entry.CIDR)
+			case entry.Used:
+				fmt.Fprintf(cmd.OutOrStdout(), "%s\tused\n", entry.CIDR)
+			default:
+				fmt.Fprintf(cmd.OutOrStdout(), "%s\tfree\n", entry.CIDR)
+			}
+		}
+		return nil
+	case "json":
+		return json.NewEncoder(cmd.OutOrStdout()).Encode(entries)
+	default:
+		return invalidInput(fmt.Errorf("unsupported output format %q", subnetsOutput))
+	}
+}
@@ -0,0 +1,58 @@
+package cmd
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestSubnets(t *testing.T) {
+	type testData struct {
+		name string
+		args []string
+		want string
+	}
+	tests := []testData{
+		{
+			name: "Full listing",
+			args: []string{"subnets", "10.0.0.0/16", "--prefix", "18", "--used", "10.0.0.0/18,10.0.130.0/24"},
+			want: "10.0.0.0/18\tused\n10.0.64.0/18\tfree\n10.0.128.0/18\tused\n10.0.192.0/18\tfree\n",
+		},
+		{
+			name: "Skip used",
+			args: []string{"subnets", "10.0.0.0/16", "--prefix", "18", "--used", "10.0.0.0/18,10.0.130.0/24", "--skip-used"},
+			want: "10.0.64.0/18\n10.0.192.0/18\n",
+		},
+		{
+			name: "Used larger than the subnets",
+			args: []string{"subnets", "10.0.0.0/16", "--prefix", "18", "--used", "10.0.0.0/17"},
+			want: "10.0.0.0/18\tused\n10.0.64.0/18\tused\n10.0.128.0/18\tfree\n10.0.192.0/18\tfree\n",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := executeCommand(tc.args...)
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err.Error())
+			}
+			if got != tc.want {
+				t.Fatalf("want: %v, got: %v", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestSubnetsJSON(t *testing.T) {
+	got, err := executeCommand("subnets", "10.0.0.0/16", "--prefix", "17", "--used", "10.0.130.0/24", "--output", "json")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err.Error())
+	}
+	var entries []subnetEntry
+	if err = json.Unmarshal([]byte(got), &entries); err != nil {
+		t.Fatalf("unexpected error parsing output: %s", err.Error())
+	}
+	want := []subnetEntry{{CIDR: "10.0.0.0/17", Used: false}, {CIDR: "10.0.128.0/17", Used: true}}
+	if len(entries) != len(want) || entries[0] != want[0] || entries[1] != want[1] {
+		t.Fatalf("want: %v, got: %v", want, entries)
+	}
+}
@@ -0,0 +1,32 @@
+package cmd
+
+import (
+	"fmt"
+	"net"
+
+	"github.com/massdriver-cloud/cola/pkg/cidr"
+	"github.com/spf13/cobra"
+)
+
+var toIntCmd = &cobra.Command{
+	Use:   "toint CIDR",
+	Short: "Print the first and last addresses of a CIDR as integers",
+	Long:  `Print the first and last addresses of a CIDR as unsigned integers, suitable for storing ranges in a database and querying with BETWEEN`,
+	Args:  exactArgs(1),
+	RunE:  runToInt,
+}
+
+func init() {
+	rootCmd.AddCommand(toIntCmd)
+}
+
+func runToInt(cmd *cobra.Command, args []string) error {
+	_, n, err := net.ParseCIDR(args[0])
+	if err != nil {
+		return invalidInput(err)
+	}
+
+	first, last := cidr.CIDRToIntRange(n)
+	fmt.Fprintln(cmd.OutOrStdout(), first.String(), last.String())
+	return nil
+}
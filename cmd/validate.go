@@ -0,0 +1,147 @@
+package cmd
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"strings"
+
+	"github.com/massdriver-cloud/cola/pkg/cidr"
+	"github.com/spf13/cobra"
+)
+
+var validateBase string
+var validateUsed []string
+var validateUsedFile string
+var validateMinSize int
+
+var validateCmd = &cobra.Command{
+	Use:   "validate",
+	Short: "Report every problem with a set of used CIDRs",
+	Long: `Check the used CIDRs against the base CIDR and report every problem at once: a base or entries
+with host bits set, a base in link-local, multicast or loopback address space, entries outside the
+base, and overlapping entries. With --min-size, entries smaller than that prefix length are
+reported too, e.g. --min-size 27 reports a /28.
+
+Very large sets of used CIDRs can be streamed from --used-file, one CIDR per line, instead of being
+passed with --used. Anything after a # or , on a line is ignored.`,
+	RunE: runValidate,
+}
+
+func init() {
+	rootCmd.AddCommand(validateCmd)
+
+	validateCmd.Flags().StringVar(&validateBase, "base", "", "Base CIDR the used CIDRs are allocated from")
+	validateCmd.Flags().StringArrayVar(&validateUsed, "used", []string{}, "Used CIDRs (repeatable, separated by commas, spaces or newlines)")
+	validateCmd.Flags().StringVar(&validateUsedFile, "used-file", "", "File of used CIDRs to stream, one per line")
+	validateCmd.Flags().IntVar(&validateMinSize, "min-size", 0, "Report used CIDRs with a prefix length longer than this")
+}
+
+func runValidate(cmd *cobra.Command, args []string) error {
+	base, err := parseRawBaseCIDR(cmd, validateBase)
+	if err != nil {
+		return err
+	}
+
+	if cidr.AddressClass(base) == cidr.AddressClassDocumentation {
+		fmt.Fprintf(cmd.ErrOrStderr(), "warning: base CIDR %s is in documentation address space, which isn't routable\n", base.String())
+	}
+
+	var errs []error
+	if validateUsedFile != "" {
+		errs, err = validateUsedStream(cmd, base)
+	} else {
+		errs, err = validateUsedFlags(cmd, base)
+	}
+	if err != nil {
+		return err
+	}
+	if specialErr := cidr.RejectSpecialUse(base); specialErr != nil {
+		errs = append([]error{specialErr}, errs...)
+	}
+	if len(errs) == 0 {
+		fmt.Fprintln(cmd.OutOrStdout(), "used CIDRs are valid")
+		return nil
+	}
+
+	for _, e := range errs {
+		fmt.Fprintln(cmd.OutOrStdout(), e.Error())
+	}
+	code := ExitInvalidInput
+	for _, e := range errs {
+		if errors.Is(e, cidr.ErrOverlappingCIDRs) {
+			code = ExitOverlappingCIDRs
+		}
+	}
+	return withExitCode(code, fmt.Errorf("found %d problems with the used CIDRs", len(errs)))
+}
+
+// validateUsedFlags checks the used CIDRs given with --used or the config
+func validateUsedFlags(cmd *cobra.Command, base *net.IPNet) ([]error, error) {
+	used, err := parseRawCIDRList(configStringSlice(cmd, "used", validateUsed))
+	if err != nil {
+		return nil, err
+	}
+	errs := cidr.ValidateUsedCIDRsAll(base, used)
+	if cmd.Flags().Changed("min-size") {
+		for _, n := range cidr.EnforceMinSize(used, validateMinSize) {
+			errs = append(errs, minSizeError(n, 0))
+		}
+	}
+	return errs, nil
+}
+
+// validateUsedStream checks the used CIDRs in --used-file without reading the whole file into memory
+func validateUsedStream(cmd *cobra.Command, base *net.IPNet) ([]error, error) {
+	if cmd.Flags().Changed("used") {
+		return nil, invalidInput(errors.New("--used and --used-file can't be combined"))
+	}
+	file, err := os.Open(validateUsedFile)
+	if err != nil {
+		return nil, invalidInput(err)
+	}
+	defer file.Close()
+
+	errs, err := cidr.ValidateStream(base, file)
+	if err != nil {
+		return nil, invalidInput(err)
+	}
+	if !cmd.Flags().Changed("min-size") {
+		return errs, nil
+	}
+
+	// check the sizes in a second pass, so the file still doesn't need to be held in memory
+	if _, seekErr := file.Seek(0, io.SeekStart); seekErr != nil {
+		return nil, invalidInput(seekErr)
+	}
+	scanner := bufio.NewScanner(file)
+	for line := 1; scanner.Scan(); line++ {
+		value := strings.TrimSpace(scanner.Text())
+		if value == "" || strings.HasPrefix(value, "#") {
+			continue
+		}
+		value, _ = splitCIDRLine(value)
+		// invalid lines were already reported by ValidateStream
+		if _, n, parseErr := net.ParseCIDR(value); parseErr == nil {
+			for _, violation := range cidr.EnforceMinSize([]*net.IPNet{n}, validateMinSize) {
+				errs = append(errs, minSizeError(violation, line))
+			}
+		}
+	}
+	if scanErr := scanner.Err(); scanErr != nil {
+		return nil, invalidInput(scanErr)
+	}
+	return errs, nil
+}
+
+// minSizeError is the problem reported for a used CIDR smaller than --min-size, naming its line if
+// it was read from --used-file
+func minSizeError(n *net.IPNet, line int) error {
+	if line > 0 {
+		return fmt.Errorf("%w: line %d: used CIDR %s is smaller than the minimum size /%d", cidr.ErrInvalidInputRanges, line, n.String(), validateMinSize)
+	}
+	return fmt.Errorf("%w: used CIDR %s is smaller than the minimum size /%d", cidr.ErrInvalidInputRanges, n.String(), validateMinSize)
+}
@@ -0,0 +1,109 @@
+package cmd
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestValidateDocumentationWarning(t *testing.T) {
+	type testData struct {
+		name     string
+		base     string
+		wantWarn bool
+	}
+	tests := []testData{
+		{
+			name:     "IPv4 documentation",
+			base:     "192.0.2.0/24",
+			wantWarn: true,
+		},
+		{
+			name:     "IPv6 documentation",
+			base:     "2001:db8::/48",
+			wantWarn: true,
+		},
+		{
+			name:     "Private",
+			base:     "10.0.0.0/16",
+			wantWarn: false,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := executeCommand("validate", "--base", tc.base)
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err.Error())
+			}
+			gotWarn := strings.Contains(got, "documentation address space")
+			if gotWarn != tc.wantWarn {
+				t.Fatalf("want warning: %v, got: %v", tc.wantWarn, got)
+			}
+		})
+	}
+}
+
+func TestValidateNonCanonicalBase(t *testing.T) {
+	got, err := executeCommand("validate", "--base", "10.0.5.0/16", "--used", "10.0.0.0/24")
+	if ExitCode(err) != ExitInvalidInput {
+		t.Fatalf("want: %v, got: %v (%v)", ExitInvalidInput, ExitCode(err), err)
+	}
+	if !strings.Contains(got, "root CIDR 10.0.5.0/16 has host bits set") {
+		t.Fatalf("want: host bits problem reported, got: %v", got)
+	}
+}
+
+func TestValidateUsedFile(t *testing.T) {
+	got, err := executeCommand("validate", "--base", "10.0.0.0/16", "--used-file", "testdata/validate.txt")
+	if ExitCode(err) != ExitOverlappingCIDRs {
+		t.Fatalf("want: %v, got: %v (%v)", ExitOverlappingCIDRs, ExitCode(err), err)
+	}
+	want := "CIDR ranges overlap: line 4: used CIDR 10.0.0.0/25 overlaps 10.0.0.0/24 on line 2\n"
+	if !strings.HasPrefix(got, want) {
+		t.Fatalf("want: %v, got: %v", want, got)
+	}
+
+	_, err = executeCommand("validate", "--base", "10.0.0.0/16", "--used-file", "testdata/validate.txt", "--used", "10.0.0.0/24")
+	if ExitCode(err) != ExitInvalidInput {
+		t.Fatalf("want: %v, got: %v (%v)", ExitInvalidInput, ExitCode(err), err)
+	}
+}
+
+func TestValidateSpecialUseBase(t *testing.T) {
+	got, err := executeCommand("validate", "--base", "224.0.0.0/16", "--used", "224.0.1.0/24")
+	if ExitCode(err) != ExitInvalidInput {
+		t.Fatalf("want: %v, got: %v (%v)", ExitInvalidInput, ExitCode(err), err)
+	}
+	want := "input ranges invalid: base CIDR 224.0.0.0/16 is in multicast address space, which can't be allocated from\n"
+	if !strings.HasPrefix(got, want) {
+		t.Fatalf("want: %v, got: %v", want, got)
+	}
+}
+
+func TestValidateMinSize(t *testing.T) {
+	got, err := executeCommand("validate", "--base", "10.0.0.0/16", "--used", "10.0.0.0/24,10.0.1.0/28,10.0.1.32/27", "--min-size", "27")
+	if ExitCode(err) != ExitInvalidInput {
+		t.Fatalf("want: %v, got: %v (%v)", ExitInvalidInput, ExitCode(err), err)
+	}
+	want := "input ranges invalid: used CIDR 10.0.1.0/28 is smaller than the minimum size /27\n"
+	if !strings.HasPrefix(got, want) {
+		t.Fatalf("want: %v, got: %v", want, got)
+	}
+
+	got, err = executeCommand("validate", "--base", "10.0.0.0/16", "--used-file", "testdata/validate.txt", "--min-size", "24")
+	if ExitCode(err) != ExitOverlappingCIDRs {
+		t.Fatalf("want: %v, got: %v (%v)", ExitOverlappingCIDRs, ExitCode(err), err)
+	}
+	want = "CIDR ranges overlap: line 4: used CIDR 10.0.0.0/25 overlaps 10.0.0.0/24 on line 2\ninput ranges invalid: line 4: used CIDR 10.0.0.0/25 is smaller than the minimum size /24\n"
+	if !strings.HasPrefix(got, want) {
+		t.Fatalf("want: %v, got: %v", want, got)
+	}
+
+	got, err = executeCommand("validate", "--base", "10.0.0.0/16", "--used", "10.0.0.0/24", "--min-size", "24")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if got != "used CIDRs are valid\n" {
+		t.Fatalf("want: %v, got: %v", "used CIDRs are valid\n", got)
+	}
+}
@@ -23,7 +23,7 @@ func main() {
 
 	// Run application
 	if err := cmd.Execute(); err != nil {
-		exitCode = 1
+		exitCode = cmd.ExitCode(err)
 		return
 	}
 }
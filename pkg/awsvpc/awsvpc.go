@@ -0,0 +1,53 @@
+// Package awsvpc reads the CIDR ranges already allocated in an AWS VPC, so they can be used as the base
+// and used CIDRs when finding an available CIDR.
+//
+// The package only depends on the small Client interface. An implementation backed by the AWS SDK, which
+// honors the standard AWS credential chain, is provided by NewClient when built with the aws build tag.
+package awsvpc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+)
+
+// ErrNoVPC is returned when the VPC can't be found.
+var ErrNoVPC = errors.New("VPC not found")
+
+// Client describes the networks in an AWS account.
+type Client interface {
+	// VPCCIDR returns the primary CIDR block of the VPC.
+	VPCCIDR(ctx context.Context, vpcID string) (string, error)
+	// SubnetCIDRs returns the IPv4 CIDR block of every subnet in the VPC. Subnets without one, such as
+	// IPv6-only subnets, are skipped.
+	SubnetCIDRs(ctx context.Context, vpcID string) ([]string, error)
+}
+
+// LoadVPC returns the primary CIDR block of the VPC as the base CIDR and the CIDR blocks of its subnets
+// as the used CIDRs.
+func LoadVPC(ctx context.Context, client Client, vpcID string) (*net.IPNet, []*net.IPNet, error) {
+	vpcCIDR, err := client.VPCCIDR(ctx, vpcID)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to describe VPC %s: %w", vpcID, err)
+	}
+	_, base, err := net.ParseCIDR(vpcCIDR)
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid CIDR %q for VPC %s: %w", vpcCIDR, vpcID, err)
+	}
+
+	subnetCIDRs, err := client.SubnetCIDRs(ctx, vpcID)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to describe subnets of VPC %s: %w", vpcID, err)
+	}
+	used := make([]*net.IPNet, 0, len(subnetCIDRs))
+	for _, subnetCIDR := range subnetCIDRs {
+		_, subnet, parseErr := net.ParseCIDR(subnetCIDR)
+		if parseErr != nil {
+			return nil, nil, fmt.Errorf("invalid CIDR %q for subnet in VPC %s: %w", subnetCIDR, vpcID, parseErr)
+		}
+		used = append(used, subnet)
+	}
+
+	return base, used, nil
+}
@@ -0,0 +1,91 @@
+package awsvpc_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/massdriver-cloud/cola/pkg/awsvpc"
+)
+
+type mockClient struct {
+	vpcs    map[string]string
+	subnets map[string][]string
+}
+
+func (m *mockClient) VPCCIDR(ctx context.Context, vpcID string) (string, error) {
+	vpcCIDR, ok := m.vpcs[vpcID]
+	if !ok {
+		return "", fmt.Errorf("%w: %s", awsvpc.ErrNoVPC, vpcID)
+	}
+	return vpcCIDR, nil
+}
+
+func (m *mockClient) SubnetCIDRs(ctx context.Context, vpcID string) ([]string, error) {
+	return m.subnets[vpcID], nil
+}
+
+func TestLoadVPC(t *testing.T) {
+	client := &mockClient{
+		vpcs: map[string]string{
+			"vpc-123": "10.0.0.0/16",
+			"vpc-bad": "10.0.0.0/16",
+		},
+		subnets: map[string][]string{
+			"vpc-123": {"10.0.0.0/24", "10.0.1.0/24"},
+			"vpc-bad": {"not-a-cidr"},
+		},
+	}
+
+	type testData struct {
+		name      string
+		vpcID     string
+		wantBase  string
+		wantUsed  []string
+		wantError error
+	}
+	tests := []testData{
+		{
+			name:     "Subnets",
+			vpcID:    "vpc-123",
+			wantBase: "10.0.0.0/16",
+			wantUsed: []string{"10.0.0.0/24", "10.0.1.0/24"},
+		},
+		{
+			name:      "Error missing VPC",
+			vpcID:     "vpc-456",
+			wantError: awsvpc.ErrNoVPC,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			base, used, err := awsvpc.LoadVPC(context.Background(), client, tc.vpcID)
+			if tc.wantError != nil {
+				if !errors.Is(err, tc.wantError) {
+					t.Fatalf("Invalid error, want: %v, got %v,", tc.wantError, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %s,", err.Error())
+			}
+			if base.String() != tc.wantBase {
+				t.Fatalf("want: %v, got: %v", tc.wantBase, base.String())
+			}
+			if len(used) != len(tc.wantUsed) {
+				t.Fatalf("want: %v, got: %v", tc.wantUsed, used)
+			}
+			for i := range used {
+				if used[i].String() != tc.wantUsed[i] {
+					t.Fatalf("want: %v, got: %v", tc.wantUsed[i], used[i].String())
+				}
+			}
+		})
+	}
+
+	if _, _, err := awsvpc.LoadVPC(context.Background(), client, "vpc-bad"); err == nil {
+		t.Fatalf("expected error for invalid subnet CIDR")
+	}
+}
@@ -0,0 +1,60 @@
+//go:build aws
+// +build aws
+
+package awsvpc
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
+)
+
+type sdkClient struct {
+	ec2 *ec2.Client
+}
+
+// NewClient returns a Client for the region using the AWS SDK and the standard AWS credential chain.
+func NewClient(ctx context.Context, region string) (Client, error) {
+	cfg, err := config.LoadDefaultConfig(ctx, config.WithRegion(region))
+	if err != nil {
+		return nil, err
+	}
+	return &sdkClient{ec2: ec2.NewFromConfig(cfg)}, nil
+}
+
+func (c *sdkClient) VPCCIDR(ctx context.Context, vpcID string) (string, error) {
+	out, err := c.ec2.DescribeVpcs(ctx, &ec2.DescribeVpcsInput{VpcIds: []string{vpcID}})
+	if err != nil {
+		return "", err
+	}
+	if len(out.Vpcs) == 0 {
+		return "", fmt.Errorf("%w: %s", ErrNoVPC, vpcID)
+	}
+	return aws.ToString(out.Vpcs[0].CidrBlock), nil
+}
+
+func (c *sdkClient) SubnetCIDRs(ctx context.Context, vpcID string) ([]string, error) {
+	input := &ec2.DescribeSubnetsInput{
+		Filters: []types.Filter{{Name: aws.String("vpc-id"), Values: []string{vpcID}}},
+	}
+	cidrs := []string{}
+	paginator := ec2.NewDescribeSubnetsPaginator(c.ec2, input)
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, err
+		}
+		for _, subnet := range page.Subnets {
+			// IPv6-only subnets have no IPv4 CIDR block
+			if subnet.CidrBlock == nil {
+				continue
+			}
+			cidrs = append(cidrs, aws.ToString(subnet.CidrBlock))
+		}
+	}
+	return cidrs, nil
+}
@@ -0,0 +1,37 @@
+package cidr
+
+import (
+	"net"
+)
+
+// ACLEntries returns the fewest CIDRs to allow, covering the used CIDRs within the root CIDR, and to
+// deny, covering the rest of the root CIDR, for pasting into a firewall or router ACL. Both lists are
+// in ascending order. Used CIDRs are clipped to the root CIDR, so one outside it is ignored and one
+// containing it allows the whole root. An ErrInvalidInputRanges error is returned if the used CIDRs
+// aren't from the root CIDR's address family.
+func ACLEntries(rootCIDR *net.IPNet, usedCIDRs []*net.IPNet) ([]*net.IPNet, []*net.IPNet, error) {
+	root, _, used, err := normalizeInputs(rootCIDR, &rootCIDR.Mask, usedCIDRs)
+	if err != nil {
+		return nil, nil, err
+	}
+	root = canonical(root)
+
+	within := []*net.IPNet{}
+	for _, u := range used {
+		switch {
+		case ContainsCIDR(root, u):
+			within = append(within, u)
+		case ContainsCIDR(u, root):
+			within = append(within, root)
+		}
+	}
+	allow, err := Aggregate(within)
+	if err != nil {
+		return nil, nil, err
+	}
+	deny, err := FreeSpace(root, within)
+	if err != nil {
+		return nil, nil, err
+	}
+	return allow, deny, nil
+}
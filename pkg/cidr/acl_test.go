@@ -0,0 +1,93 @@
+package cidr_test
+
+import (
+	"errors"
+	"net"
+	"testing"
+
+	"github.com/massdriver-cloud/cola/pkg/cidr"
+)
+
+func TestACLEntries(t *testing.T) {
+	type testData struct {
+		name      string
+		rootCIDR  string
+		usedCIDRs []string
+		wantAllow []string
+		wantDeny  []string
+		wantError error
+	}
+	tests := []testData{
+		{
+			name:      "Merges adjacent and nested used CIDRs",
+			rootCIDR:  "10.0.0.0/16",
+			usedCIDRs: []string{"10.0.1.0/24", "10.0.0.0/24", "10.0.0.128/25", "10.0.4.0/22"},
+			wantAllow: []string{"10.0.0.0/23", "10.0.4.0/22"},
+			wantDeny:  []string{"10.0.2.0/23", "10.0.8.0/21", "10.0.16.0/20", "10.0.32.0/19", "10.0.64.0/18", "10.0.128.0/17"},
+		},
+		{
+			name:      "Clipped to the root",
+			rootCIDR:  "10.0.0.0/22",
+			usedCIDRs: []string{"10.0.0.0/23", "10.1.0.0/16"},
+			wantAllow: []string{"10.0.0.0/23"},
+			wantDeny:  []string{"10.0.2.0/23"},
+		},
+		{
+			name:      "Used contains the root",
+			rootCIDR:  "10.0.0.0/22",
+			usedCIDRs: []string{"10.0.0.0/8"},
+			wantAllow: []string{"10.0.0.0/22"},
+			wantDeny:  []string{},
+		},
+		{
+			name:      "Nothing used",
+			rootCIDR:  "10.0.0.0/22",
+			usedCIDRs: []string{},
+			wantAllow: []string{},
+			wantDeny:  []string{"10.0.0.0/22"},
+		},
+		{
+			name:      "Error mixed families",
+			rootCIDR:  "10.0.0.0/22",
+			usedCIDRs: []string{"2001:db8::/64"},
+			wantError: cidr.ErrInvalidInputRanges,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			_, rootCIDR, _ := net.ParseCIDR(tc.rootCIDR)
+			usedCIDRs := make([]*net.IPNet, len(tc.usedCIDRs))
+			for i, usedCIDR := range tc.usedCIDRs {
+				_, usedCIDR, _ := net.ParseCIDR(usedCIDR)
+				usedCIDRs[i] = usedCIDR
+			}
+			allow, deny, err := cidr.ACLEntries(rootCIDR, usedCIDRs)
+			if tc.wantError != nil {
+				if !errors.Is(err, tc.wantError) {
+					t.Fatalf("Invalid error, want: %v, got %v,", tc.wantError, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %s,", err.Error())
+			}
+			if len(allow) != len(tc.wantAllow) {
+				t.Fatalf("want: %v, got: %v", tc.wantAllow, allow)
+			}
+			for i := range allow {
+				if allow[i].String() != tc.wantAllow[i] {
+					t.Fatalf("want: %v, got: %v", tc.wantAllow[i], allow[i].String())
+				}
+			}
+			if len(deny) != len(tc.wantDeny) {
+				t.Fatalf("want: %v, got: %v", tc.wantDeny, deny)
+			}
+			for i := range deny {
+				if deny[i].String() != tc.wantDeny[i] {
+					t.Fatalf("want: %v, got: %v", tc.wantDeny[i], deny[i].String())
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,138 @@
+package cidr
+
+import (
+	"math/big"
+	"net"
+	"sort"
+	"strings"
+)
+
+// CIDRWithMeta is a CIDR with a free form description, such as a subnet name or owner, which is kept
+// with the CIDR through operations like AggregateWithMeta.
+type CIDRWithMeta struct {
+	CIDR *net.IPNet
+	Meta string
+}
+
+// Aggregate returns the minimal list of CIDRs, in ascending order, which exactly covers the same
+// addresses as cidrs. Overlapping and adjacent CIDRs are merged, so 10.0.0.0/24 and 10.0.1.0/24
+// become 10.0.0.0/23.
+func Aggregate(cidrs []*net.IPNet) ([]*net.IPNet, error) {
+	withMeta := make([]CIDRWithMeta, len(cidrs))
+	for i, n := range cidrs {
+		withMeta[i] = CIDRWithMeta{CIDR: n}
+	}
+	aggregated, err := AggregateWithMeta(withMeta)
+	if err != nil {
+		return nil, err
+	}
+	result := make([]*net.IPNet, len(aggregated))
+	for i, n := range aggregated {
+		result[i] = n.CIDR
+	}
+	return result, nil
+}
+
+// Canonicalize cleans up untrusted CIDRs into the minimal sorted set covering the same addresses, ready
+// to pass to FindAvailableCIDR as used CIDRs: host bits are cleared, IPv4-mapped IPv6 networks are
+// converted to IPv4, duplicates and contained CIDRs are dropped, and adjacent CIDRs are merged with
+// Aggregate. IPv4 CIDRs sort before IPv6 ones. Entries without a valid mask, which cover no addresses,
+// are dropped.
+func Canonicalize(cidrs []*net.IPNet) []*net.IPNet {
+	valid := make([]*net.IPNet, 0, len(cidrs))
+	for _, n := range cidrs {
+		if n == nil {
+			continue
+		}
+		if _, bits := n.Mask.Size(); bits == 0 {
+			continue
+		}
+		valid = append(valid, n)
+	}
+	// every merged range is within the address family of the valid CIDRs it came from, so it always
+	// converts back to CIDRs
+	canonicalized, _ := Aggregate(valid)
+	return canonicalized
+}
+
+// Summarize collapses cidrs into the minimal sorted set covering the same addresses, as in route
+// summarization: sibling CIDRs are merged into their parent, repeatedly, and CIDRs within another are
+// dropped, so 10.0.0.0/25 and 10.0.0.128/25 become 10.0.0.0/24. It's Canonicalize under the name
+// network tooling usually gives it, and the result is the same whatever order cidrs are in.
+func Summarize(cidrs []*net.IPNet) []*net.IPNet {
+	return Canonicalize(cidrs)
+}
+
+// AggregateWithMeta is Aggregate keeping the metadata: a CIDR which isn't merged keeps its own, and
+// a merged CIDR gets the metadata of every CIDR it overlaps joined with " + ", in the order given.
+func AggregateWithMeta(cidrs []CIDRWithMeta) ([]CIDRWithMeta, error) {
+	type interval struct {
+		bits        int
+		first, last *big.Int
+	}
+	normalized := make([]CIDRWithMeta, len(cidrs))
+	intervals := make([]interval, len(cidrs))
+	for i, n := range cidrs {
+		normalized[i] = CIDRWithMeta{CIDR: canonicalForm(n.CIDR), Meta: n.Meta}
+		first, last := CIDRToIntRange(normalized[i].CIDR)
+		_, bits := normalized[i].CIDR.Mask.Size()
+		intervals[i] = interval{bits: bits, first: first, last: last}
+	}
+	sort.Slice(intervals, func(i, j int) bool {
+		if intervals[i].bits != intervals[j].bits {
+			return intervals[i].bits < intervals[j].bits
+		}
+		return intervals[i].first.Cmp(intervals[j].first) < 0
+	})
+
+	// merge the overlapping and adjacent ranges of each address family
+	one := big.NewInt(1)
+	merged := []interval{}
+	for _, current := range intervals {
+		if len(merged) > 0 {
+			previous := &merged[len(merged)-1]
+			if previous.bits == current.bits && new(big.Int).Add(previous.last, one).Cmp(current.first) >= 0 {
+				if current.last.Cmp(previous.last) > 0 {
+					previous.last = current.last
+				}
+				continue
+			}
+		}
+		merged = append(merged, interval{bits: current.bits, first: new(big.Int).Set(current.first), last: current.last})
+	}
+
+	result := []CIDRWithMeta{}
+	for _, m := range merged {
+		firstIP, err := IntToIP(m.first, m.bits)
+		if err != nil {
+			return nil, err
+		}
+		lastIP, err := IntToIP(m.last, m.bits)
+		if err != nil {
+			return nil, err
+		}
+		blocks, err := RangeToCIDRs(firstIP, lastIP)
+		if err != nil {
+			return nil, err
+		}
+		for _, block := range blocks {
+			result = append(result, CIDRWithMeta{CIDR: block, Meta: combinedMeta(block, normalized)})
+		}
+	}
+	return result, nil
+}
+
+// combinedMeta returns the metadata of the cidrs overlapping block joined with " + ", without
+// duplicates.
+func combinedMeta(block *net.IPNet, cidrs []CIDRWithMeta) string {
+	seen := map[string]bool{}
+	metas := []string{}
+	for _, n := range cidrs {
+		if n.Meta == "" || seen[n.Meta] || !OverlapsCIDR(n.CIDR, block) {
+			continue
+		}
+		seen[n.Meta] = true
+		metas = append(metas, n.Meta)
+	}
+	return strings.Join(metas, " + ")
+}
@@ -0,0 +1,179 @@
+package cidr_test
+
+import (
+	"net"
+	"testing"
+
+	"github.com/massdriver-cloud/cola/pkg/cidr"
+)
+
+func TestAggregateWithMeta(t *testing.T) {
+	type testData struct {
+		name  string
+		cidrs []string
+		metas []string
+		want  []string
+	}
+	tests := []testData{
+		{
+			name:  "Unmerged keep their names",
+			cidrs: []string{"10.0.2.0/24", "10.0.0.0/24"},
+			metas: []string{"api", "web"},
+			want:  []string{"10.0.0.0/24=web", "10.0.2.0/24=api"},
+		},
+		{
+			name:  "Adjacent pair merged",
+			cidrs: []string{"10.0.0.0/24", "10.0.1.0/24"},
+			metas: []string{"web", "api"},
+			want:  []string{"10.0.0.0/23=web + api"},
+		},
+		{
+			name:  "Contained CIDR absorbed",
+			cidrs: []string{"10.0.0.0/16", "10.0.4.0/24"},
+			metas: []string{"vpc", "web"},
+			want:  []string{"10.0.0.0/16=vpc + web"},
+		},
+		{
+			name:  "Merged range split into blocks",
+			cidrs: []string{"10.0.1.0/24", "10.0.2.0/24"},
+			metas: []string{"web", "api"},
+			want:  []string{"10.0.1.0/24=web", "10.0.2.0/24=api"},
+		},
+		{
+			name:  "Families kept apart",
+			cidrs: []string{"2001:db8::/64", "10.0.0.0/24", "::ffff:10.0.1.0/120"},
+			metas: []string{"v6", "web", "api"},
+			want:  []string{"10.0.0.0/23=web + api", "2001:db8::/64=v6"},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			cidrs := make([]cidr.CIDRWithMeta, len(tc.cidrs))
+			for i, value := range tc.cidrs {
+				_, n, _ := net.ParseCIDR(value)
+				cidrs[i] = cidr.CIDRWithMeta{CIDR: n, Meta: tc.metas[i]}
+			}
+			got, err := cidr.AggregateWithMeta(cidrs)
+			if err != nil {
+				t.Fatalf("Unexpected error: %s,", err.Error())
+			}
+			if len(got) != len(tc.want) {
+				t.Fatalf("want: %v, got: %v", tc.want, got)
+			}
+			for i := range got {
+				if value := got[i].CIDR.String() + "=" + got[i].Meta; value != tc.want[i] {
+					t.Fatalf("want: %v, got: %v", tc.want[i], value)
+				}
+			}
+		})
+	}
+}
+
+func TestCanonicalize(t *testing.T) {
+	type testData struct {
+		name  string
+		cidrs []*net.IPNet
+		want  []string
+	}
+	parse := func(values ...string) []*net.IPNet {
+		cidrs := []*net.IPNet{}
+		for _, value := range values {
+			ip, n, _ := net.ParseCIDR(value)
+			// keep the host bits of the input, as untrusted imports do
+			cidrs = append(cidrs, &net.IPNet{IP: ip, Mask: n.Mask})
+		}
+		return cidrs
+	}
+	tests := []testData{
+		{
+			name: "Messy import",
+			cidrs: parse(
+				"10.0.1.7/24",
+				"2001:db8::1/64",
+				"10.0.0.0/24",
+				"10.0.1.0/24",
+				"10.0.0.128/25",
+				"::ffff:10.0.3.0/120",
+				"10.0.2.0/24",
+				"192.168.0.0/16",
+				"192.168.4.0/24",
+				"2001:db8:0:1::/64",
+			),
+			want: []string{"10.0.0.0/22", "192.168.0.0/16", "2001:db8::/63"},
+		},
+		{
+			name:  "Invalid entries dropped",
+			cidrs: append(parse("10.0.0.0/24"), nil, &net.IPNet{IP: net.ParseIP("10.0.1.0"), Mask: net.IPMask{255, 0, 255, 0}}),
+			want:  []string{"10.0.0.0/24"},
+		},
+		{
+			name:  "Empty",
+			cidrs: []*net.IPNet{},
+			want:  []string{},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got := cidr.Canonicalize(tc.cidrs)
+			if len(got) != len(tc.want) {
+				t.Fatalf("want: %v, got: %v", tc.want, got)
+			}
+			for i := range got {
+				if got[i].String() != tc.want[i] {
+					t.Fatalf("want: %v, got: %v", tc.want, got)
+				}
+			}
+		})
+	}
+}
+
+func TestSummarize(t *testing.T) {
+	type testData struct {
+		name  string
+		cidrs []string
+		want  []string
+	}
+	tests := []testData{
+		{
+			name:  "Siblings merge",
+			cidrs: []string{"10.0.0.128/25", "10.0.0.0/25"},
+			want:  []string{"10.0.0.0/24"},
+		},
+		{
+			name:  "Siblings merge repeatedly",
+			cidrs: []string{"10.0.0.0/25", "10.0.1.0/24", "10.0.0.128/25", "10.0.2.0/23"},
+			want:  []string{"10.0.0.0/22"},
+		},
+		{
+			name:  "Contained and duplicates dropped",
+			cidrs: []string{"10.0.0.0/16", "10.0.5.0/24", "10.0.0.0/16", "10.0.200.0/21"},
+			want:  []string{"10.0.0.0/16"},
+		},
+		{
+			name:  "Nothing to merge",
+			cidrs: []string{"10.0.2.0/24", "10.0.0.128/25", "10.0.4.0/22"},
+			want:  []string{"10.0.0.128/25", "10.0.2.0/24", "10.0.4.0/22"},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			cidrs := []*net.IPNet{}
+			for _, value := range tc.cidrs {
+				_, n, _ := net.ParseCIDR(value)
+				cidrs = append(cidrs, n)
+			}
+			got := cidr.Summarize(cidrs)
+			if len(got) != len(tc.want) {
+				t.Fatalf("want: %v, got: %v", tc.want, got)
+			}
+			for i := range got {
+				if got[i].String() != tc.want[i] {
+					t.Fatalf("want: %v, got: %v", tc.want, got)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,188 @@
+package cidr
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"sort"
+	"time"
+)
+
+const (
+	// reserveRetryBackoff is how long ReserveWithRetry waits after the first busy attempt, doubling up
+	// to maxReserveRetryBackoff
+	reserveRetryBackoff    = time.Millisecond
+	maxReserveRetryBackoff = 50 * time.Millisecond
+)
+
+// Allocator tracks the used CIDRs of a root CIDR across a series of reservations and releases. It's
+// safe for concurrent use.
+type Allocator struct {
+	mu   allocatorLock
+	root *net.IPNet
+	used []*net.IPNet
+	opts []FindOption
+	// generation is the generation of the state file the allocator was loaded from or last saved to
+	generation int64
+}
+
+// NewAllocator returns an Allocator for the rootCIDR, starting with the usedCIDRs already allocated.
+// The opts are applied to every reservation. The usedCIDRs slice is not modified.
+func NewAllocator(rootCIDR *net.IPNet, usedCIDRs []*net.IPNet, opts ...FindOption) *Allocator {
+	used := make([]*net.IPNet, len(usedCIDRs))
+	copy(used, usedCIDRs)
+	return &Allocator{
+		mu:   make(allocatorLock, 1),
+		root: rootCIDR,
+		used: used,
+		opts: opts,
+	}
+}
+
+// Root returns the root CIDR of the allocator.
+func (a *Allocator) Root() *net.IPNet {
+	return a.root
+}
+
+// Reserve finds an available CIDR of the desiredMask size and marks it as used.
+func (a *Allocator) Reserve(desiredMask *net.IPMask) (*net.IPNet, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	return a.reserve(desiredMask)
+}
+
+// ReserveWithRetry is Reserve which doesn't queue behind other calls: if the allocator is busy the
+// attempt fails, and it's retried after a short backoff, doubling each time, up to attempts times.
+// Only contention is retried, any error from the reservation itself, such as ErrNoAvailableCIDR, is
+// returned straight away. If every attempt finds the allocator busy an ErrContention error is
+// returned, and if ctx is done before a reservation is made its error is returned.
+func (a *Allocator) ReserveWithRetry(ctx context.Context, desiredMask *net.IPMask, attempts int) (*net.IPNet, error) {
+	if attempts < 1 {
+		return nil, fmt.Errorf("%w: attempts must be at least 1, got %d", ErrInvalidInputRanges, attempts)
+	}
+
+	backoff := reserveRetryBackoff
+	for attempt := 1; ; attempt++ {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		if a.mu.tryLock() {
+			result, err := a.reserve(desiredMask)
+			a.mu.Unlock()
+			return result, err
+		}
+		if attempt == attempts {
+			return nil, fmt.Errorf("%w: gave up after %d attempts", ErrContention, attempts)
+		}
+
+		timer := time.NewTimer(backoff)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, ctx.Err()
+		case <-timer.C:
+		}
+		if backoff *= 2; backoff > maxReserveRetryBackoff {
+			backoff = maxReserveRetryBackoff
+		}
+	}
+}
+
+// reserve finds an available CIDR of the desiredMask size and marks it as used. The caller must hold the
+// lock.
+func (a *Allocator) reserve(desiredMask *net.IPMask) (*net.IPNet, error) {
+	result, err := FindAvailableCIDR(a.root, desiredMask, a.used, a.opts...)
+	if err != nil {
+		return nil, err
+	}
+	a.used = append(a.used, result)
+	return result, nil
+}
+
+// Release marks a previously used CIDR as available again. An error is returned if n isn't exactly
+// one of the used CIDRs.
+func (a *Allocator) Release(n *net.IPNet) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	return a.release(n)
+}
+
+// ReleaseMerges is Release which also reports whether the release heals fragmentation: if the sibling
+// of n is free, the largest free CIDR within the root which n is now part of is returned, so releasing
+// 10.0.1.0/24 next to a free 10.0.0.0/24 returns at least 10.0.0.0/23. If the sibling isn't free, or
+// n is the root, nil is returned.
+func (a *Allocator) ReleaseMerges(n *net.IPNet) (*net.IPNet, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if err := a.release(n); err != nil {
+		return nil, err
+	}
+	var mergedUpTo *net.IPNet
+	current := n
+	for SmallerMask(&current.Mask, &a.root.Mask) {
+		// the root has a shorter prefix than current, so current always has a parent
+		parent, _ := ParentCIDR(current)
+		if overlapsAnyCIDR(parent, a.used) {
+			break
+		}
+		mergedUpTo = parent
+		current = parent
+	}
+	return mergedUpTo, nil
+}
+
+// release removes n from the used CIDRs. The caller must hold the lock.
+func (a *Allocator) release(n *net.IPNet) error {
+	for i, used := range a.used {
+		if EqualCIDRs(used, n) {
+			a.used = append(a.used[:i], a.used[i+1:]...)
+			return nil
+		}
+	}
+	return fmt.Errorf("%w: %s is not allocated", ErrInvalidInputRanges, n.String())
+}
+
+// Used returns a copy of the used CIDRs in ascending address order.
+func (a *Allocator) Used() []*net.IPNet {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	return a.sortedUsed()
+}
+
+// sortedUsed returns a copy of the used CIDRs in ascending address order. The caller must hold the lock.
+func (a *Allocator) sortedUsed() []*net.IPNet {
+	used := make([]*net.IPNet, len(a.used))
+	copy(used, a.used)
+	sort.Slice(used, func(i, j int) bool {
+		return IPToInt(used[i].IP).Cmp(IPToInt(used[j].IP)) < 0
+	})
+	return used
+}
+
+// allocatorLock is a mutex which can also be tried without blocking, for ReserveWithRetry. It must be
+// made with a buffer of one.
+type allocatorLock chan struct{}
+
+// Lock waits for the lock and takes it.
+func (l allocatorLock) Lock() {
+	l <- struct{}{}
+}
+
+// tryLock takes the lock if it's free, returning false rather than waiting if it isn't.
+func (l allocatorLock) tryLock() bool {
+	select {
+	case l <- struct{}{}:
+		return true
+	default:
+		return false
+	}
+}
+
+// Unlock releases the lock.
+func (l allocatorLock) Unlock() {
+	<-l
+}
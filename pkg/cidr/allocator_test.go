@@ -0,0 +1,300 @@
+package cidr_test
+
+import (
+	"context"
+	"errors"
+	"net"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/massdriver-cloud/cola/pkg/cidr"
+)
+
+func TestAllocator(t *testing.T) {
+	_, root, _ := net.ParseCIDR("10.0.0.0/16")
+	_, existing, _ := net.ParseCIDR("10.0.0.0/24")
+	allocator := cidr.NewAllocator(root, []*net.IPNet{existing})
+
+	mask24 := net.CIDRMask(24, 32)
+	first, err := allocator.Reserve(&mask24)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s,", err.Error())
+	}
+	if first.String() != "10.0.1.0/24" {
+		t.Fatalf("want: %v, got: %v", "10.0.1.0/24", first.String())
+	}
+
+	if err = allocator.Release(existing); err != nil {
+		t.Fatalf("Unexpected error: %s,", err.Error())
+	}
+	if err = allocator.Release(existing); !errors.Is(err, cidr.ErrInvalidInputRanges) {
+		t.Fatalf("Invalid error, want: %v, got %v,", cidr.ErrInvalidInputRanges, err)
+	}
+
+	second, err := allocator.Reserve(&mask24)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s,", err.Error())
+	}
+	if second.String() != "10.0.0.0/24" {
+		t.Fatalf("want: %v, got: %v", "10.0.0.0/24", second.String())
+	}
+
+	used := allocator.Used()
+	if len(used) != 2 || used[0].String() != "10.0.0.0/24" || used[1].String() != "10.0.1.0/24" {
+		t.Fatalf("want: %v, got: %v", []string{"10.0.0.0/24", "10.0.1.0/24"}, used)
+	}
+}
+
+func TestAllocatorReleaseMerges(t *testing.T) {
+	type testData struct {
+		name      string
+		usedCIDRs []string
+		release   string
+		want      string
+		wantError error
+	}
+	tests := []testData{
+		{
+			name:      "Free sibling",
+			usedCIDRs: []string{"10.0.1.0/24", "10.0.2.0/24"},
+			release:   "10.0.1.0/24",
+			want:      "10.0.0.0/23",
+		},
+		{
+			name:      "Used sibling",
+			usedCIDRs: []string{"10.0.4.0/24", "10.0.5.0/24"},
+			release:   "10.0.4.0/24",
+			want:      "<nil>",
+		},
+		{
+			name:      "Last used CIDR frees the root",
+			usedCIDRs: []string{"10.0.9.0/24"},
+			release:   "10.0.9.0/24",
+			want:      "10.0.0.0/16",
+		},
+		{
+			name:      "Root",
+			usedCIDRs: []string{"10.0.0.0/16"},
+			release:   "10.0.0.0/16",
+			want:      "<nil>",
+		},
+		{
+			name:      "Error not allocated",
+			usedCIDRs: []string{"10.0.1.0/24"},
+			release:   "10.0.2.0/24",
+			wantError: cidr.ErrInvalidInputRanges,
+		},
+	}
+
+	_, root, _ := net.ParseCIDR("10.0.0.0/16")
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			usedCIDRs := make([]*net.IPNet, len(tc.usedCIDRs))
+			for i, usedCIDR := range tc.usedCIDRs {
+				_, usedCIDR, _ := net.ParseCIDR(usedCIDR)
+				usedCIDRs[i] = usedCIDR
+			}
+			_, release, _ := net.ParseCIDR(tc.release)
+			allocator := cidr.NewAllocator(root, usedCIDRs)
+
+			got, err := allocator.ReleaseMerges(release)
+			if tc.wantError != nil {
+				if !errors.Is(err, tc.wantError) {
+					t.Fatalf("Invalid error, want: %v, got %v,", tc.wantError, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %s,", err.Error())
+			}
+			if got.String() != tc.want {
+				t.Fatalf("want: %v, got: %v", tc.want, got.String())
+			}
+			if len(allocator.Used()) != len(tc.usedCIDRs)-1 {
+				t.Fatalf("want: %v released, got: %v", release, allocator.Used())
+			}
+		})
+	}
+}
+
+func TestAllocatorWithStride(t *testing.T) {
+	_, root, _ := net.ParseCIDR("10.0.0.0/16")
+	allocator := cidr.NewAllocator(root, []*net.IPNet{}, cidr.WithStride(20))
+
+	mask24 := net.CIDRMask(24, 32)
+	for _, want := range []string{"10.0.0.0/24", "10.0.16.0/24", "10.0.32.0/24"} {
+		got, err := allocator.Reserve(&mask24)
+		if err != nil {
+			t.Fatalf("Unexpected error: %s,", err.Error())
+		}
+		if got.String() != want {
+			t.Fatalf("want: %v, got: %v", want, got.String())
+		}
+	}
+
+	mask20 := net.CIDRMask(20, 32)
+	if _, err := allocator.Reserve(&mask20); !errors.Is(err, cidr.ErrInvalidInputRanges) {
+		t.Fatalf("Invalid error, want: %v, got %v,", cidr.ErrInvalidInputRanges, err)
+	}
+}
+
+func TestAllocatorConcurrent(t *testing.T) {
+	_, root, _ := net.ParseCIDR("10.0.0.0/16")
+	allocator := cidr.NewAllocator(root, []*net.IPNet{})
+
+	const workers = 32
+	results := make([]*net.IPNet, workers)
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			mask := net.CIDRMask(24, 32)
+			result, err := allocator.Reserve(&mask)
+			if err != nil {
+				t.Errorf("Unexpected error: %s,", err.Error())
+				return
+			}
+			results[i] = result
+		}(i)
+	}
+	wg.Wait()
+
+	seen := map[string]bool{}
+	for _, result := range results {
+		if result == nil {
+			continue
+		}
+		if seen[result.String()] {
+			t.Fatalf("%s was reserved twice", result.String())
+		}
+		seen[result.String()] = true
+	}
+}
+
+func TestAllocatorSaveAtomic(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "ipam.json")
+	_, root, _ := net.ParseCIDR("10.0.0.0/16")
+	mask24 := net.CIDRMask(24, 32)
+
+	if err := cidr.NewAllocator(root, []*net.IPNet{}).SaveAtomic(path); err != nil {
+		t.Fatalf("Unexpected error: %s,", err.Error())
+	}
+
+	// two processes load the same state
+	first, err := cidr.LoadAllocator(path)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s,", err.Error())
+	}
+	stale, err := cidr.LoadAllocator(path)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s,", err.Error())
+	}
+
+	if _, err = first.Reserve(&mask24); err != nil {
+		t.Fatalf("Unexpected error: %s,", err.Error())
+	}
+	if err = first.SaveAtomic(path); err != nil {
+		t.Fatalf("Unexpected error: %s,", err.Error())
+	}
+
+	// the second save was based on older state, so it would clobber the first
+	if _, err = stale.Reserve(&mask24); err != nil {
+		t.Fatalf("Unexpected error: %s,", err.Error())
+	}
+	if err = stale.SaveAtomic(path); !errors.Is(err, cidr.ErrStateConflict) {
+		t.Fatalf("Invalid error, want: %v, got %v,", cidr.ErrStateConflict, err)
+	}
+
+	// the first save survives, and a writer which saved can keep saving
+	if _, err = first.Reserve(&mask24); err != nil {
+		t.Fatalf("Unexpected error: %s,", err.Error())
+	}
+	if err = first.SaveAtomic(path); err != nil {
+		t.Fatalf("Unexpected error: %s,", err.Error())
+	}
+	loaded, err := cidr.LoadAllocator(path)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s,", err.Error())
+	}
+	if loaded.Root().String() != "10.0.0.0/16" {
+		t.Fatalf("want: %v, got: %v", "10.0.0.0/16", loaded.Root().String())
+	}
+	used := loaded.Used()
+	if len(used) != 2 || used[0].String() != "10.0.0.0/24" || used[1].String() != "10.0.1.0/24" {
+		t.Fatalf("want: %v, got: %v", []string{"10.0.0.0/24", "10.0.1.0/24"}, used)
+	}
+
+	// no temporary files are left behind
+	entries, err := os.ReadDir(filepath.Dir(path))
+	if err != nil {
+		t.Fatalf("Unexpected error: %s,", err.Error())
+	}
+	if len(entries) != 1 {
+		t.Fatalf("want: %v, got: %v", 1, len(entries))
+	}
+}
+
+func TestAllocatorReserveWithRetry(t *testing.T) {
+	_, root, _ := net.ParseCIDR("10.0.0.0/22")
+	mask24 := net.CIDRMask(24, 32)
+
+	// the first reservation scores its first candidate while holding the allocator, and waits there until
+	// released, so every other call finds the allocator busy until then
+	holding := make(chan struct{})
+	release := make(chan struct{})
+	var once sync.Once
+	scorer := func(candidate *net.IPNet) int {
+		once.Do(func() {
+			close(holding)
+			<-release
+		})
+		return 0
+	}
+	allocator := cidr.NewAllocator(root, []*net.IPNet{}, cidr.WithScorer(scorer), cidr.WithCandidateLimit(1))
+
+	held := make(chan error)
+	go func() {
+		_, err := allocator.Reserve(&mask24)
+		held <- err
+	}()
+	<-holding
+
+	if _, err := allocator.ReserveWithRetry(context.Background(), &mask24, 3); !errors.Is(err, cidr.ErrContention) {
+		t.Fatalf("Invalid error, want: %v, got %v,", cidr.ErrContention, err)
+	}
+	cancelled, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := allocator.ReserveWithRetry(cancelled, &mask24, 3); !errors.Is(err, context.Canceled) {
+		t.Fatalf("Invalid error, want: %v, got %v,", context.Canceled, err)
+	}
+
+	// the contention clears part way through the attempts
+	go func() {
+		time.Sleep(20 * time.Millisecond)
+		close(release)
+	}()
+	result, err := allocator.ReserveWithRetry(context.Background(), &mask24, 100)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s,", err.Error())
+	}
+	if err = <-held; err != nil {
+		t.Fatalf("Unexpected error: %s,", err.Error())
+	}
+	if result.String() != "10.0.1.0/24" {
+		t.Fatalf("want: %v, got: %v", "10.0.1.0/24", result.String())
+	}
+
+	// running out of space isn't contention, so it isn't retried
+	for i := 0; i < 2; i++ {
+		if _, err = allocator.ReserveWithRetry(context.Background(), &mask24, 100); err != nil {
+			t.Fatalf("Unexpected error: %s,", err.Error())
+		}
+	}
+	if _, err = allocator.ReserveWithRetry(context.Background(), &mask24, 100); !errors.Is(err, cidr.ErrNoAvailableCIDR) {
+		t.Fatalf("Invalid error, want: %v, got %v,", cidr.ErrNoAvailableCIDR, err)
+	}
+}
@@ -0,0 +1,45 @@
+package cidr
+
+import (
+	"fmt"
+	"net"
+
+	"github.com/apparentlymart/go-cidr/cidr"
+)
+
+// AllocateAZSubnets carves azCount equal sized subnets out of the vpc CIDR, following the
+// common one-subnet-per-availability-zone layout. The largest available aligned block within
+// the vpc is split into the next power of two of azCount equal blocks, and the first azCount
+// of those are returned. None of the returned subnets will overlap the usedCIDRs.
+func AllocateAZSubnets(vpc *net.IPNet, azCount int, usedCIDRs []*net.IPNet) ([]*net.IPNet, error) {
+	if azCount < 1 {
+		return nil, fmt.Errorf("%w: AZ count must be at least 1, got %d", ErrInvalidInputRanges, azCount)
+	}
+
+	region, err := LargestAvailableBlock(vpc, usedCIDRs)
+	if err != nil {
+		return nil, err
+	}
+
+	// the number of extra mask bits needed to fit azCount blocks (azCount rounded up to a power of 2)
+	newBits := 0
+	for (1 << newBits) < azCount {
+		newBits++
+	}
+
+	regionOnes, bits := region.Mask.Size()
+	if regionOnes+newBits > bits {
+		return nil, fmt.Errorf("%w: largest available region %s is too small to split into %d subnets", ErrNoAvailableCIDR, region.String(), azCount)
+	}
+
+	subnets := make([]*net.IPNet, 0, azCount)
+	for i := 0; i < azCount; i++ {
+		subnet, subnetErr := cidr.Subnet(region, newBits, i)
+		if subnetErr != nil {
+			return nil, subnetErr
+		}
+		subnets = append(subnets, subnet)
+	}
+
+	return subnets, nil
+}
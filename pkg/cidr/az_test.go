@@ -0,0 +1,102 @@
+package cidr_test
+
+import (
+	"errors"
+	"net"
+	"testing"
+
+	"github.com/massdriver-cloud/cola/pkg/cidr"
+)
+
+func TestAllocateAZSubnets(t *testing.T) {
+	type testData struct {
+		name      string
+		vpc       string
+		azCount   int
+		usedCIDRs []string
+		want      []string
+		wantError error
+	}
+	tests := []testData{
+		{
+			name:      "Three AZs",
+			vpc:       "10.0.0.0/16",
+			azCount:   3,
+			usedCIDRs: []string{},
+			want: []string{
+				"10.0.0.0/18",
+				"10.0.64.0/18",
+				"10.0.128.0/18",
+			},
+		},
+		{
+			name:    "Three AZs around used",
+			vpc:     "10.0.0.0/16",
+			azCount: 3,
+			usedCIDRs: []string{
+				"10.0.0.0/18",
+			},
+			want: []string{
+				"10.0.128.0/19",
+				"10.0.160.0/19",
+				"10.0.192.0/19",
+			},
+		},
+		{
+			name:      "Single AZ",
+			vpc:       "10.0.0.0/16",
+			azCount:   1,
+			usedCIDRs: []string{"10.0.0.0/17"},
+			want:      []string{"10.0.128.0/17"},
+		},
+		{
+			name:      "Error invalid count",
+			vpc:       "10.0.0.0/16",
+			azCount:   0,
+			usedCIDRs: []string{},
+			wantError: cidr.ErrInvalidInputRanges,
+		},
+		{
+			name:      "Error full",
+			vpc:       "10.0.0.0/16",
+			azCount:   2,
+			usedCIDRs: []string{"10.0.0.0/16"},
+			wantError: cidr.ErrNoAvailableCIDR,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			_, vpc, _ := net.ParseCIDR(tc.vpc)
+			usedCIDRs := make([]*net.IPNet, len(tc.usedCIDRs))
+			for i, usedCIDR := range tc.usedCIDRs {
+				_, usedCIDR, _ := net.ParseCIDR(usedCIDR)
+				usedCIDRs[i] = usedCIDR
+			}
+			got, err := cidr.AllocateAZSubnets(vpc, tc.azCount, usedCIDRs)
+			if tc.wantError != nil {
+				if !errors.Is(err, tc.wantError) {
+					t.Fatalf("Invalid error, want: %v, got %v,", tc.wantError, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %s,", err.Error())
+			}
+			if len(got) != len(tc.want) {
+				t.Fatalf("want: %v, got: %v", tc.want, got)
+			}
+			for i := range got {
+				if got[i].String() != tc.want[i] {
+					t.Fatalf("want: %v, got: %v", tc.want[i], got[i].String())
+				}
+				if !cidr.EqualMask(&got[i].Mask, &got[0].Mask) {
+					t.Fatalf("subnets are not equal sized: %v, %v", got[0].String(), got[i].String())
+				}
+				if cidr.ContainsExistingCIDR(got[i], usedCIDRs) || cidr.MatchesExistingCIDR(got[i], usedCIDRs) {
+					t.Fatalf("subnet %v overlaps used CIDRs", got[i].String())
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,185 @@
+package cidr
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"net"
+)
+
+// RemainingCapacity returns how many more non-overlapping CIDRs of the desiredMask size can be
+// allocated from the rootCIDR given the usedCIDRs. Because allocations must be aligned, this accounts
+// for fragmentation and can be less than the number of free addresses divided by the block size.
+// The count is computed from the FreeSpace blocks rather than by visiting each candidate, so it's
+// cheap even for IPv6 counts which don't fit in an int.
+func RemainingCapacity(rootCIDR *net.IPNet, desiredMask *net.IPMask, usedCIDRs []*net.IPNet) (*big.Int, error) {
+	desiredOnes, desiredBits := desiredMask.Size()
+	if _, bits := rootCIDR.Mask.Size(); desiredBits != bits {
+		return nil, fmt.Errorf("%w: desired mask doesn't match the address family of the root CIDR", ErrInvalidInputRanges)
+	}
+	for _, used := range usedCIDRs {
+		if ContainsCIDR(used, rootCIDR) && !EqualMask(&used.Mask, &rootCIDR.Mask) {
+			return nil, ErrRootInsideUsed
+		}
+	}
+
+	free, err := FreeSpace(rootCIDR, usedCIDRs)
+	if err != nil {
+		return nil, err
+	}
+	return countBlocks(free, desiredOnes), nil
+}
+
+// countBlocks returns how many aligned blocks with the prefix length fit in the free blocks. Any
+// aligned block within free space is within a single FreeSpace block, so this is the sum of
+// 2^(prefix-ones) over the free blocks at least as large as the prefix.
+func countBlocks(free []*net.IPNet, prefix int) *big.Int {
+	count := new(big.Int)
+	for _, block := range free {
+		if ones, _ := block.Mask.Size(); ones <= prefix {
+			count.Add(count, new(big.Int).Lsh(big.NewInt(1), uint(prefix-ones)))
+		}
+	}
+	return count
+}
+
+// CanSubdivide returns how many targetPrefix sized blocks of the region don't overlap any of the
+// usedCIDRs. This is every block in the region if it's entirely free, and zero if the region is
+// within a used CIDR. An error is returned if targetPrefix is shorter than the region's prefix or
+// longer than the address length.
+func CanSubdivide(region *net.IPNet, targetPrefix int, usedCIDRs []*net.IPNet) (*big.Int, error) {
+	ones, bits := region.Mask.Size()
+	if targetPrefix < ones || targetPrefix > bits {
+		return nil, fmt.Errorf("%w: /%d blocks don't fit in %s", ErrInvalidInputRanges, targetPrefix, region.String())
+	}
+	if withinAnyCIDR(region, usedCIDRs) {
+		return new(big.Int), nil
+	}
+	mask := net.CIDRMask(targetPrefix, bits)
+	return RemainingCapacity(region, &mask, usedCIDRs)
+}
+
+// FitsUniform returns whether there are at least count available prefixLen sized blocks in the
+// rootCIDR, for capacity gating where the exact RemainingCapacity isn't needed. The walk stops as soon
+// as count blocks have been found.
+func FitsUniform(rootCIDR *net.IPNet, prefixLen int, count int, usedCIDRs []*net.IPNet) (bool, error) {
+	_, bits := rootCIDR.Mask.Size()
+	if prefixLen < 0 || prefixLen > bits {
+		return false, fmt.Errorf("%w: /%d is not a valid prefix length for %s", ErrInvalidInputRanges, prefixLen, rootCIDR.String())
+	}
+	if count <= 0 {
+		return true, nil
+	}
+
+	found := 0
+	mask := net.CIDRMask(prefixLen, bits)
+	err := WalkAvailable(rootCIDR, &mask, usedCIDRs, func(*net.IPNet) bool {
+		found++
+		return found < count
+	})
+	if err != nil {
+		return false, err
+	}
+	return found >= count, nil
+}
+
+// WithMinLargestBlockAfter keeps a capacity guarantee such as "always keep a /20 available": a result
+// is rejected if allocating it would leave no free block of the root CIDR at least as large as
+// prefixLen. If every available CIDR would break the guarantee, the ErrNoAvailableCIDR error says so.
+func WithMinLargestBlockAfter(prefixLen int) FindOption {
+	return func(o *findOptions) {
+		o.minLargestBlock = prefixLen
+	}
+}
+
+// applyMinLargestBlock adds a candidate filter rejecting results which would leave no free block of
+// at least the minLargestBlock size, counting the rejections.
+func (o *findOptions) applyMinLargestBlock(rootCIDR *net.IPNet, usedCIDRs []*net.IPNet) {
+	if o.minLargestBlock < 0 {
+		return
+	}
+	o.candidateFilters = append(o.candidateFilters, func(candidate *net.IPNet) bool {
+		free, err := FreeSpace(rootCIDR, append(usedCIDRs[:len(usedCIDRs):len(usedCIDRs)], candidate))
+		if err == nil && countBlocks(free, o.minLargestBlock).Sign() > 0 {
+			return true
+		}
+		o.guardRejections++
+		return false
+	})
+}
+
+// guardError explains a failed search which rejected candidates to keep the WithMinLargestBlockAfter
+// guarantee.
+func (o *findOptions) guardError(err error) error {
+	if o.guardRejections == 0 || !errors.Is(err, ErrNoAvailableCIDR) {
+		return err
+	}
+	return fmt.Errorf("%w: every available CIDR would leave no /%d free", ErrNoAvailableCIDR, o.minLargestBlock)
+}
+
+// Default longest prefix lengths considered by MaxUniformSubnets.
+const (
+	DefaultLongestUniformPrefixIPv4 = 28
+	DefaultLongestUniformPrefixIPv6 = 64
+)
+
+// DefaultUniformSubnetsLimit is the most blocks MaxUniformSubnets will return.
+const DefaultUniformSubnetsLimit = 65536
+
+// MaxUniformSubnets answers "how should I evenly divide what's left": it returns the prefix length,
+// count and list of equal sized free blocks which cover the most free space of the rootCIDR. Free
+// space can always be covered by smaller blocks, so blocks are limited to DefaultLongestUniformPrefixIPv4
+// (or DefaultLongestUniformPrefixIPv6), and of the prefix lengths covering the most space the shortest
+// is chosen, giving the fewest, largest blocks. If there would be more than DefaultUniformSubnetsLimit
+// blocks an ErrInvalidInputRanges error is returned along with the prefix and count, but no blocks.
+func MaxUniformSubnets(rootCIDR *net.IPNet, usedCIDRs []*net.IPNet) (int, *big.Int, []*net.IPNet, error) {
+	longestPrefix := DefaultLongestUniformPrefixIPv6
+	if _, bits := rootCIDR.Mask.Size(); bits == ipv4Bits {
+		longestPrefix = DefaultLongestUniformPrefixIPv4
+	}
+	return MaxUniformSubnetsWithLimit(rootCIDR, usedCIDRs, longestPrefix, DefaultUniformSubnetsLimit)
+}
+
+// MaxUniformSubnetsWithLimit is MaxUniformSubnets with blocks limited to longestPrefix, returning at
+// most maxBlocks blocks.
+func MaxUniformSubnetsWithLimit(rootCIDR *net.IPNet, usedCIDRs []*net.IPNet, longestPrefix int, maxBlocks int) (int, *big.Int, []*net.IPNet, error) {
+	rootOnes, bits := rootCIDR.Mask.Size()
+	if longestPrefix < rootOnes || longestPrefix > bits {
+		return 0, nil, nil, fmt.Errorf("%w: longest prefix /%d doesn't fit in %s", ErrInvalidInputRanges, longestPrefix, rootCIDR.String())
+	}
+	free, err := FreeSpace(rootCIDR, usedCIDRs)
+	if err != nil {
+		return 0, nil, nil, err
+	}
+
+	// splitting a free block into smaller blocks covers the same space, so the space covered only
+	// grows as the prefix gets longer. The best prefix is the shortest which covers as much as the
+	// longest allowed prefix, which is the longest free block prefix up to that limit.
+	prefix := -1
+	for _, block := range free {
+		if ones, _ := block.Mask.Size(); ones <= longestPrefix && ones > prefix {
+			prefix = ones
+		}
+	}
+	if prefix < 0 {
+		return 0, nil, nil, fmt.Errorf("%w: no free blocks of /%d or larger", ErrNoAvailableCIDR, longestPrefix)
+	}
+
+	count := countBlocks(free, prefix)
+	if count.Cmp(big.NewInt(int64(maxBlocks))) > 0 {
+		return prefix, count, nil, fmt.Errorf("%w: %s /%d blocks are more than the limit of %d", ErrInvalidInputRanges, count.String(), prefix, maxBlocks)
+	}
+
+	blocks := make([]*net.IPNet, 0, count.Int64())
+	for _, block := range free {
+		if ones, _ := block.Mask.Size(); ones > prefix {
+			continue
+		}
+		split, splitErr := SplitByPrefix(block, prefix)
+		if splitErr != nil {
+			return 0, nil, nil, splitErr
+		}
+		blocks = append(blocks, split...)
+	}
+	return prefix, count, blocks, nil
+}
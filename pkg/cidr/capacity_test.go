@@ -0,0 +1,390 @@
+package cidr_test
+
+import (
+	"errors"
+	"math/big"
+	"net"
+	"strings"
+	"testing"
+
+	"github.com/massdriver-cloud/cola/pkg/cidr"
+)
+
+func TestRemainingCapacity(t *testing.T) {
+	type testData struct {
+		name        string
+		baseCIDR    string
+		usedCIDRs   []string
+		desiredMask net.IPMask
+		want        string
+		wantError   error
+	}
+	tests := []testData{
+		{
+			name:        "Empty",
+			baseCIDR:    "10.0.0.0/16",
+			usedCIDRs:   []string{},
+			desiredMask: net.CIDRMask(24, 32),
+			want:        "256",
+		},
+		{
+			name:     "Fragmented",
+			baseCIDR: "10.0.0.0/22",
+			// 640 free addresses would naively hold 2 /24s, but only one aligned /24 is free
+			usedCIDRs: []string{
+				"10.0.0.128/25",
+				"10.0.1.128/25",
+				"10.0.2.128/25",
+			},
+			desiredMask: net.CIDRMask(24, 32),
+			want:        "1",
+		},
+		{
+			name:        "Full",
+			baseCIDR:    "10.0.0.0/16",
+			usedCIDRs:   []string{"10.0.0.0/16"},
+			desiredMask: net.CIDRMask(24, 32),
+			want:        "0",
+		},
+		{
+			name:        "IPv6 beyond int",
+			baseCIDR:    "2001:db8::/32",
+			usedCIDRs:   []string{"2001:db8::/64"},
+			desiredMask: net.CIDRMask(64, 128),
+			want:        "4294967295",
+		},
+		{
+			name:        "Mask larger than root",
+			baseCIDR:    "10.0.0.0/24",
+			usedCIDRs:   []string{},
+			desiredMask: net.CIDRMask(16, 32),
+			want:        "0",
+		},
+		{
+			name:        "Error root within used",
+			baseCIDR:    "10.0.0.0/16",
+			usedCIDRs:   []string{"10.0.0.0/8"},
+			desiredMask: net.CIDRMask(24, 32),
+			wantError:   cidr.ErrInvalidInputRanges,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			_, baseCIDR, _ := net.ParseCIDR(tc.baseCIDR)
+			usedCIDRs := make([]*net.IPNet, len(tc.usedCIDRs))
+			for i, usedCIDR := range tc.usedCIDRs {
+				_, usedCIDR, _ := net.ParseCIDR(usedCIDR)
+				usedCIDRs[i] = usedCIDR
+			}
+			got, err := cidr.RemainingCapacity(baseCIDR, &tc.desiredMask, usedCIDRs)
+			if tc.wantError != nil {
+				if !errors.Is(err, tc.wantError) {
+					t.Fatalf("Invalid error, want: %v, got %v,", tc.wantError, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %s,", err.Error())
+			}
+			if got.String() != tc.want {
+				t.Fatalf("want: %v, got: %v", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestCanSubdivide(t *testing.T) {
+	type testData struct {
+		name         string
+		region       string
+		targetPrefix int
+		usedCIDRs    []string
+		want         string
+		wantError    error
+	}
+	tests := []testData{
+		{
+			name:         "Fully free",
+			region:       "10.0.0.0/20",
+			targetPrefix: 24,
+			usedCIDRs:    []string{"10.1.0.0/16"},
+			want:         "16",
+		},
+		{
+			name:         "Partially used",
+			region:       "10.0.0.0/20",
+			targetPrefix: 24,
+			usedCIDRs:    []string{"10.0.0.0/22", "10.0.4.128/25"},
+			want:         "11",
+		},
+		{
+			name:         "Within used",
+			region:       "10.0.0.0/20",
+			targetPrefix: 24,
+			usedCIDRs:    []string{"10.0.0.0/16"},
+			want:         "0",
+		},
+		{
+			name:         "Error target larger than region",
+			region:       "10.0.0.0/20",
+			targetPrefix: 16,
+			wantError:    cidr.ErrInvalidInputRanges,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			_, region, _ := net.ParseCIDR(tc.region)
+			usedCIDRs := make([]*net.IPNet, len(tc.usedCIDRs))
+			for i, usedCIDR := range tc.usedCIDRs {
+				_, usedCIDR, _ := net.ParseCIDR(usedCIDR)
+				usedCIDRs[i] = usedCIDR
+			}
+			got, err := cidr.CanSubdivide(region, tc.targetPrefix, usedCIDRs)
+			if tc.wantError != nil {
+				if !errors.Is(err, tc.wantError) {
+					t.Fatalf("Invalid error, want: %v, got %v,", tc.wantError, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %s,", err.Error())
+			}
+			if got.String() != tc.want {
+				t.Fatalf("want: %v, got: %v", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestFitsUniform(t *testing.T) {
+	type testData struct {
+		name      string
+		rootCIDR  string
+		prefixLen int
+		count     int
+		usedCIDRs []string
+		want      bool
+		wantError error
+	}
+	tests := []testData{
+		{
+			name:      "Exactly count",
+			rootCIDR:  "10.0.0.0/20",
+			prefixLen: 24,
+			count:     11,
+			usedCIDRs: []string{"10.0.0.0/22", "10.0.4.128/25"},
+			want:      true,
+		},
+		{
+			name:      "One more than count",
+			rootCIDR:  "10.0.0.0/20",
+			prefixLen: 24,
+			count:     12,
+			usedCIDRs: []string{"10.0.0.0/22", "10.0.4.128/25"},
+			want:      false,
+		},
+		{
+			name:      "Stops early in a large root",
+			rootCIDR:  "2001:db8::/32",
+			prefixLen: 64,
+			count:     3,
+			usedCIDRs: []string{"2001:db8::/64"},
+			want:      true,
+		},
+		{
+			name:      "Fully used",
+			rootCIDR:  "10.0.0.0/20",
+			prefixLen: 24,
+			count:     1,
+			usedCIDRs: []string{"10.0.0.0/20"},
+			want:      false,
+		},
+		{
+			name:      "Zero count",
+			rootCIDR:  "10.0.0.0/20",
+			prefixLen: 24,
+			count:     0,
+			usedCIDRs: []string{"10.0.0.0/20"},
+			want:      true,
+		},
+		{
+			name:      "Error invalid prefix",
+			rootCIDR:  "10.0.0.0/20",
+			prefixLen: 33,
+			count:     1,
+			wantError: cidr.ErrInvalidInputRanges,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			_, rootCIDR, _ := net.ParseCIDR(tc.rootCIDR)
+			usedCIDRs := make([]*net.IPNet, len(tc.usedCIDRs))
+			for i, usedCIDR := range tc.usedCIDRs {
+				_, usedCIDR, _ := net.ParseCIDR(usedCIDR)
+				usedCIDRs[i] = usedCIDR
+			}
+			got, err := cidr.FitsUniform(rootCIDR, tc.prefixLen, tc.count, usedCIDRs)
+			if tc.wantError != nil {
+				if !errors.Is(err, tc.wantError) {
+					t.Fatalf("Invalid error, want: %v, got %v,", tc.wantError, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %s,", err.Error())
+			}
+			if got != tc.want {
+				t.Fatalf("want: %v, got: %v", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestMaxUniformSubnets(t *testing.T) {
+	type testData struct {
+		name       string
+		baseCIDR   string
+		usedCIDRs  []string
+		wantPrefix int
+		wantCount  string
+		wantBlocks []string
+		wantError  error
+	}
+	tests := []testData{
+		{
+			name:       "Empty",
+			baseCIDR:   "10.0.0.0/16",
+			usedCIDRs:  []string{},
+			wantPrefix: 16,
+			wantBlocks: []string{"10.0.0.0/16"},
+		},
+		{
+			name:       "Partially used",
+			baseCIDR:   "10.0.0.0/16",
+			usedCIDRs:  []string{"10.0.0.0/18", "10.0.192.0/19"},
+			wantPrefix: 19,
+			wantBlocks: []string{"10.0.64.0/19", "10.0.96.0/19", "10.0.128.0/19", "10.0.160.0/19", "10.0.224.0/19"},
+		},
+		{
+			name:       "Free space smaller than the limit is ignored",
+			baseCIDR:   "10.0.0.0/24",
+			usedCIDRs:  []string{"10.0.0.0/25", "10.0.0.128/26", "10.0.0.192/29"},
+			wantPrefix: 28,
+			wantBlocks: []string{"10.0.0.208/28", "10.0.0.224/28", "10.0.0.240/28"},
+		},
+		{
+			name:      "Full",
+			baseCIDR:  "10.0.0.0/24",
+			usedCIDRs: []string{"10.0.0.0/24"},
+			wantError: cidr.ErrNoAvailableCIDR,
+		},
+		{
+			name:       "Error too many IPv6 blocks",
+			baseCIDR:   "2001:db8::/32",
+			usedCIDRs:  []string{"2001:db8::/64"},
+			wantPrefix: 64,
+			wantCount:  "4294967295",
+			wantError:  cidr.ErrInvalidInputRanges,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			_, baseCIDR, _ := net.ParseCIDR(tc.baseCIDR)
+			usedCIDRs := make([]*net.IPNet, len(tc.usedCIDRs))
+			for i, usedCIDR := range tc.usedCIDRs {
+				_, usedCIDR, _ := net.ParseCIDR(usedCIDR)
+				usedCIDRs[i] = usedCIDR
+			}
+			prefix, count, blocks, err := cidr.MaxUniformSubnets(baseCIDR, usedCIDRs)
+			if tc.wantError != nil {
+				if !errors.Is(err, tc.wantError) {
+					t.Fatalf("Invalid error, want: %v, got %v,", tc.wantError, err)
+				}
+				if tc.wantCount != "" && (prefix != tc.wantPrefix || count.String() != tc.wantCount || blocks != nil) {
+					t.Fatalf("want: /%d x %s, got: /%d x %v, %d blocks", tc.wantPrefix, tc.wantCount, prefix, count, len(blocks))
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %s,", err.Error())
+			}
+			if prefix != tc.wantPrefix {
+				t.Fatalf("want: %v, got: %v", tc.wantPrefix, prefix)
+			}
+			if count.Cmp(big.NewInt(int64(len(tc.wantBlocks)))) != 0 || len(blocks) != len(tc.wantBlocks) {
+				t.Fatalf("want: %v, got: %v", tc.wantBlocks, blocks)
+			}
+			for i := range blocks {
+				if blocks[i].String() != tc.wantBlocks[i] {
+					t.Fatalf("want: %v, got: %v", tc.wantBlocks[i], blocks[i].String())
+				}
+			}
+		})
+	}
+}
+
+func TestFindAvailableCIDRWithMinLargestBlockAfter(t *testing.T) {
+	type testData struct {
+		name        string
+		baseCIDR    string
+		usedCIDRs   []string
+		desiredMask net.IPMask
+		minLargest  int
+		want        string
+		wantError   error
+	}
+	tests := []testData{
+		{
+			name:        "First fit keeps the guarantee",
+			baseCIDR:    "10.0.0.0/19",
+			usedCIDRs:   []string{"10.0.0.0/21"},
+			desiredMask: net.CIDRMask(21, 32),
+			minLargest:  20,
+			want:        "10.0.8.0/21",
+		},
+		{
+			name:        "Moved to keep the last free /20",
+			baseCIDR:    "10.0.0.0/19",
+			usedCIDRs:   []string{"10.0.20.0/22"},
+			desiredMask: net.CIDRMask(22, 32),
+			minLargest:  20,
+			want:        "10.0.16.0/22",
+		},
+		{
+			name:        "Error every result breaks the guarantee",
+			baseCIDR:    "10.0.0.0/19",
+			usedCIDRs:   []string{"10.0.0.0/21"},
+			desiredMask: net.CIDRMask(20, 32),
+			minLargest:  20,
+			wantError:   cidr.ErrNoAvailableCIDR,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			_, baseCIDR, _ := net.ParseCIDR(tc.baseCIDR)
+			usedCIDRs := make([]*net.IPNet, len(tc.usedCIDRs))
+			for i, usedCIDR := range tc.usedCIDRs {
+				_, usedCIDR, _ := net.ParseCIDR(usedCIDR)
+				usedCIDRs[i] = usedCIDR
+			}
+			got, err := cidr.FindAvailableCIDR(baseCIDR, &tc.desiredMask, usedCIDRs, cidr.WithMinLargestBlockAfter(tc.minLargest))
+			if tc.wantError != nil {
+				if !errors.Is(err, tc.wantError) || !strings.Contains(err.Error(), "would leave no /20 free") {
+					t.Fatalf("Invalid error, want: %v, got %v,", tc.wantError, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %s,", err.Error())
+			}
+			if got.String() != tc.want {
+				t.Fatalf("want: %v, got: %v", tc.want, got.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,74 @@
+package cidr
+
+import (
+	"fmt"
+	"net"
+)
+
+// Address classes returned by AddressClass.
+const (
+	AddressClassPublic        = "public"
+	AddressClassPrivate       = "private"
+	AddressClassShared        = "shared"
+	AddressClassLoopback      = "loopback"
+	AddressClassLinkLocal     = "link-local"
+	AddressClassMulticast     = "multicast"
+	AddressClassDocumentation = "documentation"
+)
+
+// specialRanges maps each address class other than public to the ranges it covers
+var specialRanges = map[string][]*net.IPNet{
+	AddressClassPrivate: mustParseCIDRs("10.0.0.0/8", "172.16.0.0/12", "192.168.0.0/16", "fc00::/7"),
+	// RFC 6598 shared address space for carrier-grade NAT
+	AddressClassShared:    mustParseCIDRs("100.64.0.0/10"),
+	AddressClassLoopback:  mustParseCIDRs("127.0.0.0/8", "::1/128"),
+	AddressClassLinkLocal: mustParseCIDRs("169.254.0.0/16", "fe80::/10"),
+	AddressClassMulticast: mustParseCIDRs("224.0.0.0/4", "ff00::/8"),
+	// RFC 5737 and RFC 3849 ranges reserved for examples, which aren't routable
+	AddressClassDocumentation: mustParseCIDRs("192.0.2.0/24", "198.51.100.0/24", "203.0.113.0/24", "2001:db8::/32"),
+}
+
+// AddressClass returns the class of address space n is in, such as AddressClassPrivate for
+// 10.0.0.0/16 or AddressClassDocumentation for 192.0.2.0/24. A CIDR which isn't entirely within one
+// of the special use ranges is AddressClassPublic.
+func AddressClass(n *net.IPNet) string {
+	if v4, ok := toIPv4Net(n); ok {
+		n = v4
+	}
+	for class, ranges := range specialRanges {
+		if withinAnyCIDR(n, ranges) {
+			return class
+		}
+	}
+	return AddressClassPublic
+}
+
+// RejectSpecialUse returns an ErrInvalidInputRanges error if the base CIDR is in link-local, multicast
+// or loopback address space, where allocating subnets is always a mistake.
+func RejectSpecialUse(base *net.IPNet) error {
+	switch class := AddressClass(base); class {
+	case AddressClassLinkLocal, AddressClassMulticast, AddressClassLoopback:
+		return fmt.Errorf("%w: base CIDR %s is in %s address space, which can't be allocated from", ErrInvalidInputRanges, base.String(), class)
+	}
+	return nil
+}
+
+// WithRejectSpecialUse makes FindAvailableCIDR check the root CIDR with RejectSpecialUse before
+// searching.
+func WithRejectSpecialUse() FindOption {
+	return func(o *findOptions) {
+		o.rejectSpecialUse = true
+	}
+}
+
+func mustParseCIDRs(values ...string) []*net.IPNet {
+	cidrs := make([]*net.IPNet, len(values))
+	for i, value := range values {
+		_, n, err := net.ParseCIDR(value)
+		if err != nil {
+			panic(err)
+		}
+		cidrs[i] = n
+	}
+	return cidrs
+}
@@ -0,0 +1,91 @@
+package cidr_test
+
+import (
+	"errors"
+	"net"
+	"testing"
+
+	"github.com/massdriver-cloud/cola/pkg/cidr"
+)
+
+func TestAddressClass(t *testing.T) {
+	type testData struct {
+		cidr string
+		want string
+	}
+	tests := []testData{
+		{cidr: "10.0.0.0/16", want: cidr.AddressClassPrivate},
+		{cidr: "172.31.0.0/16", want: cidr.AddressClassPrivate},
+		{cidr: "fd00::/64", want: cidr.AddressClassPrivate},
+		{cidr: "100.64.0.0/10", want: cidr.AddressClassShared},
+		{cidr: "100.127.255.0/24", want: cidr.AddressClassShared},
+		{cidr: "100.128.0.0/24", want: cidr.AddressClassPublic},
+		{cidr: "127.0.0.1/32", want: cidr.AddressClassLoopback},
+		{cidr: "169.254.0.0/16", want: cidr.AddressClassLinkLocal},
+		{cidr: "224.0.0.0/24", want: cidr.AddressClassMulticast},
+		{cidr: "192.0.2.0/24", want: cidr.AddressClassDocumentation},
+		{cidr: "192.0.2.255/32", want: cidr.AddressClassDocumentation},
+		{cidr: "192.0.1.255/32", want: cidr.AddressClassPublic},
+		{cidr: "192.0.3.0/32", want: cidr.AddressClassPublic},
+		{cidr: "198.51.100.0/24", want: cidr.AddressClassDocumentation},
+		{cidr: "198.51.99.255/32", want: cidr.AddressClassPublic},
+		{cidr: "198.51.101.0/32", want: cidr.AddressClassPublic},
+		{cidr: "203.0.113.0/24", want: cidr.AddressClassDocumentation},
+		{cidr: "203.0.112.255/32", want: cidr.AddressClassPublic},
+		{cidr: "203.0.114.0/32", want: cidr.AddressClassPublic},
+		{cidr: "192.0.2.0/23", want: cidr.AddressClassPublic},
+		{cidr: "2001:db8::/32", want: cidr.AddressClassDocumentation},
+		{cidr: "2001:db8:ffff:ffff::/64", want: cidr.AddressClassDocumentation},
+		{cidr: "2001:db7:ffff:ffff::/64", want: cidr.AddressClassPublic},
+		{cidr: "2001:db9::/64", want: cidr.AddressClassPublic},
+		{cidr: "::ffff:192.0.2.0/120", want: cidr.AddressClassDocumentation},
+		{cidr: "8.8.8.0/24", want: cidr.AddressClassPublic},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.cidr, func(t *testing.T) {
+			_, n, _ := net.ParseCIDR(tc.cidr)
+			got := cidr.AddressClass(n)
+			if got != tc.want {
+				t.Fatalf("want: %v, got: %v", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestFindAvailableCIDRWithRejectSpecialUse(t *testing.T) {
+	type testData struct {
+		name      string
+		baseCIDR  string
+		mask      net.IPMask
+		want      string
+		wantError error
+	}
+	tests := []testData{
+		{name: "Private", baseCIDR: "10.0.0.0/16", mask: net.CIDRMask(24, 32), want: "10.0.0.0/24"},
+		{name: "IPv4 multicast", baseCIDR: "239.1.0.0/16", mask: net.CIDRMask(24, 32), wantError: cidr.ErrInvalidInputRanges},
+		{name: "IPv4 link-local", baseCIDR: "169.254.0.0/16", mask: net.CIDRMask(24, 32), wantError: cidr.ErrInvalidInputRanges},
+		{name: "IPv4 loopback", baseCIDR: "127.0.0.0/8", mask: net.CIDRMask(24, 32), wantError: cidr.ErrInvalidInputRanges},
+		{name: "IPv6 multicast", baseCIDR: "ff02::/16", mask: net.CIDRMask(64, 128), wantError: cidr.ErrInvalidInputRanges},
+		{name: "IPv6 link-local", baseCIDR: "fe80::/10", mask: net.CIDRMask(64, 128), wantError: cidr.ErrInvalidInputRanges},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			_, baseCIDR, _ := net.ParseCIDR(tc.baseCIDR)
+			got, err := cidr.FindAvailableCIDR(baseCIDR, &tc.mask, []*net.IPNet{}, cidr.WithRejectSpecialUse())
+			if tc.wantError != nil {
+				if !errors.Is(err, tc.wantError) {
+					t.Fatalf("Invalid error, want: %v, got %v,", tc.wantError, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %s,", err.Error())
+			}
+			if got.String() != tc.want {
+				t.Fatalf("want: %v, got: %v", tc.want, got.String())
+			}
+		})
+	}
+}
@@ -18,3 +18,116 @@ func SmallerMask(smaller *net.IPMask, larger *net.IPMask) bool {
 
 	return smallerOnes > largerOnes
 }
+
+// PlansEqual returns true if a and b contain the same set of networks, ignoring order, duplicates
+// and differences in representation (see CanonicalKey).
+func PlansEqual(a []*net.IPNet, b []*net.IPNet) bool {
+	aKeys := canonicalKeySet(a)
+	bKeys := canonicalKeySet(b)
+	if len(aKeys) != len(bKeys) {
+		return false
+	}
+	for key := range aKeys {
+		if !bKeys[key] {
+			return false
+		}
+	}
+	return true
+}
+
+func canonicalKeySet(cidrs []*net.IPNet) map[string]bool {
+	keys := make(map[string]bool, len(cidrs))
+	for _, n := range cidrs {
+		keys[CanonicalKey(n)] = true
+	}
+	return keys
+}
+
+// PlanDiff is the difference between an expected and an actual set of networks, see Diff.
+type PlanDiff struct {
+	// Missing are the expected networks which don't overlap any actual network
+	Missing []*net.IPNet
+	// Unexpected are the actual networks which don't overlap any expected network
+	Unexpected []*net.IPNet
+	// Resized are the expected networks which overlap actual networks of a different size or position
+	Resized []ResizedCIDR
+}
+
+// ResizedCIDR pairs an expected network with an overlapping actual network which doesn't match it.
+type ResizedCIDR struct {
+	Expected *net.IPNet
+	Actual   *net.IPNet
+}
+
+// Empty returns true if there are no differences.
+func (d *PlanDiff) Empty() bool {
+	return len(d.Missing) == 0 && len(d.Unexpected) == 0 && len(d.Resized) == 0
+}
+
+// Diff compares the expected networks against the actual networks, ignoring order, duplicates and
+// differences in representation (see CanonicalKey). Networks in both are ignored, and the rest are
+// reported in their canonical form, in the order given, as missing, unexpected or resized.
+func Diff(expected []*net.IPNet, actual []*net.IPNet) *PlanDiff {
+	expectedKeys := canonicalKeySet(expected)
+	actualKeys := canonicalKeySet(actual)
+	onlyExpected := onlyIn(expected, actualKeys)
+	onlyActual := onlyIn(actual, expectedKeys)
+
+	diff := &PlanDiff{Missing: []*net.IPNet{}, Unexpected: []*net.IPNet{}, Resized: []ResizedCIDR{}}
+	for _, e := range onlyExpected {
+		found := false
+		for _, a := range onlyActual {
+			if OverlapsCIDR(e, a) {
+				diff.Resized = append(diff.Resized, ResizedCIDR{Expected: e, Actual: a})
+				found = true
+			}
+		}
+		if !found {
+			diff.Missing = append(diff.Missing, e)
+		}
+	}
+	for _, a := range onlyActual {
+		found := false
+		for _, e := range onlyExpected {
+			if OverlapsCIDR(a, e) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			diff.Unexpected = append(diff.Unexpected, a)
+		}
+	}
+	return diff
+}
+
+// onlyIn returns the canonical form of each network whose key isn't in keys, without duplicates.
+func onlyIn(cidrs []*net.IPNet, keys map[string]bool) []*net.IPNet {
+	seen := map[string]bool{}
+	result := []*net.IPNet{}
+	for _, n := range cidrs {
+		key := CanonicalKey(n)
+		if keys[key] || seen[key] {
+			continue
+		}
+		seen[key] = true
+		result = append(result, canonicalForm(n))
+	}
+	return result
+}
+
+// ToAllocate returns the minimal aligned CIDRs which must be newly allocated to cover all of target
+// given what current already covers, sorted as by Aggregate. Unlike Diff, which compares networks as
+// a whole, this works on addresses: a target 10.0.0.0/23 with 10.0.0.0/24 current needs only
+// 10.0.1.0/24. Both sets are cleaned up with Canonicalize first, so host bits, duplicates and
+// IPv4-mapped forms don't matter.
+func ToAllocate(target []*net.IPNet, current []*net.IPNet) []*net.IPNet {
+	current = Canonicalize(current)
+	uncovered := []*net.IPNet{}
+	for _, block := range Canonicalize(target) {
+		// canonical blocks always convert to a range, so FreeSpace can't fail
+		free, _ := FreeSpace(block, current)
+		uncovered = append(uncovered, free...)
+	}
+	return Canonicalize(uncovered)
+}
@@ -2,6 +2,7 @@ package cidr_test
 
 import (
 	"net"
+	"strings"
 	"testing"
 
 	"github.com/massdriver-cloud/cola/pkg/cidr"
@@ -51,3 +52,220 @@ func TestEqualCIDRs(t *testing.T) {
 		})
 	}
 }
+
+func TestPlansEqual(t *testing.T) {
+	type testData struct {
+		name string
+		a    []net.IPNet
+		b    []net.IPNet
+		want bool
+	}
+	tests := []testData{
+		{
+			name: "Reordered",
+			a: []net.IPNet{
+				{IP: net.IPv4(10, 0, 0, 0).To4(), Mask: net.CIDRMask(24, 32)},
+				{IP: net.IPv4(10, 0, 4, 0).To4(), Mask: net.CIDRMask(22, 32)},
+			},
+			b: []net.IPNet{
+				{IP: net.IPv4(10, 0, 4, 0).To4(), Mask: net.CIDRMask(22, 32)},
+				{IP: net.IPv4(10, 0, 0, 0).To4(), Mask: net.CIDRMask(24, 32)},
+			},
+			want: true,
+		},
+		{
+			name: "Mixed representations",
+			a: []net.IPNet{
+				{IP: net.IPv4(10, 0, 0, 0).To4(), Mask: net.CIDRMask(24, 32)},
+				{IP: net.IPv4(10, 0, 4, 0).To4(), Mask: net.CIDRMask(22, 32)},
+			},
+			b: []net.IPNet{
+				{IP: net.IPv4(10, 0, 0, 0), Mask: net.CIDRMask(120, 128)},
+				{IP: net.IPv4(10, 0, 5, 9).To4(), Mask: net.CIDRMask(22, 32)},
+			},
+			want: true,
+		},
+		{
+			name: "Duplicates",
+			a: []net.IPNet{
+				{IP: net.IPv4(10, 0, 0, 0).To4(), Mask: net.CIDRMask(24, 32)},
+				{IP: net.IPv4(10, 0, 0, 0).To4(), Mask: net.CIDRMask(24, 32)},
+			},
+			b: []net.IPNet{
+				{IP: net.IPv4(10, 0, 0, 0).To4(), Mask: net.CIDRMask(24, 32)},
+			},
+			want: true,
+		},
+		{
+			name: "Different size",
+			a: []net.IPNet{
+				{IP: net.IPv4(10, 0, 0, 0).To4(), Mask: net.CIDRMask(24, 32)},
+			},
+			b: []net.IPNet{
+				{IP: net.IPv4(10, 0, 0, 0).To4(), Mask: net.CIDRMask(23, 32)},
+			},
+			want: false,
+		},
+		{
+			name: "Missing network",
+			a: []net.IPNet{
+				{IP: net.IPv4(10, 0, 0, 0).To4(), Mask: net.CIDRMask(24, 32)},
+				{IP: net.IPv4(10, 0, 1, 0).To4(), Mask: net.CIDRMask(24, 32)},
+			},
+			b: []net.IPNet{
+				{IP: net.IPv4(10, 0, 0, 0).To4(), Mask: net.CIDRMask(24, 32)},
+			},
+			want: false,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			a := make([]*net.IPNet, len(tc.a))
+			for i := range tc.a {
+				a[i] = &tc.a[i]
+			}
+			b := make([]*net.IPNet, len(tc.b))
+			for i := range tc.b {
+				b[i] = &tc.b[i]
+			}
+			got := cidr.PlansEqual(a, b)
+			if got != tc.want {
+				t.Fatalf("want: %v, got: %v", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestDiff(t *testing.T) {
+	type testData struct {
+		name           string
+		expected       []string
+		actual         []string
+		wantMissing    []string
+		wantUnexpected []string
+		wantResized    []string
+	}
+	tests := []testData{
+		{
+			name:     "No drift",
+			expected: []string{"10.0.0.0/24", "10.0.4.0/22"},
+			actual:   []string{"10.0.4.0/22", "::ffff:10.0.0.0/120"},
+		},
+		{
+			name:           "Missing and unexpected",
+			expected:       []string{"10.0.0.0/24", "10.0.1.0/24"},
+			actual:         []string{"10.0.0.0/24", "10.0.9.0/24"},
+			wantMissing:    []string{"10.0.1.0/24"},
+			wantUnexpected: []string{"10.0.9.0/24"},
+		},
+		{
+			name:        "Resized",
+			expected:    []string{"10.0.4.0/22", "10.0.8.0/24"},
+			actual:      []string{"10.0.4.0/23", "10.0.8.0/23"},
+			wantResized: []string{"10.0.4.0/22=10.0.4.0/23", "10.0.8.0/24=10.0.8.0/23"},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			expected := make([]*net.IPNet, len(tc.expected))
+			for i, value := range tc.expected {
+				_, expected[i], _ = net.ParseCIDR(value)
+			}
+			actual := make([]*net.IPNet, len(tc.actual))
+			for i, value := range tc.actual {
+				_, actual[i], _ = net.ParseCIDR(value)
+			}
+			diff := cidr.Diff(expected, actual)
+
+			missing := make([]string, len(diff.Missing))
+			for i, n := range diff.Missing {
+				missing[i] = n.String()
+			}
+			if got := strings.Join(missing, ","); got != strings.Join(tc.wantMissing, ",") {
+				t.Fatalf("want: %v, got: %v", tc.wantMissing, got)
+			}
+			unexpected := make([]string, len(diff.Unexpected))
+			for i, n := range diff.Unexpected {
+				unexpected[i] = n.String()
+			}
+			if got := strings.Join(unexpected, ","); got != strings.Join(tc.wantUnexpected, ",") {
+				t.Fatalf("want: %v, got: %v", tc.wantUnexpected, got)
+			}
+			resized := make([]string, len(diff.Resized))
+			for i, r := range diff.Resized {
+				resized[i] = r.Expected.String() + "=" + r.Actual.String()
+			}
+			if got := strings.Join(resized, ","); got != strings.Join(tc.wantResized, ",") {
+				t.Fatalf("want: %v, got: %v", tc.wantResized, got)
+			}
+			if diff.Empty() != (len(tc.wantMissing)+len(tc.wantUnexpected)+len(tc.wantResized) == 0) {
+				t.Fatalf("want: %v, got: %v", !diff.Empty(), diff.Empty())
+			}
+		})
+	}
+}
+
+func TestToAllocate(t *testing.T) {
+	type testData struct {
+		name    string
+		target  []string
+		current []string
+		want    []string
+	}
+	tests := []testData{
+		{
+			name:    "Partly covered",
+			target:  []string{"10.0.0.0/22"},
+			current: []string{"10.0.0.0/24", "10.0.2.128/25"},
+			want:    []string{"10.0.1.0/24", "10.0.2.0/25", "10.0.3.0/24"},
+		},
+		{
+			name:    "Uncovered remainder merged",
+			target:  []string{"10.0.0.0/24", "10.0.1.0/24", "10.0.2.0/24", "10.0.3.0/24"},
+			current: []string{"10.0.0.0/24"},
+			want:    []string{"10.0.1.0/24", "10.0.2.0/23"},
+		},
+		{
+			name:    "Fully covered",
+			target:  []string{"10.0.4.0/24"},
+			current: []string{"10.0.0.0/16"},
+			want:    []string{},
+		},
+		{
+			name:    "Nothing current",
+			target:  []string{"10.0.4.0/24", "2001:db8::/64"},
+			current: []string{},
+			want:    []string{"10.0.4.0/24", "2001:db8::/64"},
+		},
+		{
+			name:    "Current outside target ignored",
+			target:  []string{"10.0.4.0/23"},
+			current: []string{"10.0.9.0/24", "10.0.5.0/24"},
+			want:    []string{"10.0.4.0/24"},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			target := make([]*net.IPNet, len(tc.target))
+			for i, value := range tc.target {
+				_, target[i], _ = net.ParseCIDR(value)
+			}
+			current := make([]*net.IPNet, len(tc.current))
+			for i, value := range tc.current {
+				_, current[i], _ = net.ParseCIDR(value)
+			}
+			got := cidr.ToAllocate(target, current)
+			if len(got) != len(tc.want) {
+				t.Fatalf("want: %v, got: %v", tc.want, got)
+			}
+			for i := range got {
+				if got[i].String() != tc.want[i] {
+					t.Fatalf("want: %v, got: %v", tc.want, got)
+				}
+			}
+		})
+	}
+}
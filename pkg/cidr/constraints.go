@@ -0,0 +1,48 @@
+package cidr
+
+import (
+	"fmt"
+	"net"
+)
+
+// Constraints are the prefix lengths a provider allows for a CIDR of one address family, such as
+// AWSSubnetConstraints. Bits is the address length the constraints apply to (32 for IPv4, 128 for
+// IPv6), and MinPrefix and MaxPrefix are the shortest and longest allowed prefix lengths.
+type Constraints struct {
+	Name      string
+	Bits      int
+	MinPrefix int
+	MaxPrefix int
+}
+
+// AWSSubnetConstraints are the allowed sizes of an AWS VPC subnet's IPv4 CIDR, /16 to /28.
+var AWSSubnetConstraints = Constraints{Name: "AWS subnets", Bits: 8 * net.IPv4len, MinPrefix: 16, MaxPrefix: 28}
+
+// Check returns an ErrInvalidInputRanges error if the mask is outside the allowed prefix lengths.
+// Masks of a different address family aren't checked.
+func (c Constraints) Check(mask *net.IPMask) error {
+	ones, bits := mask.Size()
+	if bits != c.Bits || (ones >= c.MinPrefix && ones <= c.MaxPrefix) {
+		return nil
+	}
+	return fmt.Errorf("%w: %s must be between /%d and /%d, got /%d", ErrInvalidInputRanges, c.Name, c.MinPrefix, c.MaxPrefix, ones)
+}
+
+// WithConstraints makes FindAvailableCIDR check the desired mask against the constraints before
+// searching, so an impossible request fails fast with a descriptive error rather than a search.
+func WithConstraints(constraints Constraints) FindOption {
+	return func(o *findOptions) {
+		o.constraints = append(o.constraints, constraints)
+	}
+}
+
+// IPv6SubnetConstraints keep IPv6 subnets at /64 or shorter, since SLAAC needs a 64 bit interface
+// identifier and doesn't work on smaller subnets.
+var IPv6SubnetConstraints = Constraints{Name: "IPv6 subnets using SLAAC", Bits: 8 * net.IPv6len, MinPrefix: 0, MaxPrefix: 64}
+
+// WithIPv6SubnetBoundary refuses to allocate IPv6 subnets smaller than a /64, with an error explaining
+// why, so subnets always keep the /64 boundary SLAAC relies on. The search never descends below the
+// desired mask, so it doesn't split a /64 either. IPv4 searches aren't affected.
+func WithIPv6SubnetBoundary() FindOption {
+	return WithConstraints(IPv6SubnetConstraints)
+}
@@ -0,0 +1,138 @@
+package cidr_test
+
+import (
+	"errors"
+	"net"
+	"testing"
+
+	"github.com/massdriver-cloud/cola/pkg/cidr"
+)
+
+func TestFindAvailableCIDRWithConstraints(t *testing.T) {
+	type testData struct {
+		name        string
+		baseCIDR    string
+		desiredMask net.IPMask
+		constraints cidr.Constraints
+		want        string
+		wantError   string
+	}
+	tests := []testData{
+		{
+			name:        "Within AWS limits",
+			baseCIDR:    "10.0.0.0/16",
+			desiredMask: net.CIDRMask(24, 32),
+			constraints: cidr.AWSSubnetConstraints,
+			want:        "10.0.0.0/24",
+		},
+		{
+			name:        "Too small for AWS",
+			baseCIDR:    "10.0.0.0/16",
+			desiredMask: net.CIDRMask(30, 32),
+			constraints: cidr.AWSSubnetConstraints,
+			wantError:   "input ranges invalid: AWS subnets must be between /16 and /28, got /30",
+		},
+		{
+			name:        "Too large for AWS",
+			baseCIDR:    "10.0.0.0/8",
+			desiredMask: net.CIDRMask(12, 32),
+			constraints: cidr.AWSSubnetConstraints,
+			wantError:   "input ranges invalid: AWS subnets must be between /16 and /28, got /12",
+		},
+		{
+			name:        "Other address family unchecked",
+			baseCIDR:    "2001:db8::/56",
+			desiredMask: net.CIDRMask(64, 128),
+			constraints: cidr.AWSSubnetConstraints,
+			want:        "2001:db8::/64",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			_, baseCIDR, _ := net.ParseCIDR(tc.baseCIDR)
+			got, err := cidr.FindAvailableCIDR(baseCIDR, &tc.desiredMask, []*net.IPNet{}, cidr.WithConstraints(tc.constraints))
+			if tc.wantError != "" {
+				if !errors.Is(err, cidr.ErrInvalidInputRanges) || err.Error() != tc.wantError {
+					t.Fatalf("Invalid error, want: %v, got %v,", tc.wantError, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %s,", err.Error())
+			}
+			if got.String() != tc.want {
+				t.Fatalf("want: %v, got: %v", tc.want, got.String())
+			}
+		})
+	}
+}
+
+func TestFindAvailableCIDRWithIPv6SubnetBoundary(t *testing.T) {
+	type testData struct {
+		name        string
+		baseCIDR    string
+		usedCIDRs   []string
+		desiredMask net.IPMask
+		want        string
+		wantError   string
+	}
+	tests := []testData{
+		{
+			name:        "/48 allocates a /64",
+			baseCIDR:    "2001:db8::/48",
+			usedCIDRs:   []string{"2001:db8::/64", "2001:db8:0:1::/64"},
+			desiredMask: net.CIDRMask(64, 128),
+			want:        "2001:db8:0:2::/64",
+		},
+		{
+			name:        "Skips a /64 split by a used CIDR",
+			baseCIDR:    "2001:db8::/48",
+			usedCIDRs:   []string{"2001:db8::/80"},
+			desiredMask: net.CIDRMask(64, 128),
+			want:        "2001:db8:0:1::/64",
+		},
+		{
+			name:        "Shorter than /64",
+			baseCIDR:    "2001:db8::/48",
+			desiredMask: net.CIDRMask(56, 128),
+			want:        "2001:db8::/56",
+		},
+		{
+			name:        "Rejects /80",
+			baseCIDR:    "2001:db8::/48",
+			desiredMask: net.CIDRMask(80, 128),
+			wantError:   "input ranges invalid: IPv6 subnets using SLAAC must be between /0 and /64, got /80",
+		},
+		{
+			name:        "IPv4 unaffected",
+			baseCIDR:    "10.0.0.0/16",
+			desiredMask: net.CIDRMask(28, 32),
+			want:        "10.0.0.0/28",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			_, baseCIDR, _ := net.ParseCIDR(tc.baseCIDR)
+			usedCIDRs := make([]*net.IPNet, len(tc.usedCIDRs))
+			for i, usedCIDR := range tc.usedCIDRs {
+				_, usedCIDR, _ := net.ParseCIDR(usedCIDR)
+				usedCIDRs[i] = usedCIDR
+			}
+			got, err := cidr.FindAvailableCIDR(baseCIDR, &tc.desiredMask, usedCIDRs, cidr.WithIPv6SubnetBoundary())
+			if tc.wantError != "" {
+				if !errors.Is(err, cidr.ErrInvalidInputRanges) || err.Error() != tc.wantError {
+					t.Fatalf("Invalid error, want: %v, got %v,", tc.wantError, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %s,", err.Error())
+			}
+			if got.String() != tc.want {
+				t.Fatalf("want: %v, got: %v", tc.want, got.String())
+			}
+		})
+	}
+}
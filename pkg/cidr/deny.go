@@ -0,0 +1,46 @@
+package cidr
+
+import "net"
+
+// ViolatesDenyList returns the first CIDR in the deny list which overlaps n, and true if
+// any overlap was found. Deny ranges don't need to be within any particular root CIDR.
+func ViolatesDenyList(n *net.IPNet, deny []*net.IPNet) (*net.IPNet, bool) {
+	for _, denied := range deny {
+		if OverlapsCIDR(n, denied) {
+			return denied, true
+		}
+	}
+	return nil, false
+}
+
+// WithDenyList prevents FindAvailableCIDR from returning any CIDR which overlaps one of the
+// deny ranges. Unlike used CIDRs, deny ranges may lie partially or entirely outside the root CIDR.
+// This is how to reserve space which nothing occupies yet, such as a range set aside for future
+// peering: it's never allocated, but as it isn't passed as used it isn't counted by Utilization.
+func WithDenyList(deny []*net.IPNet) FindOption {
+	return func(o *findOptions) {
+		o.denyList = append(o.denyList, deny...)
+	}
+}
+
+// WithAllowedSupernets is the inverse of WithDenyList: FindAvailableCIDR only returns CIDRs contained
+// within one of the allowed supernets, ignoring the rest of the root CIDR even where it's free. If no
+// allowed supernet has room, or none are given, an ErrNoAvailableCIDR error is returned. Parts of the
+// root outside every allowed supernet aren't searched, so this also speeds up a search of a large root.
+func WithAllowedSupernets(allowed []*net.IPNet) FindOption {
+	return func(o *findOptions) {
+		o.allowList = true
+		o.allowedSupernets = append(o.allowedSupernets, allowed...)
+	}
+}
+
+// allowed returns true if n is within an allowed supernet, or there is no allow list.
+func (o *findOptions) allowed(n *net.IPNet) bool {
+	return !o.allowList || withinAnyCIDR(n, o.allowedSupernets)
+}
+
+// outsideAllowed returns true if there is an allow list and none of it overlaps n, so nothing within n
+// can be returned.
+func (o *findOptions) outsideAllowed(n *net.IPNet) bool {
+	return o.allowList && !overlapsAnyCIDR(n, o.allowedSupernets)
+}
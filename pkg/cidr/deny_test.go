@@ -0,0 +1,281 @@
+package cidr_test
+
+import (
+	"errors"
+	"net"
+	"testing"
+
+	"github.com/massdriver-cloud/cola/pkg/cidr"
+)
+
+func TestViolatesDenyList(t *testing.T) {
+	type testData struct {
+		name     string
+		cidr     string
+		deny     []string
+		want     string
+		wantBool bool
+	}
+	tests := []testData{
+		{
+			name:     "No deny list",
+			cidr:     "10.0.0.0/24",
+			deny:     []string{},
+			want:     "",
+			wantBool: false,
+		},
+		{
+			name:     "Disjoint",
+			cidr:     "10.0.0.0/24",
+			deny:     []string{"10.0.1.0/24", "192.168.0.0/16"},
+			want:     "",
+			wantBool: false,
+		},
+		{
+			name:     "Within denied",
+			cidr:     "10.0.1.0/24",
+			deny:     []string{"192.168.0.0/16", "10.0.0.0/16"},
+			want:     "10.0.0.0/16",
+			wantBool: true,
+		},
+		{
+			name:     "Contains denied",
+			cidr:     "10.0.0.0/16",
+			deny:     []string{"192.168.0.0/16", "10.0.5.0/28"},
+			want:     "10.0.5.0/28",
+			wantBool: true,
+		},
+		{
+			name:     "Returns first violation",
+			cidr:     "10.0.0.0/16",
+			deny:     []string{"10.0.5.0/28", "10.0.0.0/8"},
+			want:     "10.0.5.0/28",
+			wantBool: true,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			_, n, _ := net.ParseCIDR(tc.cidr)
+			deny := make([]*net.IPNet, len(tc.deny))
+			for i, denied := range tc.deny {
+				_, denied, _ := net.ParseCIDR(denied)
+				deny[i] = denied
+			}
+			got, gotBool := cidr.ViolatesDenyList(n, deny)
+
+			if gotBool != tc.wantBool {
+				t.Fatalf("want: %v, got: %v", tc.wantBool, gotBool)
+			}
+			if tc.wantBool && got.String() != tc.want {
+				t.Fatalf("want: %v, got: %v", tc.want, got.String())
+			}
+		})
+	}
+}
+
+func TestFindAvailableCIDRWithDenyList(t *testing.T) {
+	type testData struct {
+		name        string
+		baseCIDR    string
+		usedCIDRs   []string
+		deny        []string
+		desiredMask net.IPMask
+		want        string
+		wantError   error
+	}
+	tests := []testData{
+		{
+			name:        "Skips denied block",
+			baseCIDR:    "10.0.0.0/16",
+			usedCIDRs:   []string{},
+			deny:        []string{"10.0.0.0/24"},
+			desiredMask: net.CIDRMask(24, 32),
+			want:        "10.0.1.0/24",
+		},
+		{
+			name:        "Skips block containing denied range",
+			baseCIDR:    "10.0.0.0/16",
+			usedCIDRs:   []string{"10.0.0.0/24"},
+			deny:        []string{"10.0.2.7/32"},
+			desiredMask: net.CIDRMask(23, 32),
+			want:        "10.0.4.0/23",
+		},
+		{
+			name:        "Deny list larger than base",
+			baseCIDR:    "10.0.0.0/16",
+			usedCIDRs:   []string{},
+			deny:        []string{"10.0.0.0/8"},
+			desiredMask: net.CIDRMask(24, 32),
+			wantError:   cidr.ErrNoAvailableCIDR,
+		},
+		{
+			name:        "Deny list outside base is ignored",
+			baseCIDR:    "10.0.0.0/16",
+			usedCIDRs:   []string{},
+			deny:        []string{"192.168.0.0/16"},
+			desiredMask: net.CIDRMask(24, 32),
+			want:        "10.0.0.0/24",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			_, baseCIDR, _ := net.ParseCIDR(tc.baseCIDR)
+			usedCIDRs := make([]*net.IPNet, len(tc.usedCIDRs))
+			for i, usedCIDR := range tc.usedCIDRs {
+				_, usedCIDR, _ := net.ParseCIDR(usedCIDR)
+				usedCIDRs[i] = usedCIDR
+			}
+			deny := make([]*net.IPNet, len(tc.deny))
+			for i, denied := range tc.deny {
+				_, denied, _ := net.ParseCIDR(denied)
+				deny[i] = denied
+			}
+			got, err := cidr.FindAvailableCIDR(baseCIDR, &tc.desiredMask, usedCIDRs, cidr.WithDenyList(deny))
+			if tc.wantError != nil {
+				if !errors.Is(err, tc.wantError) {
+					t.Fatalf("Invalid error, want: %v, got %v,", tc.wantError, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %s,", err.Error())
+			}
+			if got.String() != tc.want {
+				t.Fatalf("want: %v, got: %v", tc.want, got.String())
+			}
+		})
+	}
+}
+
+func TestFindAvailableCIDRWithReservedRange(t *testing.T) {
+	_, root, _ := net.ParseCIDR("10.0.0.0/22")
+	_, used, _ := net.ParseCIDR("10.0.0.0/24")
+	_, reserved, _ := net.ParseCIDR("10.0.1.0/25")
+	usedCIDRs := []*net.IPNet{used}
+	mask := net.CIDRMask(24, 32)
+
+	// 10.0.1.0/24 would be chosen, but overlaps the range reserved for peering
+	got, err := cidr.FindAvailableCIDR(root, &mask, usedCIDRs)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s,", err.Error())
+	}
+	if got.String() != "10.0.1.0/24" {
+		t.Fatalf("want: %v, got: %v", "10.0.1.0/24", got.String())
+	}
+	got, err = cidr.FindAvailableCIDR(root, &mask, usedCIDRs, cidr.WithDenyList([]*net.IPNet{reserved}))
+	if err != nil {
+		t.Fatalf("Unexpected error: %s,", err.Error())
+	}
+	if got.String() != "10.0.2.0/24" {
+		t.Fatalf("want: %v, got: %v", "10.0.2.0/24", got.String())
+	}
+
+	// the reserved range isn't used, so it doesn't count towards utilization
+	stats, err := cidr.Utilization(root, usedCIDRs)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s,", err.Error())
+	}
+	if stats.Percent != 25 {
+		t.Fatalf("want: %v, got: %v", 25, stats.Percent)
+	}
+}
+
+func TestFindAvailableCIDRWithAllowedSupernets(t *testing.T) {
+	type testData struct {
+		name        string
+		baseCIDR    string
+		usedCIDRs   []string
+		desiredMask net.IPMask
+		allowed     []string
+		want        string
+		wantError   error
+	}
+	tests := []testData{
+		{
+			name:        "Free blocks outside skipped",
+			baseCIDR:    "10.0.0.0/16",
+			usedCIDRs:   []string{},
+			desiredMask: net.CIDRMask(24, 32),
+			allowed:     []string{"10.0.128.0/20"},
+			want:        "10.0.128.0/24",
+		},
+		{
+			name:        "First allowed supernet full",
+			baseCIDR:    "10.0.0.0/16",
+			usedCIDRs:   []string{"10.0.16.0/22"},
+			desiredMask: net.CIDRMask(24, 32),
+			allowed:     []string{"10.0.200.0/22", "10.0.16.0/22"},
+			want:        "10.0.200.0/24",
+		},
+		{
+			name:        "Result larger than an allowed supernet",
+			baseCIDR:    "10.0.0.0/16",
+			usedCIDRs:   []string{},
+			desiredMask: net.CIDRMask(22, 32),
+			allowed:     []string{"10.0.4.0/24", "10.0.8.0/21"},
+			want:        "10.0.8.0/22",
+		},
+		{
+			name:        "Allowed supernet partly outside the root",
+			baseCIDR:    "10.0.0.0/16",
+			usedCIDRs:   []string{"10.0.0.0/24"},
+			desiredMask: net.CIDRMask(24, 32),
+			allowed:     []string{"10.0.0.0/8"},
+			want:        "10.0.1.0/24",
+		},
+		{
+			name:        "Error no room in allowed supernets",
+			baseCIDR:    "10.0.0.0/16",
+			usedCIDRs:   []string{"10.0.4.0/23"},
+			desiredMask: net.CIDRMask(24, 32),
+			allowed:     []string{"10.0.4.0/23"},
+			wantError:   cidr.ErrNoAvailableCIDR,
+		},
+		{
+			name:        "Error empty allow list",
+			baseCIDR:    "10.0.0.0/16",
+			usedCIDRs:   []string{},
+			desiredMask: net.CIDRMask(24, 32),
+			allowed:     []string{},
+			wantError:   cidr.ErrNoAvailableCIDR,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			_, baseCIDR, _ := net.ParseCIDR(tc.baseCIDR)
+			usedCIDRs := make([]*net.IPNet, len(tc.usedCIDRs))
+			for i, usedCIDR := range tc.usedCIDRs {
+				_, usedCIDR, _ := net.ParseCIDR(usedCIDR)
+				usedCIDRs[i] = usedCIDR
+			}
+			allowed := make([]*net.IPNet, len(tc.allowed))
+			for i, supernet := range tc.allowed {
+				_, supernet, _ := net.ParseCIDR(supernet)
+				allowed[i] = supernet
+			}
+			got, err := cidr.FindAvailableCIDR(baseCIDR, &tc.desiredMask, usedCIDRs, cidr.WithAllowedSupernets(allowed))
+			if tc.wantError != nil {
+				if !errors.Is(err, tc.wantError) {
+					t.Fatalf("Invalid error, want: %v, got %v,", tc.wantError, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %s,", err.Error())
+			}
+			if got.String() != tc.want {
+				t.Fatalf("want: %v, got: %v", tc.want, got.String())
+			}
+			within := false
+			for _, supernet := range allowed {
+				within = within || cidr.ContainsCIDR(supernet, got)
+			}
+			if !within {
+				t.Fatalf("want: result within %v, got: %v", tc.allowed, got)
+			}
+		})
+	}
+}
@@ -2,9 +2,48 @@ package cidr
 
 import (
 	"errors"
+	"fmt"
+	"net"
 )
 
 var (
-	ErrNoAvailableCidr    = errors.New("unable to find available CIDR range")
-	ErrInvalidInputRanges = errors.New("input ranges invalid")
+	ErrNoAvailableCIDR     = errors.New("unable to find available CIDR range")
+	ErrInvalidInputRanges  = errors.New("input ranges invalid")
+	ErrSearchDepthExceeded = errors.New("search depth exceeded")
+	ErrOverlappingCIDRs    = errors.New("CIDR ranges overlap")
+
+	// ErrNoAvailableCidr is the original name of ErrNoAvailableCIDR, kept so existing callers still
+	// build. It's the same error, so errors.Is matches either name.
+	//
+	// Deprecated: use ErrNoAvailableCIDR.
+	ErrNoAvailableCidr = ErrNoAvailableCIDR
+
+	// ErrRootFullyUsed is returned when a used CIDR is identical to the root CIDR. It wraps
+	// ErrNoAvailableCIDR, since the root is valid but has no space left.
+	ErrRootFullyUsed = fmt.Errorf("%w: a used CIDR matches the root CIDR", ErrNoAvailableCIDR)
+	// ErrRootInsideUsed is returned when the root CIDR is strictly within a used CIDR. It wraps
+	// ErrInvalidInputRanges, since a root inside a used range points to a mistake in the inputs.
+	ErrRootInsideUsed = fmt.Errorf("%w: root CIDR is within a used CIDR", ErrInvalidInputRanges)
+	// ErrStateConflict is returned by (*Allocator).SaveAtomic when the state file was saved by someone
+	// else after the allocator loaded it, so saving would overwrite their changes.
+	ErrStateConflict = errors.New("allocator state was changed by another writer")
+	// ErrContention is returned by (*Allocator).ReserveWithRetry when every attempt found the allocator
+	// busy with another call. Unlike ErrNoAvailableCIDR, trying again later may succeed.
+	ErrContention = errors.New("allocator is busy")
 )
+
+// ConflictError is returned by FindAvailableCIDR when WithConflictReport is set and no CIDR is
+// available. Blockers lists the used CIDRs which matched or were contained by the candidates that
+// were checked, in the order they were found. It unwraps to the underlying error.
+type ConflictError struct {
+	Blockers []*net.IPNet
+	Err      error
+}
+
+func (e *ConflictError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *ConflictError) Unwrap() error {
+	return e.Err
+}
@@ -1,36 +1,134 @@
 package cidr
 
 import (
+	"errors"
 	"fmt"
 	"net"
+	"sort"
 
 	"github.com/apparentlymart/go-cidr/cidr"
 )
 
 // FindAvailableCIDR will find a CIDR range of specified desiredMask size within the
-// rootCIDR given a list of already existing usedCIDRs.
-func FindAvailableCIDR(rootCIDR *net.IPNet, desiredMask *net.IPMask, usedCIDRs []*net.IPNet) (*net.IPNet, error) {
+// rootCIDR given a list of already existing usedCIDRs. Optional behavior can be configured with FindOptions.
+// A rootCIDR of 0.0.0.0/0 or ::/0 searches the full address space of that family.
+func FindAvailableCIDR(rootCIDR *net.IPNet, desiredMask *net.IPMask, usedCIDRs []*net.IPNet, opts ...FindOption) (*net.IPNet, error) {
+	options := newFindOptions(opts)
+	if result, found := options.findInPreferredRegion(rootCIDR, desiredMask, usedCIDRs, opts); found {
+		return result, nil
+	}
+
+	rootCIDR, desiredMask, usedCIDRs, err := normalizeInputs(rootCIDR, desiredMask, usedCIDRs)
+	if err != nil {
+		return nil, err
+	}
+	if err = options.checkUsed(usedCIDRs); err != nil {
+		return nil, err
+	}
+	if options.rejectSpecialUse {
+		if err = RejectSpecialUse(rootCIDR); err != nil {
+			return nil, err
+		}
+	}
+	for _, constraints := range options.constraints {
+		if err = constraints.Check(desiredMask); err != nil {
+			return nil, err
+		}
+	}
+	rootCIDR, usedCIDRs, err = canonicalizeInputs(rootCIDR, usedCIDRs, options.normalizeInput)
+	if err != nil {
+		return nil, err
+	}
+	options.applyExcludeBaseEdges(rootCIDR)
+	options.applyMinLargestBlock(rootCIDR, usedCIDRs)
+	options.applyMaxSubnetsUnder(usedCIDRs)
+	options.applyMinimizeRoutes(usedCIDRs)
+	usedCIDRs = options.applyKeepOut(rootCIDR, usedCIDRs)
+	usedCIDRs, err = options.applyStride(rootCIDR, desiredMask, usedCIDRs)
+	if err != nil {
+		return nil, err
+	}
+
 	// if somehow the rootCIDR is within a used CIDR, then this is impossible
 	for _, used := range usedCIDRs {
 		if ContainsCIDR(used, rootCIDR) {
 			// If the masks are equal this just means the the used CIDR is identical to the root CIDR, but still means theres no more space
 			if EqualMask(&rootCIDR.Mask, &used.Mask) {
-				return nil, fmt.Errorf("%w: a used CIDR matches the root CIDR", ErrNoAvailableCidr)
+				options.recordConflicts(rootCIDR, []*net.IPNet{used})
+				return nil, options.conflictError(ErrRootFullyUsed)
 			}
-			return nil, fmt.Errorf("%w: root CIDR is within a used CIDR", ErrInvalidInputRanges)
+			return nil, ErrRootInsideUsed
 		}
 	}
 
 	// If the root cidr has a smaller mask than the desired cidr, then this is impossible
 	if SmallerMask(&rootCIDR.Mask, desiredMask) {
-		return nil, fmt.Errorf("%w: desired mask is larger than the root CIDR range", ErrNoAvailableCidr)
+		return nil, fmt.Errorf("%w: desired mask is larger than the root CIDR range", ErrNoAvailableCIDR)
+	}
+
+	search := options.applyScorer()
+	result, err := evaluateCidr(rootCIDR, desiredMask, usedCIDRs, options)
+	if search != nil && search.best != nil && (err == nil || errors.Is(err, ErrNoAvailableCIDR)) {
+		// the walk ends with the candidate that hit the limit, or with no candidate at all
+		return search.best, nil
+	}
+	if err != nil {
+		return nil, options.conflictError(options.guardError(err))
+	}
+	return result, nil
+}
+
+// FindAvailableCIDRPath is FindAvailableCIDR which also returns the path the walk took to the result:
+// the root CIDR, each ancestor of the result in turn, and the result itself. For the example below
+// this is 10.0.0.0/16, 10.0.0.0/17, 10.0.64.0/18, 10.0.64.0/19, 10.0.80.0/20 and 10.0.88.0/21.
+func FindAvailableCIDRPath(rootCIDR *net.IPNet, desiredMask *net.IPMask, usedCIDRs []*net.IPNet, opts ...FindOption) (*net.IPNet, []*net.IPNet, error) {
+	result, err := FindAvailableCIDR(rootCIDR, desiredMask, usedCIDRs, opts...)
+	if err != nil {
+		return nil, nil, err
 	}
 
-	return evaluateCidr(rootCIDR, desiredMask, usedCIDRs)
+	// the walk only descends into children containing the result, so the path is its ancestors
+	rootOnes, _ := rootCIDR.Mask.Size()
+	ones, bits := result.Mask.Size()
+	if _, rootBits := rootCIDR.Mask.Size(); rootBits != bits {
+		// an IPv4-mapped root was normalized to IPv4 like the result
+		rootOnes -= ipv4MappedPrefix
+	}
+	path := make([]*net.IPNet, 0, ones-rootOnes+1)
+	for prefix := rootOnes; prefix <= ones; prefix++ {
+		mask := net.CIDRMask(prefix, bits)
+		path = append(path, &net.IPNet{IP: result.IP.Mask(mask), Mask: mask})
+	}
+	return result, path, nil
+}
+
+// FindAvailableCIDRs finds count CIDRs of the desiredMask size within the rootCIDR which don't collide
+// with the usedCIDRs or each other, as if FindAvailableCIDR was called count times adding each result
+// to the used CIDRs. The usedCIDRs slice is not modified. If fewer than count are available, the ones
+// found are returned along with an ErrNoAvailableCIDR error.
+func FindAvailableCIDRs(rootCIDR *net.IPNet, desiredMask *net.IPMask, count int, usedCIDRs []*net.IPNet) ([]*net.IPNet, error) {
+	if count < 0 {
+		return nil, fmt.Errorf("%w: count can't be negative, got %d", ErrInvalidInputRanges, count)
+	}
+	used := make([]*net.IPNet, len(usedCIDRs), len(usedCIDRs)+count)
+	copy(used, usedCIDRs)
+	found := make([]*net.IPNet, 0, count)
+	for len(found) < count {
+		result, err := FindAvailableCIDR(rootCIDR, desiredMask, used)
+		if errors.Is(err, ErrNoAvailableCIDR) {
+			return found, fmt.Errorf("%w: found %d of %d CIDRs", err, len(found), count)
+		}
+		if err != nil {
+			return nil, err
+		}
+		found = append(found, result)
+		used = append(used, result)
+	}
+	return found, nil
 }
 
 //                                Core Algorithm
-// We're going to walk down the CIDR, each iteration checking the current CIDR to see:
+// We're going to walk down the CIDR, depth first, each iteration checking the current CIDR to see:
 //   1. If we match an existing CIDR, skip it
 //   2. If our mask fits the desired mask size then just make sure...
 //   3. We don't contain an already existing CIDR (a /18 block might look good, til you check to see there are existing /20 blocks within it)
@@ -71,36 +169,83 @@ func FindAvailableCIDR(rootCIDR *net.IPNet, desiredMask *net.IPMask, usedCIDRs [
 //                     (contains another subnet)   FOUND MATCH!
 //
 //                                 RESULT: 10.0.88.0/21
-func evaluateCidr(current *net.IPNet, desiredMask *net.IPMask, usedCIDRs []*net.IPNet) (*net.IPNet, error) {
-	if MatchesExistingCIDR(current, usedCIDRs) {
-		return nil, fmt.Errorf("%w: CIDR range collides with an existing CIDR", ErrNoAvailableCidr)
-	}
+func evaluateCidr(rootCIDR *net.IPNet, desiredMask *net.IPMask, usedCIDRs []*net.IPNet, options *findOptions) (*net.IPNet, error) {
+	// the walk keeps its own stack rather than recursing, so a large difference between the root and
+	// desired mask, such as a /120 in an IPv6 /8, doesn't grow the goroutine stack. Children are pushed
+	// in reverse so the first child is still visited first.
+	stack := []walkNode{{cidr: rootCIDR}}
+	for len(stack) > 0 {
+		node := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
 
-	if EqualMask(desiredMask, &current.Mask) {
-		if ContainsExistingCIDR(current, usedCIDRs) {
-			return nil, fmt.Errorf("%w: CIDR range contains an existing CIDR", ErrNoAvailableCidr)
-		} else {
+		descend, err := visitCidr(node.cidr, desiredMask, usedCIDRs, options, node.depth)
+		if err != nil {
+			// exceeding the depth aborts the entire walk rather than just this branch, and the root
+			// has no other branches, so either way there's nothing left to search
+			if errors.Is(err, ErrSearchDepthExceeded) || node.depth == 0 {
+				return nil, err
+			}
+			continue
+		}
+		if !descend {
 			// We found it!
-			return current, nil
+			return node.cidr, nil
 		}
-	} else {
-		child1, child2, err := ChildCIDRs(current)
+
+		child1, child2, err := ChildCIDRs(node.cidr)
 		if err != nil {
 			return nil, err
 		}
-
-		for _, child := range []*net.IPNet{child1, child2} {
-			result, err := evaluateCidr(child, desiredMask, usedCIDRs)
-			// if the result is set with no errors it means we found a CIDR, and should return it
-			// all the way up the stack. Otherwise we no-op, which will either check the other child,
-			// or return the catch-all error that no CIDRs exist in this current branch of the tree
-			if result != nil && err == nil {
-				return result, nil
-			}
+		children := options.childOrder(child1, child2)
+		for i := len(children) - 1; i >= 0; i-- {
+			stack = append(stack, walkNode{cidr: children[i], depth: node.depth + 1})
 		}
 	}
 
-	return nil, fmt.Errorf("%w: searched all available ranges could not find space for requested mask", ErrNoAvailableCidr)
+	return nil, fmt.Errorf("%w: searched all available ranges could not find space for requested mask", ErrNoAvailableCIDR)
+}
+
+// walkNode is a CIDR waiting to be visited by evaluateCidr, with its depth below the root.
+type walkNode struct {
+	cidr  *net.IPNet
+	depth int
+}
+
+// visitCidr checks a single CIDR of the walk. An error means neither it nor anything within it can be
+// the result. Otherwise descend is true if the walk should carry on into its children, or false if
+// it's the result.
+func visitCidr(current *net.IPNet, desiredMask *net.IPMask, usedCIDRs []*net.IPNet, options *findOptions, depth int) (bool, error) {
+	if options.maxDepth >= 0 && depth > options.maxDepth {
+		return false, fmt.Errorf("%w: exceeded maximum depth of %d", ErrSearchDepthExceeded, options.maxDepth)
+	}
+
+	if MatchesExistingCIDR(current, usedCIDRs) {
+		options.recordConflicts(current, usedCIDRs)
+		return false, fmt.Errorf("%w: CIDR range collides with an existing CIDR", ErrNoAvailableCIDR)
+	}
+
+	if withinAnyCIDR(current, options.denyList) {
+		return false, fmt.Errorf("%w: CIDR range is within a denied CIDR", ErrNoAvailableCIDR)
+	}
+
+	if options.outsideAllowed(current) {
+		return false, fmt.Errorf("%w: CIDR range is outside every allowed supernet", ErrNoAvailableCIDR)
+	}
+
+	if !EqualMask(desiredMask, &current.Mask) {
+		return true, nil
+	}
+	if ContainsExistingCIDR(current, usedCIDRs) {
+		options.recordConflicts(current, usedCIDRs)
+		return false, fmt.Errorf("%w: CIDR range contains an existing CIDR", ErrNoAvailableCIDR)
+	} else if _, denied := ViolatesDenyList(current, options.denyList); denied {
+		return false, fmt.Errorf("%w: CIDR range overlaps a denied CIDR", ErrNoAvailableCIDR)
+	} else if !options.allowed(current) {
+		return false, fmt.Errorf("%w: CIDR range is not within an allowed supernet", ErrNoAvailableCIDR)
+	} else if !options.acceptCandidate(current) {
+		return false, fmt.Errorf("%w: CIDR range rejected by find options", ErrNoAvailableCIDR)
+	}
+	return false, nil
 }
 
 func MatchesExistingCIDR(currentCIDR *net.IPNet, usedCIDRs []*net.IPNet) bool {
@@ -123,6 +268,24 @@ func ContainsExistingCIDR(currentCIDR *net.IPNet, usedCIDRs []*net.IPNet) bool {
 	return false
 }
 
+// UsedWithin returns the usedCIDRs contained within the region, including any equal to it, sorted by
+// address and then by prefix length.
+func UsedWithin(region *net.IPNet, usedCIDRs []*net.IPNet) []*net.IPNet {
+	within := []*net.IPNet{}
+	for _, usedCIDR := range usedCIDRs {
+		if ContainsCIDR(region, usedCIDR) {
+			within = append(within, usedCIDR)
+		}
+	}
+	sort.Slice(within, func(i, j int) bool {
+		if c := IPToInt(within[i].IP).Cmp(IPToInt(within[j].IP)); c != 0 {
+			return c < 0
+		}
+		return BlockSizePrefix(&within[i].Mask) < BlockSizePrefix(&within[j].Mask)
+	})
+	return within
+}
+
 // ContainsCIDR returns true if the childCIDR is contained within parentCIDR, and false otherwise.
 // Comparison checking is inclusive, so identical CIDRs will return true.
 func ContainsCIDR(parentCIDR *net.IPNet, childCIDR *net.IPNet) bool {
@@ -133,7 +296,40 @@ func ContainsCIDR(parentCIDR *net.IPNet, childCIDR *net.IPNet) bool {
 	return false
 }
 
-// ChildCIDRs will return the two child CIDRs from extending the mask 1 bit
+// ContainsIP returns true if ip is within n. IPv4 addresses match regardless of whether they
+// are stored in their 4 or 16 byte form.
+func ContainsIP(n *net.IPNet, ip net.IP) bool {
+	return n.Contains(ip)
+}
+
+// OverlapsCIDR returns true if x and y share any addresses. Since CIDR ranges are either
+// nested or disjoint, this is true exactly when one contains the other.
+func OverlapsCIDR(x *net.IPNet, y *net.IPNet) bool {
+	return ContainsCIDR(x, y) || ContainsCIDR(y, x)
+}
+
+// withinAnyCIDR returns true if currentCIDR is contained within any of the cidrs.
+func withinAnyCIDR(currentCIDR *net.IPNet, cidrs []*net.IPNet) bool {
+	for _, c := range cidrs {
+		if ContainsCIDR(c, currentCIDR) {
+			return true
+		}
+	}
+	return false
+}
+
+// overlapsAnyCIDR returns true if currentCIDR overlaps any of the cidrs.
+func overlapsAnyCIDR(currentCIDR *net.IPNet, cidrs []*net.IPNet) bool {
+	for _, c := range cidrs {
+		if OverlapsCIDR(c, currentCIDR) {
+			return true
+		}
+	}
+	return false
+}
+
+// ChildCIDRs will return the two child CIDRs from extending the mask 1 bit. The children of the full
+// address space (0.0.0.0/0 or ::/0) are its two halves, and a single address has no children.
 func ChildCIDRs(parent *net.IPNet) (*net.IPNet, *net.IPNet, error) {
 	child1, err := cidr.Subnet(parent, 1, 0)
 	if err != nil {
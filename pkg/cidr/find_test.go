@@ -2,8 +2,10 @@ package cidr_test
 
 import (
 	"errors"
+	"fmt"
 	"net"
 	"testing"
+	"time"
 
 	"github.com/massdriver-cloud/cola/pkg/cidr"
 )
@@ -116,7 +118,7 @@ func TestFindAvailableCIDRs(t *testing.T) {
 			},
 			desiredMask: net.CIDRMask(16, 32),
 			want:        "",
-			wantError:   cidr.ErrNoAvailableCidr,
+			wantError:   cidr.ErrNoAvailableCIDR,
 		},
 		{
 			name:     "Error full",
@@ -129,7 +131,7 @@ func TestFindAvailableCIDRs(t *testing.T) {
 			},
 			desiredMask: net.CIDRMask(24, 32),
 			want:        "",
-			wantError:   cidr.ErrNoAvailableCidr,
+			wantError:   cidr.ErrNoAvailableCIDR,
 		},
 		{
 			name:        "Error Mask too large",
@@ -137,7 +139,7 @@ func TestFindAvailableCIDRs(t *testing.T) {
 			usedCIDRs:   []string{},
 			desiredMask: net.CIDRMask(15, 32),
 			want:        "",
-			wantError:   cidr.ErrNoAvailableCidr,
+			wantError:   cidr.ErrNoAvailableCIDR,
 		},
 		{
 			name:        "baseCIDR is usedCIDR",
@@ -145,7 +147,7 @@ func TestFindAvailableCIDRs(t *testing.T) {
 			usedCIDRs:   []string{"10.0.0.0/16"},
 			desiredMask: net.CIDRMask(24, 32),
 			want:        "",
-			wantError:   cidr.ErrNoAvailableCidr,
+			wantError:   cidr.ErrNoAvailableCIDR,
 		},
 		{
 			name:        "baseCIDR within usedCIDR",
@@ -185,6 +187,71 @@ func TestFindAvailableCIDRs(t *testing.T) {
 	}
 }
 
+func TestFindAvailableCIDRPath(t *testing.T) {
+	type testData struct {
+		name        string
+		baseCIDR    string
+		usedCIDRs   []string
+		desiredMask net.IPMask
+		want        []string
+	}
+	tests := []testData{
+		{
+			name:        "Documented example",
+			baseCIDR:    "10.0.0.0/16",
+			usedCIDRs:   []string{"10.0.0.0/18", "10.0.64.0/20", "10.0.80.0/24"},
+			desiredMask: net.CIDRMask(21, 32),
+			want:        []string{"10.0.0.0/16", "10.0.0.0/17", "10.0.64.0/18", "10.0.64.0/19", "10.0.80.0/20", "10.0.88.0/21"},
+		},
+		{
+			name:        "Root is the result",
+			baseCIDR:    "10.0.0.0/24",
+			usedCIDRs:   []string{},
+			desiredMask: net.CIDRMask(24, 32),
+			want:        []string{"10.0.0.0/24"},
+		},
+		{
+			name:        "IPv4-mapped root",
+			baseCIDR:    "::ffff:10.0.0.0/120",
+			usedCIDRs:   []string{"10.0.0.0/25"},
+			desiredMask: net.CIDRMask(25, 32),
+			want:        []string{"10.0.0.0/24", "10.0.0.128/25"},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			_, baseCIDR, _ := net.ParseCIDR(tc.baseCIDR)
+			usedCIDRs := make([]*net.IPNet, len(tc.usedCIDRs))
+			for i, usedCIDR := range tc.usedCIDRs {
+				_, usedCIDR, _ := net.ParseCIDR(usedCIDR)
+				usedCIDRs[i] = usedCIDR
+			}
+			result, path, err := cidr.FindAvailableCIDRPath(baseCIDR, &tc.desiredMask, usedCIDRs)
+			if err != nil {
+				t.Fatalf("Unexpected error: %s,", err.Error())
+			}
+			if len(path) != len(tc.want) {
+				t.Fatalf("want: %v, got: %v", tc.want, path)
+			}
+			for i := range path {
+				if path[i].String() != tc.want[i] {
+					t.Fatalf("want: %v, got: %v", tc.want[i], path[i].String())
+				}
+			}
+			if result.String() != tc.want[len(tc.want)-1] {
+				t.Fatalf("want: %v, got: %v", tc.want[len(tc.want)-1], result.String())
+			}
+		})
+	}
+
+	_, baseCIDR, _ := net.ParseCIDR("10.0.0.0/24")
+	mask := net.CIDRMask(24, 32)
+	if _, _, err := cidr.FindAvailableCIDRPath(baseCIDR, &mask, []*net.IPNet{baseCIDR}); !errors.Is(err, cidr.ErrNoAvailableCIDR) {
+		t.Fatalf("Invalid error, want: %v, got %v,", cidr.ErrNoAvailableCIDR, err)
+	}
+}
+
 func TestMatchesExistingCIDR(t *testing.T) {
 	type testData struct {
 		name        string
@@ -308,6 +375,61 @@ func TestContainsExistingCIDR(t *testing.T) {
 	}
 }
 
+func TestUsedWithin(t *testing.T) {
+	type testData struct {
+		name      string
+		region    string
+		usedCIDRs []string
+		want      []string
+	}
+	tests := []testData{
+		{
+			name:   "Some within",
+			region: "10.0.0.0/18",
+			usedCIDRs: []string{
+				"10.0.64.0/24",
+				"10.0.17.0/24",
+				"10.0.0.0/24",
+				"10.0.0.0/8",
+				"10.0.16.0/20",
+			},
+			want: []string{"10.0.0.0/24", "10.0.16.0/20", "10.0.17.0/24"},
+		},
+		{
+			name:      "Equal to region",
+			region:    "10.0.0.0/18",
+			usedCIDRs: []string{"10.0.0.0/18", "10.0.0.0/24"},
+			want:      []string{"10.0.0.0/18", "10.0.0.0/24"},
+		},
+		{
+			name:      "None within",
+			region:    "10.0.0.0/18",
+			usedCIDRs: []string{"10.0.64.0/18", "192.168.0.0/24"},
+			want:      []string{},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			_, region, _ := net.ParseCIDR(tc.region)
+			usedCIDRs := make([]*net.IPNet, len(tc.usedCIDRs))
+			for i, usedCIDR := range tc.usedCIDRs {
+				_, usedCIDR, _ := net.ParseCIDR(usedCIDR)
+				usedCIDRs[i] = usedCIDR
+			}
+			got := cidr.UsedWithin(region, usedCIDRs)
+			if len(got) != len(tc.want) {
+				t.Fatalf("want: %v, got: %v", tc.want, got)
+			}
+			for i := range got {
+				if got[i].String() != tc.want[i] {
+					t.Fatalf("want: %v, got: %v", tc.want[i], got[i].String())
+				}
+			}
+		})
+	}
+}
+
 func TestContainsCIDR(t *testing.T) {
 	type testData struct {
 		name        string
@@ -369,19 +491,1004 @@ func TestContainsCIDR(t *testing.T) {
 }
 
 func TestChildCIDRs(t *testing.T) {
-	_, parent, _ := net.ParseCIDR("10.0.0.0/16")
-	_, want1, _ := net.ParseCIDR("10.0.0.0/17")
-	_, want2, _ := net.ParseCIDR("10.0.128.0/17")
+	type testData struct {
+		name      string
+		parent    string
+		want1     string
+		want2     string
+		wantError bool
+	}
+	tests := []testData{
+		{
+			name:   "IPv4",
+			parent: "10.0.0.0/16",
+			want1:  "10.0.0.0/17",
+			want2:  "10.0.128.0/17",
+		},
+		{
+			name:   "IPv4 full space",
+			parent: "0.0.0.0/0",
+			want1:  "0.0.0.0/1",
+			want2:  "128.0.0.0/1",
+		},
+		{
+			name:   "IPv6 full space",
+			parent: "::/0",
+			want1:  "::/1",
+			want2:  "8000::/1",
+		},
+		{
+			name:      "Error single address",
+			parent:    "10.0.0.1/32",
+			wantError: true,
+		},
+	}
 
-	got1, got2, err := cidr.ChildCIDRs(parent)
-	if err != nil {
-		t.Fatalf("%d, unexpected error", err)
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			_, parent, _ := net.ParseCIDR(tc.parent)
+			got1, got2, err := cidr.ChildCIDRs(parent)
+			if tc.wantError {
+				if err == nil {
+					t.Fatalf("want: error, got: %v, %v", got1, got2)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %s,", err.Error())
+			}
+			if got1.String() != tc.want1 {
+				t.Fatalf("want: %v, got: %v", tc.want1, got1.String())
+			}
+			if got2.String() != tc.want2 {
+				t.Fatalf("want: %v, got: %v", tc.want2, got2.String())
+			}
+		})
+	}
+}
+
+func TestFindAvailableCIDRFullSpace(t *testing.T) {
+	type testData struct {
+		name        string
+		baseCIDR    string
+		usedCIDRs   []string
+		desiredMask net.IPMask
+		want        string
+		wantError   error
+	}
+	tests := []testData{
+		{
+			name:        "IPv4 /8",
+			baseCIDR:    "0.0.0.0/0",
+			usedCIDRs:   []string{"0.0.0.0/8", "1.0.0.0/8", "2.0.0.0/7"},
+			desiredMask: net.CIDRMask(8, 32),
+			want:        "4.0.0.0/8",
+		},
+		{
+			name:        "IPv4 whole space",
+			baseCIDR:    "0.0.0.0/0",
+			usedCIDRs:   []string{},
+			desiredMask: net.CIDRMask(0, 32),
+			want:        "0.0.0.0/0",
+		},
+		{
+			name:        "IPv4 upper half",
+			baseCIDR:    "0.0.0.0/0",
+			usedCIDRs:   []string{"0.0.0.0/1"},
+			desiredMask: net.CIDRMask(1, 32),
+			want:        "128.0.0.0/1",
+		},
+		{
+			name:        "IPv6 /8",
+			baseCIDR:    "::/0",
+			usedCIDRs:   []string{"::/8"},
+			desiredMask: net.CIDRMask(8, 128),
+			want:        "100::/8",
+		},
+		{
+			name:        "Fully used",
+			baseCIDR:    "0.0.0.0/0",
+			usedCIDRs:   []string{"0.0.0.0/1", "128.0.0.0/1"},
+			desiredMask: net.CIDRMask(8, 32),
+			wantError:   cidr.ErrNoAvailableCIDR,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			_, baseCIDR, _ := net.ParseCIDR(tc.baseCIDR)
+			usedCIDRs := make([]*net.IPNet, len(tc.usedCIDRs))
+			for i, usedCIDR := range tc.usedCIDRs {
+				_, usedCIDR, _ := net.ParseCIDR(usedCIDR)
+				usedCIDRs[i] = usedCIDR
+			}
+			got, err := cidr.FindAvailableCIDR(baseCIDR, &tc.desiredMask, usedCIDRs)
+			if tc.wantError != nil {
+				if !errors.Is(err, tc.wantError) {
+					t.Fatalf("Invalid error, want: %v, got %v,", tc.wantError, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %s,", err.Error())
+			}
+			if got.String() != tc.want {
+				t.Fatalf("want: %v, got: %v", tc.want, got.String())
+			}
+		})
+	}
+}
+
+func TestFindAvailableCIDRWithMaxDepth(t *testing.T) {
+	type testData struct {
+		name        string
+		baseCIDR    string
+		usedCIDRs   []string
+		desiredMask net.IPMask
+		maxDepth    int
+		want        string
+		wantError   error
+	}
+	tests := []testData{
+		{
+			name:        "Within depth",
+			baseCIDR:    "10.0.0.0/16",
+			usedCIDRs:   []string{"10.0.0.0/24"},
+			desiredMask: net.CIDRMask(24, 32),
+			maxDepth:    8,
+			want:        "10.0.1.0/24",
+		},
+		{
+			name:        "Deep search exceeds depth",
+			baseCIDR:    "10.0.0.0/8",
+			usedCIDRs:   []string{"10.0.0.0/32"},
+			desiredMask: net.CIDRMask(32, 32),
+			maxDepth:    4,
+			wantError:   cidr.ErrSearchDepthExceeded,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			_, baseCIDR, _ := net.ParseCIDR(tc.baseCIDR)
+			usedCIDRs := make([]*net.IPNet, len(tc.usedCIDRs))
+			for i, usedCIDR := range tc.usedCIDRs {
+				_, usedCIDR, _ := net.ParseCIDR(usedCIDR)
+				usedCIDRs[i] = usedCIDR
+			}
+			start := time.Now()
+			got, err := cidr.FindAvailableCIDR(baseCIDR, &tc.desiredMask, usedCIDRs, cidr.WithMaxDepth(tc.maxDepth))
+			if elapsed := time.Since(start); elapsed > time.Second {
+				t.Fatalf("search took too long: %v", elapsed)
+			}
+			if tc.wantError != nil {
+				if !errors.Is(err, tc.wantError) {
+					t.Fatalf("Invalid error, want: %v, got %v,", tc.wantError, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %s,", err.Error())
+			}
+			if got.String() != tc.want {
+				t.Fatalf("want: %v, got: %v", tc.want, got.String())
+			}
+		})
+	}
+}
+
+func TestFindAvailableCIDRMixedRepresentations(t *testing.T) {
+	type testData struct {
+		name        string
+		baseCIDR    string
+		usedCIDRs   []string
+		desiredMask net.IPMask
+		want        string
+		wantError   error
+	}
+	tests := []testData{
+		{
+			name:        "IPv4 root with IPv4-mapped used",
+			baseCIDR:    "10.0.0.0/16",
+			usedCIDRs:   []string{"::ffff:10.0.0.0/120"},
+			desiredMask: net.CIDRMask(24, 32),
+			want:        "10.0.1.0/24",
+		},
+		{
+			name:        "IPv4 root with IPv4-mapped mask",
+			baseCIDR:    "10.0.0.0/16",
+			usedCIDRs:   []string{"10.0.0.0/24"},
+			desiredMask: net.CIDRMask(120, 128),
+			want:        "10.0.1.0/24",
+		},
+		{
+			name:        "IPv4-mapped root",
+			baseCIDR:    "::ffff:10.0.0.0/112",
+			usedCIDRs:   []string{"10.0.0.0/24"},
+			desiredMask: net.CIDRMask(24, 32),
+			want:        "10.0.1.0/24",
+		},
+		{
+			name:        "IPv4 root with IPv6 used",
+			baseCIDR:    "10.0.0.0/16",
+			usedCIDRs:   []string{"2001:db8::/64"},
+			desiredMask: net.CIDRMask(24, 32),
+			wantError:   cidr.ErrInvalidInputRanges,
+		},
+		{
+			name:        "IPv6 root with IPv4 used",
+			baseCIDR:    "2001:db8::/48",
+			usedCIDRs:   []string{"10.0.0.0/24"},
+			desiredMask: net.CIDRMask(64, 128),
+			wantError:   cidr.ErrInvalidInputRanges,
+		},
+		{
+			name:        "IPv6 root with IPv4 mask",
+			baseCIDR:    "2001:db8::/48",
+			usedCIDRs:   []string{},
+			desiredMask: net.CIDRMask(24, 32),
+			wantError:   cidr.ErrInvalidInputRanges,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			_, baseCIDR, _ := net.ParseCIDR(tc.baseCIDR)
+			usedCIDRs := make([]*net.IPNet, len(tc.usedCIDRs))
+			for i, usedCIDR := range tc.usedCIDRs {
+				_, usedCIDR, _ := net.ParseCIDR(usedCIDR)
+				usedCIDRs[i] = usedCIDR
+			}
+			got, err := cidr.FindAvailableCIDR(baseCIDR, &tc.desiredMask, usedCIDRs)
+			if tc.wantError != nil {
+				if !errors.Is(err, tc.wantError) {
+					t.Fatalf("Invalid error, want: %v, got %v,", tc.wantError, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %s,", err.Error())
+			}
+			if got.String() != tc.want {
+				t.Fatalf("want: %v, got: %v", tc.want, got.String())
+			}
+		})
 	}
+}
 
-	if got1.String() != want1.String() {
-		t.Fatalf("want: %v, got: %v", got1.String(), want1.String())
+func TestFindAvailableCIDRWithKeepOut(t *testing.T) {
+	type testData struct {
+		name        string
+		baseCIDR    string
+		usedCIDRs   []string
+		desiredMask net.IPMask
+		keepOut     int
+		want        string
+		wantError   error
 	}
-	if got2.String() != want2.String() {
-		t.Fatalf("want: %v, got: %v", got2.String(), want2.String())
+	tests := []testData{
+		{
+			name:        "Skips keep-out block",
+			baseCIDR:    "10.0.0.0/16",
+			usedCIDRs:   []string{"10.0.0.0/24"},
+			desiredMask: net.CIDRMask(24, 32),
+			keepOut:     22,
+			want:        "10.0.5.0/24",
+		},
+		{
+			name:        "Used at top of keep-out block",
+			baseCIDR:    "10.0.0.0/16",
+			usedCIDRs:   []string{"10.0.3.0/24"},
+			desiredMask: net.CIDRMask(24, 32),
+			keepOut:     22,
+			want:        "10.0.5.0/24",
+		},
+		{
+			name:        "Used at bottom of root",
+			baseCIDR:    "10.0.0.0/22",
+			usedCIDRs:   []string{"10.0.3.0/25"},
+			desiredMask: net.CIDRMask(25, 32),
+			keepOut:     24,
+			want:        "10.0.0.0/25",
+		},
+		{
+			name:        "Used larger than keep-out",
+			baseCIDR:    "10.0.0.0/16",
+			usedCIDRs:   []string{"10.0.0.0/20"},
+			desiredMask: net.CIDRMask(24, 32),
+			keepOut:     22,
+			want:        "10.0.17.0/24",
+		},
+		{
+			name:        "Keep-out outside root",
+			baseCIDR:    "10.0.0.0/16",
+			usedCIDRs:   []string{"10.1.0.0/24"},
+			desiredMask: net.CIDRMask(24, 32),
+			keepOut:     8,
+			want:        "10.0.0.0/24",
+		},
+		{
+			name:        "Keep-out covers root",
+			baseCIDR:    "10.0.0.0/16",
+			usedCIDRs:   []string{"10.0.0.0/24"},
+			desiredMask: net.CIDRMask(24, 32),
+			keepOut:     8,
+			wantError:   cidr.ErrNoAvailableCIDR,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			_, baseCIDR, _ := net.ParseCIDR(tc.baseCIDR)
+			usedCIDRs := make([]*net.IPNet, len(tc.usedCIDRs))
+			for i, usedCIDR := range tc.usedCIDRs {
+				_, usedCIDR, _ := net.ParseCIDR(usedCIDR)
+				usedCIDRs[i] = usedCIDR
+			}
+			got, err := cidr.FindAvailableCIDR(baseCIDR, &tc.desiredMask, usedCIDRs, cidr.WithKeepOut(tc.keepOut))
+			if tc.wantError != nil {
+				if !errors.Is(err, tc.wantError) {
+					t.Fatalf("Invalid error, want: %v, got %v,", tc.wantError, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %s,", err.Error())
+			}
+			if got.String() != tc.want {
+				t.Fatalf("want: %v, got: %v", tc.want, got.String())
+			}
+		})
+	}
+}
+
+func TestFindAvailableCIDRWithMaxSubnetsUnder(t *testing.T) {
+	type testData struct {
+		name           string
+		baseCIDR       string
+		usedCIDRs      []string
+		desiredMask    net.IPMask
+		supernetPrefix int
+		maxCount       int
+		want           string
+		wantError      error
+	}
+	tests := []testData{
+		{
+			name:           "Room under the cap",
+			baseCIDR:       "10.0.0.0/16",
+			usedCIDRs:      []string{"10.0.0.0/24", "10.0.1.0/24"},
+			desiredMask:    net.CIDRMask(24, 32),
+			supernetPrefix: 20,
+			maxCount:       4,
+			want:           "10.0.2.0/24",
+		},
+		{
+			name:           "Cap forces the next supernet",
+			baseCIDR:       "10.0.0.0/16",
+			usedCIDRs:      []string{"10.0.0.0/24", "10.0.1.0/24", "10.0.2.0/24", "10.0.3.0/24"},
+			desiredMask:    net.CIDRMask(24, 32),
+			supernetPrefix: 20,
+			maxCount:       4,
+			want:           "10.0.16.0/24",
+		},
+		{
+			name:           "Counts used CIDRs of any size",
+			baseCIDR:       "10.0.0.0/16",
+			usedCIDRs:      []string{"10.0.0.0/26", "10.0.8.0/22", "10.0.16.0/24"},
+			desiredMask:    net.CIDRMask(24, 32),
+			supernetPrefix: 20,
+			maxCount:       2,
+			want:           "10.0.17.0/24",
+		},
+		{
+			name:           "Larger than the supernet",
+			baseCIDR:       "10.0.0.0/16",
+			usedCIDRs:      []string{"10.0.0.0/24"},
+			desiredMask:    net.CIDRMask(19, 32),
+			supernetPrefix: 20,
+			maxCount:       0,
+			want:           "10.0.32.0/19",
+		},
+		{
+			name:           "Every supernet full",
+			baseCIDR:       "10.0.0.0/23",
+			usedCIDRs:      []string{"10.0.0.0/25", "10.0.1.0/25"},
+			desiredMask:    net.CIDRMask(25, 32),
+			supernetPrefix: 24,
+			maxCount:       1,
+			wantError:      cidr.ErrNoAvailableCIDR,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			_, baseCIDR, _ := net.ParseCIDR(tc.baseCIDR)
+			usedCIDRs := make([]*net.IPNet, len(tc.usedCIDRs))
+			for i, usedCIDR := range tc.usedCIDRs {
+				_, usedCIDR, _ := net.ParseCIDR(usedCIDR)
+				usedCIDRs[i] = usedCIDR
+			}
+			got, err := cidr.FindAvailableCIDR(baseCIDR, &tc.desiredMask, usedCIDRs, cidr.WithMaxSubnetsUnder(tc.supernetPrefix, tc.maxCount))
+			if tc.wantError != nil {
+				if !errors.Is(err, tc.wantError) {
+					t.Fatalf("Invalid error, want: %v, got %v,", tc.wantError, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %s,", err.Error())
+			}
+			if got.String() != tc.want {
+				t.Fatalf("want: %v, got: %v", tc.want, got.String())
+			}
+		})
+	}
+}
+
+func TestFindAvailableCIDRWithAffinity(t *testing.T) {
+	type testData struct {
+		name        string
+		baseCIDR    string
+		usedCIDRs   []string
+		desiredMask net.IPMask
+		hint        string
+		want        string
+	}
+	tests := []testData{
+		{
+			name:        "Free hint",
+			baseCIDR:    "10.0.0.0/16",
+			usedCIDRs:   []string{},
+			desiredMask: net.CIDRMask(24, 32),
+			hint:        "10.0.200.0/24",
+			want:        "10.0.200.0/24",
+		},
+		{
+			name:        "Used hint takes sibling",
+			baseCIDR:    "10.0.0.0/16",
+			usedCIDRs:   []string{"10.0.200.0/24"},
+			desiredMask: net.CIDRMask(24, 32),
+			hint:        "10.0.200.0/24",
+			want:        "10.0.201.0/24",
+		},
+		{
+			name:        "Clusters near hint",
+			baseCIDR:    "10.0.0.0/16",
+			usedCIDRs:   []string{"10.0.200.0/23", "10.0.203.0/24"},
+			desiredMask: net.CIDRMask(24, 32),
+			hint:        "10.0.201.0/24",
+			want:        "10.0.202.0/24",
+		},
+		{
+			name:        "Larger hint",
+			baseCIDR:    "10.0.0.0/16",
+			usedCIDRs:   []string{"10.0.128.0/24"},
+			desiredMask: net.CIDRMask(24, 32),
+			hint:        "10.0.128.0/17",
+			want:        "10.0.129.0/24",
+		},
+		{
+			name:        "Hint outside root",
+			baseCIDR:    "10.0.0.0/16",
+			usedCIDRs:   []string{},
+			desiredMask: net.CIDRMask(24, 32),
+			hint:        "10.1.200.0/24",
+			want:        "10.0.0.0/24",
+		},
+		{
+			name:        "Hint in another family",
+			baseCIDR:    "10.0.0.0/16",
+			usedCIDRs:   []string{},
+			desiredMask: net.CIDRMask(24, 32),
+			hint:        "2001:db8::/64",
+			want:        "10.0.0.0/24",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			_, baseCIDR, _ := net.ParseCIDR(tc.baseCIDR)
+			_, hint, _ := net.ParseCIDR(tc.hint)
+			usedCIDRs := make([]*net.IPNet, len(tc.usedCIDRs))
+			for i, usedCIDR := range tc.usedCIDRs {
+				_, usedCIDR, _ := net.ParseCIDR(usedCIDR)
+				usedCIDRs[i] = usedCIDR
+			}
+			got, err := cidr.FindAvailableCIDR(baseCIDR, &tc.desiredMask, usedCIDRs, cidr.WithAffinity(hint))
+			if err != nil {
+				t.Fatalf("Unexpected error: %s,", err.Error())
+			}
+			if got.String() != tc.want {
+				t.Fatalf("want: %v, got: %v", tc.want, got.String())
+			}
+		})
+	}
+}
+
+func TestFindAvailableCIDRWithPreferRegion(t *testing.T) {
+	type testData struct {
+		name        string
+		baseCIDR    string
+		usedCIDRs   []string
+		desiredMask net.IPMask
+		region      string
+		want        string
+	}
+	tests := []testData{
+		{
+			name:        "Space in region",
+			baseCIDR:    "10.0.0.0/16",
+			usedCIDRs:   []string{"10.0.64.0/24"},
+			desiredMask: net.CIDRMask(24, 32),
+			region:      "10.0.64.0/22",
+			want:        "10.0.65.0/24",
+		},
+		{
+			name:        "Region full falls back",
+			baseCIDR:    "10.0.0.0/16",
+			usedCIDRs:   []string{"10.0.0.0/24", "10.0.64.0/22"},
+			desiredMask: net.CIDRMask(24, 32),
+			region:      "10.0.64.0/22",
+			want:        "10.0.1.0/24",
+		},
+		{
+			name:        "Region too small falls back",
+			baseCIDR:    "10.0.0.0/16",
+			usedCIDRs:   []string{},
+			desiredMask: net.CIDRMask(24, 32),
+			region:      "10.0.64.0/25",
+			want:        "10.0.0.0/24",
+		},
+		{
+			name:        "Region outside root",
+			baseCIDR:    "10.0.0.0/16",
+			usedCIDRs:   []string{},
+			desiredMask: net.CIDRMask(24, 32),
+			region:      "10.1.64.0/22",
+			want:        "10.0.0.0/24",
+		},
+		{
+			name:        "Region host bits cleared",
+			baseCIDR:    "10.0.0.0/16",
+			usedCIDRs:   []string{},
+			desiredMask: net.CIDRMask(24, 32),
+			region:      "10.0.66.7/22",
+			want:        "10.0.64.0/24",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			_, baseCIDR, _ := net.ParseCIDR(tc.baseCIDR)
+			ip, region, _ := net.ParseCIDR(tc.region)
+			region.IP = ip
+			usedCIDRs := make([]*net.IPNet, len(tc.usedCIDRs))
+			for i, usedCIDR := range tc.usedCIDRs {
+				_, usedCIDR, _ := net.ParseCIDR(usedCIDR)
+				usedCIDRs[i] = usedCIDR
+			}
+			got, err := cidr.FindAvailableCIDR(baseCIDR, &tc.desiredMask, usedCIDRs, cidr.WithPreferRegion(region))
+			if err != nil {
+				t.Fatalf("Unexpected error: %s,", err.Error())
+			}
+			if got.String() != tc.want {
+				t.Fatalf("want: %v, got: %v", tc.want, got.String())
+			}
+		})
+	}
+}
+
+func TestFindAvailableCIDRNonCanonicalRoot(t *testing.T) {
+	type testData struct {
+		name        string
+		normalize   bool
+		baseCIDR    net.IPNet
+		usedCIDRs   []net.IPNet
+		desiredMask net.IPMask
+		want        string
+		wantError   error
+	}
+	tests := []testData{
+		{
+			name:        "Error host bits set",
+			baseCIDR:    net.IPNet{IP: net.IPv4(10, 0, 5, 0).To4(), Mask: net.CIDRMask(16, 32)},
+			desiredMask: net.CIDRMask(24, 32),
+			wantError:   cidr.ErrInvalidInputRanges,
+		},
+		{
+			name:        "Normalized",
+			normalize:   true,
+			baseCIDR:    net.IPNet{IP: net.IPv4(10, 0, 5, 0).To4(), Mask: net.CIDRMask(16, 32)},
+			usedCIDRs:   []net.IPNet{{IP: net.IPv4(10, 0, 0, 7).To4(), Mask: net.CIDRMask(24, 32)}},
+			desiredMask: net.CIDRMask(24, 32),
+			want:        "10.0.1.0/24",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			usedCIDRs := make([]*net.IPNet, len(tc.usedCIDRs))
+			for i := range tc.usedCIDRs {
+				usedCIDRs[i] = &tc.usedCIDRs[i]
+			}
+			opts := []cidr.FindOption{}
+			if tc.normalize {
+				opts = append(opts, cidr.WithNormalizeInput())
+			}
+			got, err := cidr.FindAvailableCIDR(&tc.baseCIDR, &tc.desiredMask, usedCIDRs, opts...)
+			if tc.wantError != nil {
+				if !errors.Is(err, tc.wantError) {
+					t.Fatalf("Invalid error, want: %v, got %v,", tc.wantError, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %s,", err.Error())
+			}
+			if got.String() != tc.want {
+				t.Fatalf("want: %v, got: %v", tc.want, got.String())
+			}
+		})
+	}
+}
+
+func TestFindAvailableCIDRWithConflictReport(t *testing.T) {
+	type testData struct {
+		name         string
+		baseCIDR     string
+		usedCIDRs    []string
+		desiredMask  net.IPMask
+		wantBlockers []string
+	}
+	tests := []testData{
+		{
+			name:     "Full",
+			baseCIDR: "10.0.0.0/16",
+			usedCIDRs: []string{
+				"10.0.128.0/18",
+				"10.0.0.0/18",
+				"10.0.64.0/18",
+				"10.0.192.0/18",
+				"192.168.0.0/24",
+			},
+			desiredMask: net.CIDRMask(24, 32),
+			wantBlockers: []string{
+				"10.0.0.0/18",
+				"10.0.64.0/18",
+				"10.0.128.0/18",
+				"10.0.192.0/18",
+			},
+		},
+		{
+			name:     "Fragmented",
+			baseCIDR: "10.0.0.0/23",
+			usedCIDRs: []string{
+				"10.0.0.0/25",
+				"10.0.1.128/25",
+			},
+			desiredMask: net.CIDRMask(24, 32),
+			wantBlockers: []string{
+				"10.0.0.0/25",
+				"10.0.1.128/25",
+			},
+		},
+		{
+			name:         "Root used",
+			baseCIDR:     "10.0.0.0/16",
+			usedCIDRs:    []string{"10.0.0.0/16"},
+			desiredMask:  net.CIDRMask(24, 32),
+			wantBlockers: []string{"10.0.0.0/16"},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			_, baseCIDR, _ := net.ParseCIDR(tc.baseCIDR)
+			usedCIDRs := make([]*net.IPNet, len(tc.usedCIDRs))
+			for i, usedCIDR := range tc.usedCIDRs {
+				_, usedCIDR, _ := net.ParseCIDR(usedCIDR)
+				usedCIDRs[i] = usedCIDR
+			}
+			_, err := cidr.FindAvailableCIDR(baseCIDR, &tc.desiredMask, usedCIDRs, cidr.WithConflictReport())
+			if !errors.Is(err, cidr.ErrNoAvailableCIDR) {
+				t.Fatalf("Invalid error, want: %v, got %v,", cidr.ErrNoAvailableCIDR, err)
+			}
+			var conflictErr *cidr.ConflictError
+			if !errors.As(err, &conflictErr) {
+				t.Fatalf("want: *cidr.ConflictError, got: %T", err)
+			}
+			if len(conflictErr.Blockers) != len(tc.wantBlockers) {
+				t.Fatalf("want: %v, got: %v", tc.wantBlockers, conflictErr.Blockers)
+			}
+			for i := range conflictErr.Blockers {
+				if conflictErr.Blockers[i].String() != tc.wantBlockers[i] {
+					t.Fatalf("want: %v, got: %v", tc.wantBlockers[i], conflictErr.Blockers[i].String())
+				}
+			}
+		})
+	}
+}
+
+func TestFindAvailableCIDRRootUsedErrors(t *testing.T) {
+	type testData struct {
+		name       string
+		baseCIDR   string
+		usedCIDRs  []string
+		wantErrors []error
+		notErrors  []error
+	}
+	tests := []testData{
+		{
+			name:       "Root equals used",
+			baseCIDR:   "10.0.0.0/16",
+			usedCIDRs:  []string{"10.0.0.0/16"},
+			wantErrors: []error{cidr.ErrRootFullyUsed, cidr.ErrNoAvailableCIDR},
+			notErrors:  []error{cidr.ErrRootInsideUsed, cidr.ErrInvalidInputRanges},
+		},
+		{
+			name:       "Root inside used",
+			baseCIDR:   "10.1.0.0/16",
+			usedCIDRs:  []string{"10.0.0.0/14"},
+			wantErrors: []error{cidr.ErrRootInsideUsed, cidr.ErrInvalidInputRanges},
+			notErrors:  []error{cidr.ErrRootFullyUsed, cidr.ErrNoAvailableCIDR},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			_, baseCIDR, _ := net.ParseCIDR(tc.baseCIDR)
+			usedCIDRs := make([]*net.IPNet, len(tc.usedCIDRs))
+			for i, usedCIDR := range tc.usedCIDRs {
+				_, usedCIDR, _ := net.ParseCIDR(usedCIDR)
+				usedCIDRs[i] = usedCIDR
+			}
+			mask := net.CIDRMask(24, 32)
+			_, err := cidr.FindAvailableCIDR(baseCIDR, &mask, usedCIDRs)
+			for _, want := range tc.wantErrors {
+				if !errors.Is(err, want) {
+					t.Fatalf("Invalid error, want: %v, got %v,", want, err)
+				}
+			}
+			for _, notWant := range tc.notErrors {
+				if errors.Is(err, notWant) {
+					t.Fatalf("Invalid error, didn't want: %v, got %v,", notWant, err)
+				}
+			}
+		})
+	}
+}
+
+func TestFindAvailableCIDRNoAvailableCIDRWrapped(t *testing.T) {
+	type testData struct {
+		name      string
+		baseCIDR  string
+		mask      int
+		usedCIDRs []string
+	}
+	tests := []testData{
+		{
+			name:      "Mask larger than root",
+			baseCIDR:  "10.0.0.0/24",
+			mask:      16,
+			usedCIDRs: []string{},
+		},
+		{
+			name:      "Collides with used",
+			baseCIDR:  "10.0.0.0/23",
+			mask:      24,
+			usedCIDRs: []string{"10.0.0.0/24", "10.0.1.0/24"},
+		},
+		{
+			name:      "Contains used",
+			baseCIDR:  "10.0.0.0/23",
+			mask:      24,
+			usedCIDRs: []string{"10.0.0.0/25", "10.0.1.128/25"},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			_, baseCIDR, _ := net.ParseCIDR(tc.baseCIDR)
+			usedCIDRs := make([]*net.IPNet, len(tc.usedCIDRs))
+			for i, usedCIDR := range tc.usedCIDRs {
+				_, usedCIDR, _ := net.ParseCIDR(usedCIDR)
+				usedCIDRs[i] = usedCIDR
+			}
+			mask := net.CIDRMask(tc.mask, 32)
+			_, err := cidr.FindAvailableCIDR(baseCIDR, &mask, usedCIDRs)
+			if err == cidr.ErrNoAvailableCIDR || !errors.Is(err, cidr.ErrNoAvailableCIDR) {
+				t.Fatalf("Invalid error, want: %v wrapped, got %v,", cidr.ErrNoAvailableCIDR, err)
+			}
+			// the deprecated name is the same error
+			if !errors.Is(err, cidr.ErrNoAvailableCidr) {
+				t.Fatalf("Invalid error, want: %v, got %v,", cidr.ErrNoAvailableCidr, err)
+			}
+		})
+	}
+}
+
+func TestFindAvailableCIDRsCount(t *testing.T) {
+	type testData struct {
+		name      string
+		baseCIDR  string
+		count     int
+		usedCIDRs []string
+		want      []string
+		wantError error
+	}
+	tests := []testData{
+		{
+			name:      "Three in an empty root",
+			baseCIDR:  "10.0.0.0/16",
+			count:     3,
+			usedCIDRs: []string{},
+			want:      []string{"10.0.0.0/24", "10.0.1.0/24", "10.0.2.0/24"},
+		},
+		{
+			name:      "Interleaved with used",
+			baseCIDR:  "10.0.0.0/16",
+			count:     3,
+			usedCIDRs: []string{"10.0.1.0/24", "10.0.3.0/24"},
+			want:      []string{"10.0.0.0/24", "10.0.2.0/24", "10.0.4.0/24"},
+		},
+		{
+			name:      "Zero",
+			baseCIDR:  "10.0.0.0/16",
+			count:     0,
+			usedCIDRs: []string{},
+			want:      []string{},
+		},
+		{
+			name:      "Error more than capacity",
+			baseCIDR:  "10.0.0.0/22",
+			count:     5,
+			usedCIDRs: []string{"10.0.1.0/24"},
+			want:      []string{"10.0.0.0/24", "10.0.2.0/24", "10.0.3.0/24"},
+			wantError: cidr.ErrNoAvailableCIDR,
+		},
+		{
+			name:      "Error negative count",
+			baseCIDR:  "10.0.0.0/16",
+			count:     -1,
+			usedCIDRs: []string{},
+			wantError: cidr.ErrInvalidInputRanges,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			_, baseCIDR, _ := net.ParseCIDR(tc.baseCIDR)
+			usedCIDRs := make([]*net.IPNet, len(tc.usedCIDRs))
+			for i, usedCIDR := range tc.usedCIDRs {
+				_, usedCIDR, _ := net.ParseCIDR(usedCIDR)
+				usedCIDRs[i] = usedCIDR
+			}
+			mask := net.CIDRMask(24, 32)
+			got, err := cidr.FindAvailableCIDRs(baseCIDR, &mask, tc.count, usedCIDRs)
+			if tc.wantError != nil {
+				if !errors.Is(err, tc.wantError) {
+					t.Fatalf("Invalid error, want: %v, got %v,", tc.wantError, err)
+				}
+			} else if err != nil {
+				t.Fatalf("Unexpected error: %s,", err.Error())
+			}
+			if len(got) != len(tc.want) {
+				t.Fatalf("want: %v, got: %v", tc.want, got)
+			}
+			for i, n := range got {
+				if n.String() != tc.want[i] {
+					t.Fatalf("want: %v, got: %v", tc.want, got)
+				}
+			}
+		})
+	}
+}
+
+// recursiveFind is the recursive walk FindAvailableCIDR used before it kept its own stack, without
+// any of the FindOptions, as a reference for the iterative walk.
+func recursiveFind(current *net.IPNet, desiredMask *net.IPMask, usedCIDRs []*net.IPNet) *net.IPNet {
+	if cidr.MatchesExistingCIDR(current, usedCIDRs) {
+		return nil
+	}
+	if cidr.EqualMask(desiredMask, &current.Mask) {
+		if cidr.ContainsExistingCIDR(current, usedCIDRs) {
+			return nil
+		}
+		return current
+	}
+	child1, child2, err := cidr.ChildCIDRs(current)
+	if err != nil {
+		return nil
+	}
+	if result := recursiveFind(child1, desiredMask, usedCIDRs); result != nil {
+		return result
+	}
+	return recursiveFind(child2, desiredMask, usedCIDRs)
+}
+
+func TestFindAvailableCIDRMatchesRecursiveWalk(t *testing.T) {
+	type testData struct {
+		baseCIDR  string
+		usedCIDRs []string
+	}
+	tests := []testData{
+		{
+			baseCIDR:  "10.0.0.0/16",
+			usedCIDRs: []string{"10.0.0.0/18", "10.0.64.0/20", "10.0.80.0/24"},
+		},
+		{
+			baseCIDR:  "10.0.0.0/20",
+			usedCIDRs: []string{"10.0.0.0/24", "10.0.1.5/32", "10.0.2.0/23", "10.0.8.0/22", "10.0.13.128/25"},
+		},
+		{
+			baseCIDR:  "192.168.0.0/24",
+			usedCIDRs: []string{"192.168.0.64/26", "192.168.0.200/29"},
+		},
+		{
+			baseCIDR:  "2001:db8::/48",
+			usedCIDRs: []string{"2001:db8::/56", "2001:db8:0:100::/64", "2001:db8:0:200::/120"},
+		},
+	}
+
+	for _, tc := range tests {
+		_, baseCIDR, _ := net.ParseCIDR(tc.baseCIDR)
+		usedCIDRs := make([]*net.IPNet, len(tc.usedCIDRs))
+		for i, usedCIDR := range tc.usedCIDRs {
+			_, usedCIDR, _ := net.ParseCIDR(usedCIDR)
+			usedCIDRs[i] = usedCIDR
+		}
+		rootOnes, bits := baseCIDR.Mask.Size()
+		for prefix := rootOnes; prefix <= bits; prefix++ {
+			t.Run(fmt.Sprintf("%s /%d", tc.baseCIDR, prefix), func(t *testing.T) {
+				mask := net.CIDRMask(prefix, bits)
+				want := recursiveFind(baseCIDR, &mask, usedCIDRs)
+				got, err := cidr.FindAvailableCIDR(baseCIDR, &mask, usedCIDRs)
+				if want == nil {
+					if !errors.Is(err, cidr.ErrNoAvailableCIDR) {
+						t.Fatalf("Invalid error, want: %v, got %v,", cidr.ErrNoAvailableCIDR, err)
+					}
+					return
+				}
+				if err != nil {
+					t.Fatalf("Unexpected error: %s,", err.Error())
+				}
+				if got.String() != want.String() {
+					t.Fatalf("want: %v, got: %v", want.String(), got.String())
+				}
+			})
+		}
+	}
+}
+
+// benchmarkDeepUsed returns an IPv6 /32 root with the first /120 of each of its first 64 /64s used, so
+// finding a /120 descends 88 levels
+func benchmarkDeepUsed() (*net.IPNet, []*net.IPNet) {
+	_, rootCIDR, _ := net.ParseCIDR("2001:db8::/32")
+	usedCIDRs := []*net.IPNet{}
+	for i := 0; i < 64; i++ {
+		_, usedCIDR, _ := net.ParseCIDR(fmt.Sprintf("2001:db8:0:%x::/120", i))
+		usedCIDRs = append(usedCIDRs, usedCIDR)
+	}
+	return rootCIDR, usedCIDRs
+}
+
+func BenchmarkFindAvailableCIDRDeep(b *testing.B) {
+	rootCIDR, usedCIDRs := benchmarkDeepUsed()
+	mask := net.CIDRMask(120, 128)
+	for i := 0; i < b.N; i++ {
+		if _, err := cidr.FindAvailableCIDR(rootCIDR, &mask, usedCIDRs); err != nil {
+			b.Fatalf("Unexpected error: %s,", err.Error())
+		}
+	}
+}
+
+func BenchmarkRecursiveFindDeep(b *testing.B) {
+	rootCIDR, usedCIDRs := benchmarkDeepUsed()
+	mask := net.CIDRMask(120, 128)
+	for i := 0; i < b.N; i++ {
+		if recursiveFind(rootCIDR, &mask, usedCIDRs) == nil {
+			b.Fatalf("want: %v, got: %v", "a /120", nil)
+		}
 	}
 }
@@ -0,0 +1,109 @@
+package cidr
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"net"
+	"sort"
+)
+
+// FreeSpace returns the minimal list of CIDRs, in ascending order, which exactly covers the addresses
+// of rootCIDR not within any of the usedCIDRs. Used CIDRs outside the rootCIDR, or from a different
+// address family, are ignored.
+func FreeSpace(rootCIDR *net.IPNet, usedCIDRs []*net.IPNet) ([]*net.IPNet, error) {
+	_, bits := rootCIDR.Mask.Size()
+	rootFirst, rootLast := CIDRToIntRange(rootCIDR)
+
+	// clip each used CIDR to the root, then walk them in order collecting the gaps between them
+	type interval struct{ first, last *big.Int }
+	used := make([]interval, 0, len(usedCIDRs))
+	for _, usedCIDR := range usedCIDRs {
+		if _, usedBits := usedCIDR.Mask.Size(); usedBits != bits || !OverlapsCIDR(rootCIDR, usedCIDR) {
+			continue
+		}
+		first, last := CIDRToIntRange(usedCIDR)
+		if first.Cmp(rootFirst) < 0 {
+			first = rootFirst
+		}
+		if last.Cmp(rootLast) > 0 {
+			last = rootLast
+		}
+		used = append(used, interval{first: first, last: last})
+	}
+	sort.Slice(used, func(i, j int) bool {
+		return used[i].first.Cmp(used[j].first) < 0
+	})
+
+	one := big.NewInt(1)
+	free := []*net.IPNet{}
+	next := new(big.Int).Set(rootFirst)
+	addGap := func(last *big.Int) error {
+		if next.Cmp(last) > 0 {
+			return nil
+		}
+		firstIP, err := IntToIP(next, bits)
+		if err != nil {
+			return err
+		}
+		lastIP, err := IntToIP(last, bits)
+		if err != nil {
+			return err
+		}
+		cidrs, err := RangeToCIDRs(firstIP, lastIP)
+		if err != nil {
+			return err
+		}
+		free = append(free, cidrs...)
+		return nil
+	}
+	for _, u := range used {
+		if err := addGap(new(big.Int).Sub(u.first, one)); err != nil {
+			return nil, err
+		}
+		if afterUsed := new(big.Int).Add(u.last, one); afterUsed.Cmp(next) > 0 {
+			next = afterUsed
+		}
+	}
+	if err := addGap(rootLast); err != nil {
+		return nil, err
+	}
+
+	return free, nil
+}
+
+// LargestAvailableBlock returns the largest aligned CIDR within rootCIDR which doesn't collide with any
+// of the usedCIDRs, the lowest one if there are several of the same size. An ErrNoAvailableCIDR error
+// is returned if the rootCIDR is fully used.
+func LargestAvailableBlock(rootCIDR *net.IPNet, usedCIDRs []*net.IPNet) (*net.IPNet, error) {
+	rootOnes, bits := rootCIDR.Mask.Size()
+	for ones := rootOnes; ones <= bits; ones++ {
+		mask := net.CIDRMask(ones, bits)
+		block, err := FindAvailableCIDR(rootCIDR, &mask, usedCIDRs)
+		if err == nil {
+			return block, nil
+		}
+		if !errors.Is(err, ErrNoAvailableCIDR) {
+			return nil, err
+		}
+	}
+	return nil, fmt.Errorf("%w: root CIDR is fully used", ErrNoAvailableCIDR)
+}
+
+// FillGap finds a CIDR of the desiredMask size within the gap, such as one of the FreeSpace blocks,
+// placed to keep what's left of the gap in as few blocks as possible. A defragmenting allocator can
+// use it to fill the most fragmented gaps first and consolidate them. In 10.0.0.0/24 with
+// 10.0.0.192/27 used, a /26 goes at 10.0.0.128/26 leaving 10.0.0.0/25 free, where the first available
+// /26, 10.0.0.0/26, would leave the free space in three blocks. Ties go to the lowest placement. Like
+// WithScorer, every placement in the gap is evaluated, so the gap shouldn't be much larger than the
+// desiredMask.
+func FillGap(gap *net.IPNet, desiredMask *net.IPMask, usedCIDRs []*net.IPNet) (*net.IPNet, error) {
+	used := make([]*net.IPNet, len(usedCIDRs), len(usedCIDRs)+1)
+	copy(used, usedCIDRs)
+	remainingBlocks := func(candidate *net.IPNet) int {
+		// the candidate is available, so the gap isn't fully used and FreeSpace can't fail
+		free, _ := FreeSpace(gap, append(used, candidate))
+		return len(free)
+	}
+	return FindAvailableCIDR(gap, desiredMask, usedCIDRs, WithScorer(remainingBlocks))
+}
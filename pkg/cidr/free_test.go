@@ -0,0 +1,226 @@
+package cidr_test
+
+import (
+	"errors"
+	"net"
+	"strings"
+	"testing"
+
+	"github.com/massdriver-cloud/cola/pkg/cidr"
+)
+
+func TestFreeSpace(t *testing.T) {
+	type testData struct {
+		name      string
+		baseCIDR  string
+		usedCIDRs []string
+		want      []string
+	}
+	tests := []testData{
+		{
+			name:      "Empty",
+			baseCIDR:  "10.0.0.0/16",
+			usedCIDRs: []string{},
+			want:      []string{"10.0.0.0/16"},
+		},
+		{
+			name:     "Comment example",
+			baseCIDR: "10.0.0.0/16",
+			usedCIDRs: []string{
+				"10.0.80.0/24",
+				"10.0.0.0/18",
+				"10.0.64.0/20",
+			},
+			want: []string{
+				"10.0.81.0/24",
+				"10.0.82.0/23",
+				"10.0.84.0/22",
+				"10.0.88.0/21",
+				"10.0.96.0/19",
+				"10.0.128.0/17",
+			},
+		},
+		{
+			name:     "Nested and outside used",
+			baseCIDR: "10.0.0.0/22",
+			usedCIDRs: []string{
+				"10.0.0.0/23",
+				"10.0.1.0/24",
+				"192.168.0.0/16",
+				"2001:db8::/64",
+			},
+			want: []string{"10.0.2.0/23"},
+		},
+		{
+			name:      "Full",
+			baseCIDR:  "10.0.0.0/16",
+			usedCIDRs: []string{"10.0.0.0/8"},
+			want:      []string{},
+		},
+		{
+			name:      "Top of the address space",
+			baseCIDR:  "255.255.255.0/24",
+			usedCIDRs: []string{"255.255.255.0/25"},
+			want:      []string{"255.255.255.128/25"},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			_, baseCIDR, _ := net.ParseCIDR(tc.baseCIDR)
+			usedCIDRs := make([]*net.IPNet, len(tc.usedCIDRs))
+			for i, usedCIDR := range tc.usedCIDRs {
+				_, usedCIDR, _ := net.ParseCIDR(usedCIDR)
+				usedCIDRs[i] = usedCIDR
+			}
+			got, err := cidr.FreeSpace(baseCIDR, usedCIDRs)
+			if err != nil {
+				t.Fatalf("Unexpected error: %s,", err.Error())
+			}
+			if len(got) != len(tc.want) {
+				t.Fatalf("want: %v, got: %v", tc.want, got)
+			}
+			for i := range got {
+				if got[i].String() != tc.want[i] {
+					t.Fatalf("want: %v, got: %v", tc.want[i], got[i].String())
+				}
+			}
+		})
+	}
+}
+
+func TestLargestAvailableBlock(t *testing.T) {
+	type testData struct {
+		name      string
+		baseCIDR  string
+		usedCIDRs []string
+		want      string
+		wantError error
+	}
+	tests := []testData{
+		{
+			name:      "Empty",
+			baseCIDR:  "10.0.0.0/16",
+			usedCIDRs: []string{},
+			want:      "10.0.0.0/16",
+		},
+		{
+			name:      "Lowest of the largest",
+			baseCIDR:  "10.0.0.0/16",
+			usedCIDRs: []string{"10.0.0.0/18", "10.0.128.0/24"},
+			want:      "10.0.64.0/18",
+		},
+		{
+			name:      "Full",
+			baseCIDR:  "10.0.0.0/16",
+			usedCIDRs: []string{"10.0.0.0/17", "10.0.128.0/17"},
+			wantError: cidr.ErrNoAvailableCIDR,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			_, baseCIDR, _ := net.ParseCIDR(tc.baseCIDR)
+			usedCIDRs := make([]*net.IPNet, len(tc.usedCIDRs))
+			for i, usedCIDR := range tc.usedCIDRs {
+				_, usedCIDR, _ := net.ParseCIDR(usedCIDR)
+				usedCIDRs[i] = usedCIDR
+			}
+			got, err := cidr.LargestAvailableBlock(baseCIDR, usedCIDRs)
+			if tc.wantError != nil {
+				if !errors.Is(err, tc.wantError) {
+					t.Fatalf("Invalid error, want: %v, got %v,", tc.wantError, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %s,", err.Error())
+			}
+			if got.String() != tc.want {
+				t.Fatalf("want: %v, got: %v", tc.want, got.String())
+			}
+		})
+	}
+}
+
+func TestFillGap(t *testing.T) {
+	type testData struct {
+		name      string
+		gap       string
+		mask      int
+		usedCIDRs []string
+		want      string
+		remaining []string
+		wantError error
+	}
+	tests := []testData{
+		{
+			name:      "Empty gap",
+			gap:       "10.0.0.0/24",
+			mask:      26,
+			usedCIDRs: []string{},
+			want:      "10.0.0.0/26",
+			remaining: []string{"10.0.0.64/26", "10.0.0.128/25"},
+		},
+		{
+			name:      "Keeps the remainder aligned",
+			gap:       "10.0.0.0/24",
+			mask:      26,
+			usedCIDRs: []string{"10.0.0.192/27"},
+			want:      "10.0.0.128/26",
+			remaining: []string{"10.0.0.0/25", "10.0.0.224/27"},
+		},
+		{
+			name:      "Ignores used outside the gap",
+			gap:       "10.0.1.0/24",
+			mask:      25,
+			usedCIDRs: []string{"10.0.0.0/24", "10.0.1.0/26"},
+			want:      "10.0.1.128/25",
+			remaining: []string{"10.0.1.64/26"},
+		},
+		{
+			name:      "Error gap too small",
+			gap:       "10.0.0.0/24",
+			mask:      23,
+			usedCIDRs: []string{},
+			wantError: cidr.ErrNoAvailableCIDR,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			_, gap, _ := net.ParseCIDR(tc.gap)
+			usedCIDRs := make([]*net.IPNet, len(tc.usedCIDRs))
+			for i, usedCIDR := range tc.usedCIDRs {
+				_, usedCIDR, _ := net.ParseCIDR(usedCIDR)
+				usedCIDRs[i] = usedCIDR
+			}
+			mask := net.CIDRMask(tc.mask, 32)
+			got, err := cidr.FillGap(gap, &mask, usedCIDRs)
+			if tc.wantError != nil {
+				if !errors.Is(err, tc.wantError) {
+					t.Fatalf("Invalid error, want: %v, got %v,", tc.wantError, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %s,", err.Error())
+			}
+			if got.String() != tc.want {
+				t.Fatalf("want: %v, got: %v", tc.want, got.String())
+			}
+
+			free, err := cidr.FreeSpace(gap, append(usedCIDRs, got))
+			if err != nil {
+				t.Fatalf("Unexpected error: %s,", err.Error())
+			}
+			remaining := make([]string, len(free))
+			for i, block := range free {
+				remaining[i] = block.String()
+			}
+			if strings.Join(remaining, ",") != strings.Join(tc.remaining, ",") {
+				t.Fatalf("want: %v, got: %v", tc.remaining, remaining)
+			}
+		})
+	}
+}
@@ -0,0 +1,44 @@
+package cidr
+
+import (
+	"fmt"
+	"net"
+)
+
+// maxFreeListSplitBits bounds how many times larger than the desired mask a free block carved by
+// AllocateFromFreeList can be, since its remainder is returned as individual blocks.
+const maxFreeListSplitBits = 16
+
+// AllocateFromFreeList allocates a CIDR of the desired mask size from an explicit, ordered list of
+// free blocks. The first free block large enough to hold the desired mask is chosen and its lowest
+// addressed block of that size is allocated. The rest of the chosen block is split into aligned blocks
+// of the desired size which take its place in the returned free list, so a /24 carved out of a /22
+// leaves three /24s. To keep the free list bounded, a block more than 2^16 times the desired size
+// can't be carved and returns ErrInvalidInputRanges. The freeBlocks slice itself is not modified.
+func AllocateFromFreeList(freeBlocks []*net.IPNet, desiredMask *net.IPMask) (*net.IPNet, []*net.IPNet, error) {
+	desiredOnes, desiredBits := desiredMask.Size()
+
+	for i, free := range freeBlocks {
+		freeOnes, freeBits := free.Mask.Size()
+		if freeBits != desiredBits || freeOnes > desiredOnes {
+			continue
+		}
+		if desiredOnes-freeOnes > maxFreeListSplitBits {
+			return nil, freeBlocks, fmt.Errorf("%w: splitting %s into /%d blocks would leave more than 2^%d free blocks", ErrInvalidInputRanges, free.String(), desiredOnes, maxFreeListSplitBits)
+		}
+
+		canonicalFree := &net.IPNet{IP: free.IP.Mask(free.Mask), Mask: free.Mask}
+		blocks, err := SplitByPrefix(canonicalFree, desiredOnes)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		updated := make([]*net.IPNet, 0, len(freeBlocks)-2+len(blocks))
+		updated = append(updated, freeBlocks[:i]...)
+		updated = append(updated, blocks[1:]...)
+		updated = append(updated, freeBlocks[i+1:]...)
+		return blocks[0], updated, nil
+	}
+
+	return nil, freeBlocks, fmt.Errorf("%w: no free block is large enough for the requested mask", ErrNoAvailableCIDR)
+}
@@ -0,0 +1,86 @@
+package cidr_test
+
+import (
+	"errors"
+	"net"
+	"testing"
+
+	"github.com/massdriver-cloud/cola/pkg/cidr"
+)
+
+func TestAllocateFromFreeList(t *testing.T) {
+	type testData struct {
+		name        string
+		freeBlocks  []string
+		desiredMask net.IPMask
+		want        string
+		wantFree    []string
+		wantError   error
+	}
+	tests := []testData{
+		{
+			name:        "Carve /24 from /22",
+			freeBlocks:  []string{"10.0.0.0/22"},
+			desiredMask: net.CIDRMask(24, 32),
+			want:        "10.0.0.0/24",
+			wantFree:    []string{"10.0.1.0/24", "10.0.2.0/24", "10.0.3.0/24"},
+		},
+		{
+			name:        "Exact fit",
+			freeBlocks:  []string{"10.0.0.0/22", "10.0.8.0/24"},
+			desiredMask: net.CIDRMask(22, 32),
+			want:        "10.0.0.0/22",
+			wantFree:    []string{"10.0.8.0/24"},
+		},
+		{
+			name:        "Skips blocks that are too small",
+			freeBlocks:  []string{"10.0.0.0/26", "10.0.4.0/22", "10.0.8.0/22"},
+			desiredMask: net.CIDRMask(24, 32),
+			want:        "10.0.4.0/24",
+			wantFree:    []string{"10.0.0.0/26", "10.0.5.0/24", "10.0.6.0/24", "10.0.7.0/24", "10.0.8.0/22"},
+		},
+		{
+			name:        "Error block too large to split",
+			freeBlocks:  []string{"10.0.0.0/8"},
+			desiredMask: net.CIDRMask(28, 32),
+			wantError:   cidr.ErrInvalidInputRanges,
+		},
+		{
+			name:        "Error no block large enough",
+			freeBlocks:  []string{"10.0.0.0/26", "10.0.1.0/25"},
+			desiredMask: net.CIDRMask(24, 32),
+			wantError:   cidr.ErrNoAvailableCIDR,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			freeBlocks := make([]*net.IPNet, len(tc.freeBlocks))
+			for i, free := range tc.freeBlocks {
+				_, free, _ := net.ParseCIDR(free)
+				freeBlocks[i] = free
+			}
+			got, gotFree, err := cidr.AllocateFromFreeList(freeBlocks, &tc.desiredMask)
+			if tc.wantError != nil {
+				if !errors.Is(err, tc.wantError) {
+					t.Fatalf("Invalid error, want: %v, got %v,", tc.wantError, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %s,", err.Error())
+			}
+			if got.String() != tc.want {
+				t.Fatalf("want: %v, got: %v", tc.want, got.String())
+			}
+			if len(gotFree) != len(tc.wantFree) {
+				t.Fatalf("want: %v, got: %v", tc.wantFree, gotFree)
+			}
+			for i := range gotFree {
+				if gotFree[i].String() != tc.wantFree[i] {
+					t.Fatalf("want: %v, got: %v", tc.wantFree[i], gotFree[i].String())
+				}
+			}
+		})
+	}
+}
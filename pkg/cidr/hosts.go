@@ -0,0 +1,56 @@
+package cidr
+
+import (
+	"fmt"
+	"math/big"
+	"net"
+)
+
+// DefaultHostsLimit is the most addresses Hosts will return, to avoid accidentally expanding a large
+// CIDR into millions of addresses. Use WalkHosts for larger CIDRs.
+const DefaultHostsLimit = 65536
+
+// Hosts returns every usable host address in n, as defined by UsableRange. An error is returned if
+// there would be more than DefaultHostsLimit addresses.
+func Hosts(n *net.IPNet) ([]net.IP, error) {
+	return HostsWithLimit(n, DefaultHostsLimit)
+}
+
+// HostsWithLimit returns every usable host address in n, as defined by UsableRange. An error is
+// returned if there would be more than limit addresses.
+func HostsWithLimit(n *net.IPNet, limit int) ([]net.IP, error) {
+	if v4, ok := toIPv4Net(n); ok {
+		n = v4
+	}
+	ones, bits := n.Mask.Size()
+	count := usableHostCount(ones, bits)
+	if count.Cmp(big.NewInt(int64(limit))) > 0 {
+		return nil, fmt.Errorf("%w: %s has %s hosts, more than the limit of %d", ErrInvalidInputRanges, n.String(), count.String(), limit)
+	}
+
+	hosts := make([]net.IP, 0, count.Int64())
+	WalkHosts(n, func(ip net.IP) bool {
+		hosts = append(hosts, ip)
+		return true
+	})
+	return hosts, nil
+}
+
+// WalkHosts calls fn with every usable host address in n, as defined by UsableRange, in ascending
+// order. The walk stops early if fn returns false.
+func WalkHosts(n *net.IPNet, fn func(net.IP) bool) {
+	if v4, ok := toIPv4Net(n); ok {
+		n = v4
+	}
+	_, bits := n.Mask.Size()
+	first, last := UsableRange(n)
+	end := IPToInt(last)
+	one := big.NewInt(1)
+	for current := IPToInt(first); current.Cmp(end) <= 0; current.Add(current, one) {
+		// current never leaves the range of n, so the conversion can't fail
+		ip, _ := IntToIP(current, bits)
+		if !fn(ip) {
+			return
+		}
+	}
+}
@@ -0,0 +1,81 @@
+package cidr_test
+
+import (
+	"errors"
+	"net"
+	"testing"
+
+	"github.com/massdriver-cloud/cola/pkg/cidr"
+)
+
+func TestHosts(t *testing.T) {
+	type testData struct {
+		name      string
+		cidr      string
+		want      []string
+		wantError error
+	}
+	tests := []testData{
+		{
+			name: "IPv4 /30",
+			cidr: "10.0.0.0/30",
+			want: []string{"10.0.0.1", "10.0.0.2"},
+		},
+		{
+			name: "IPv4 /31",
+			cidr: "10.0.0.0/31",
+			want: []string{"10.0.0.0", "10.0.0.1"},
+		},
+		{
+			name: "IPv4-mapped /126",
+			cidr: "::ffff:10.0.0.0/126",
+			want: []string{"10.0.0.1", "10.0.0.2"},
+		},
+		{
+			name: "IPv6 /126",
+			cidr: "2001:db8::/126",
+			want: []string{"2001:db8::", "2001:db8::1", "2001:db8::2", "2001:db8::3"},
+		},
+		{
+			name:      "Error limit exceeded",
+			cidr:      "10.0.0.0/8",
+			wantError: cidr.ErrInvalidInputRanges,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			_, n, _ := net.ParseCIDR(tc.cidr)
+			got, err := cidr.Hosts(n)
+			if tc.wantError != nil {
+				if !errors.Is(err, tc.wantError) {
+					t.Fatalf("Invalid error, want: %v, got %v,", tc.wantError, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %s,", err.Error())
+			}
+			if len(got) != len(tc.want) {
+				t.Fatalf("want: %v, got: %v", tc.want, got)
+			}
+			for i := range got {
+				if got[i].String() != tc.want[i] {
+					t.Fatalf("want: %v, got: %v", tc.want[i], got[i].String())
+				}
+			}
+		})
+	}
+}
+
+func TestWalkHostsStops(t *testing.T) {
+	_, n, _ := net.ParseCIDR("10.0.0.0/8")
+	visited := 0
+	cidr.WalkHosts(n, func(ip net.IP) bool {
+		visited++
+		return visited < 3
+	})
+	if visited != 3 {
+		t.Fatalf("want: %v, got: %v", 3, visited)
+	}
+}
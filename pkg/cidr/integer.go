@@ -0,0 +1,97 @@
+package cidr
+
+import (
+	"fmt"
+	"math/big"
+	"net"
+)
+
+// IPToInt returns the unsigned integer representation of ip. IPv4 addresses are converted
+// from their 4 byte form, so 10.0.0.0 is 167772160 regardless of how the address is stored.
+func IPToInt(ip net.IP) *big.Int {
+	if v4 := ip.To4(); v4 != nil {
+		ip = v4
+	}
+	return new(big.Int).SetBytes(ip)
+}
+
+// IntToIP converts an unsigned integer back to an IP address of the given bit length (32 for
+// IPv4, 128 for IPv6). An error is returned if the integer doesn't fit in the address space.
+func IntToIP(i *big.Int, bits int) (net.IP, error) {
+	if bits != 8*net.IPv4len && bits != 8*net.IPv6len {
+		return nil, fmt.Errorf("%w: invalid address length %d", ErrInvalidInputRanges, bits)
+	}
+	if i.Sign() < 0 || i.BitLen() > bits {
+		return nil, fmt.Errorf("%w: %s is outside the %d bit address space", ErrInvalidInputRanges, i.String(), bits)
+	}
+	ip := make(net.IP, bits/8)
+	i.FillBytes(ip)
+	return ip, nil
+}
+
+// CIDRToIntRange returns the first and last addresses of n as unsigned integers.
+func CIDRToIntRange(n *net.IPNet) (*big.Int, *big.Int) {
+	first := IPToInt(n.IP.Mask(n.Mask))
+	last := new(big.Int).Add(first, BlockSize(&n.Mask))
+	last.Sub(last, big.NewInt(1))
+	return first, last
+}
+
+// RangeToCIDRs returns the minimal list of CIDRs, in ascending order, which exactly covers
+// every address from first to last inclusive.
+func RangeToCIDRs(first net.IP, last net.IP) ([]*net.IPNet, error) {
+	bits := 8 * net.IPv6len
+	if first.To4() != nil && last.To4() != nil {
+		bits = 8 * net.IPv4len
+	} else if first.To4() != nil || last.To4() != nil {
+		return nil, fmt.Errorf("%w: range mixes IPv4 and IPv6 addresses", ErrInvalidInputRanges)
+	}
+
+	start := IPToInt(first)
+	end := IPToInt(last)
+	if start.Cmp(end) > 0 {
+		return nil, fmt.Errorf("%w: range start %s is after range end %s", ErrInvalidInputRanges, first.String(), last.String())
+	}
+
+	one := big.NewInt(1)
+	cidrs := []*net.IPNet{}
+	for start.Cmp(end) <= 0 {
+		// grow the block while it stays aligned to start and doesn't pass the end of the range
+		hostBits := 0
+		for hostBits < bits && start.Bit(hostBits) == 0 {
+			size := new(big.Int).Lsh(one, uint(hostBits+1))
+			blockLast := new(big.Int).Add(start, size)
+			if blockLast.Sub(blockLast, one).Cmp(end) > 0 {
+				break
+			}
+			hostBits++
+		}
+
+		ip, err := IntToIP(start, bits)
+		if err != nil {
+			return nil, err
+		}
+		cidrs = append(cidrs, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits-hostBits, bits)})
+
+		start.Add(start, new(big.Int).Lsh(one, uint(hostBits)))
+	}
+
+	return cidrs, nil
+}
+
+// AreAdjacent returns true if the last address of one CIDR is immediately followed by the first address
+// of the other, regardless of their prefix lengths. 10.0.1.0/24 and 10.0.2.0/23 are adjacent even though
+// they can't be joined into a single CIDR. CIDRs from different address families are never adjacent, and
+// there is no wrap around at the top of the address space.
+func AreAdjacent(a *net.IPNet, b *net.IPNet) bool {
+	_, aBits := a.Mask.Size()
+	_, bBits := b.Mask.Size()
+	if aBits == 0 || aBits != bBits {
+		return false
+	}
+
+	aFirst, aLast := CIDRToIntRange(a)
+	bFirst, bLast := CIDRToIntRange(b)
+	one := big.NewInt(1)
+	return new(big.Int).Add(aLast, one).Cmp(bFirst) == 0 || new(big.Int).Add(bLast, one).Cmp(aFirst) == 0
+}
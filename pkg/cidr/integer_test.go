@@ -0,0 +1,217 @@
+package cidr_test
+
+import (
+	"math/big"
+	"net"
+	"testing"
+
+	"github.com/massdriver-cloud/cola/pkg/cidr"
+)
+
+func TestCIDRToIntRange(t *testing.T) {
+	type testData struct {
+		name      string
+		cidr      string
+		wantFirst string
+		wantLast  string
+	}
+	tests := []testData{
+		{
+			name:      "IPv4 /24",
+			cidr:      "10.0.0.0/24",
+			wantFirst: "167772160",
+			wantLast:  "167772415",
+		},
+		{
+			name:      "IPv4 /32",
+			cidr:      "192.168.1.1/32",
+			wantFirst: "3232235777",
+			wantLast:  "3232235777",
+		},
+		{
+			name:      "IPv4 entire space",
+			cidr:      "0.0.0.0/0",
+			wantFirst: "0",
+			wantLast:  "4294967295",
+		},
+		{
+			name:      "IPv6 /64",
+			cidr:      "2001:db8::/64",
+			wantFirst: "42540766411282592856903984951653826560",
+			wantLast:  "42540766411282592875350729025363378175",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			_, n, _ := net.ParseCIDR(tc.cidr)
+			first, last := cidr.CIDRToIntRange(n)
+
+			if first.String() != tc.wantFirst {
+				t.Fatalf("want: %v, got: %v", tc.wantFirst, first.String())
+			}
+			if last.String() != tc.wantLast {
+				t.Fatalf("want: %v, got: %v", tc.wantLast, last.String())
+			}
+		})
+	}
+}
+
+func TestIntRangeRoundTrip(t *testing.T) {
+	tests := []string{
+		"10.0.0.0/24",
+		"10.0.88.0/21",
+		"172.16.0.0/12",
+		"192.168.1.1/32",
+		"0.0.0.0/0",
+		"2001:db8::/64",
+	}
+
+	for _, test := range tests {
+		t.Run(test, func(t *testing.T) {
+			_, n, _ := net.ParseCIDR(test)
+			_, bits := n.Mask.Size()
+
+			first, last := cidr.CIDRToIntRange(n)
+			firstIP, err := cidr.IntToIP(first, bits)
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err.Error())
+			}
+			lastIP, err := cidr.IntToIP(last, bits)
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err.Error())
+			}
+
+			got, err := cidr.RangeToCIDRs(firstIP, lastIP)
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err.Error())
+			}
+			if len(got) != 1 || got[0].String() != n.String() {
+				t.Fatalf("want: %v, got: %v", n.String(), got)
+			}
+		})
+	}
+}
+
+func TestRangeToCIDRs(t *testing.T) {
+	type testData struct {
+		name  string
+		first string
+		last  string
+		want  []string
+	}
+	tests := []testData{
+		{
+			name:  "Unaligned range",
+			first: "10.0.0.1",
+			last:  "10.0.0.6",
+			want:  []string{"10.0.0.1/32", "10.0.0.2/31", "10.0.0.4/31", "10.0.0.6/32"},
+		},
+		{
+			name:  "Spans blocks",
+			first: "10.0.0.0",
+			last:  "10.0.2.255",
+			want:  []string{"10.0.0.0/23", "10.0.2.0/24"},
+		},
+		{
+			name:  "Single address",
+			first: "10.0.0.5",
+			last:  "10.0.0.5",
+			want:  []string{"10.0.0.5/32"},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := cidr.RangeToCIDRs(net.ParseIP(tc.first), net.ParseIP(tc.last))
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err.Error())
+			}
+			if len(got) != len(tc.want) {
+				t.Fatalf("want: %v, got: %v", tc.want, got)
+			}
+			for i := range got {
+				if got[i].String() != tc.want[i] {
+					t.Fatalf("want: %v, got: %v", tc.want[i], got[i].String())
+				}
+			}
+		})
+	}
+}
+
+func TestIntToIPOutOfRange(t *testing.T) {
+	tooBig := new(big.Int).Lsh(big.NewInt(1), 32)
+	if _, err := cidr.IntToIP(tooBig, 32); err == nil {
+		t.Fatalf("expected error converting %s to an IPv4 address", tooBig.String())
+	}
+}
+
+func TestAreAdjacent(t *testing.T) {
+	type testData struct {
+		name string
+		a    string
+		b    string
+		want bool
+	}
+	tests := []testData{
+		{
+			name: "Siblings",
+			a:    "10.0.0.0/24",
+			b:    "10.0.1.0/24",
+			want: true,
+		},
+		{
+			name: "Adjacent but not siblings",
+			a:    "10.0.1.0/24",
+			b:    "10.0.2.0/23",
+			want: true,
+		},
+		{
+			name: "Adjacent in reverse order",
+			a:    "10.0.2.0/23",
+			b:    "10.0.1.0/24",
+			want: true,
+		},
+		{
+			name: "One address gap",
+			a:    "10.0.0.0/31",
+			b:    "10.0.0.3/32",
+			want: false,
+		},
+		{
+			name: "Overlapping",
+			a:    "10.0.0.0/16",
+			b:    "10.0.1.0/24",
+			want: false,
+		},
+		{
+			name: "Top of the address space",
+			a:    "255.255.255.0/24",
+			b:    "0.0.0.0/24",
+			want: false,
+		},
+		{
+			name: "IPv6",
+			a:    "2001:db8::/64",
+			b:    "2001:db8:0:1::/64",
+			want: true,
+		},
+		{
+			name: "Mixed families",
+			a:    "0.0.0.0/32",
+			b:    "::1/128",
+			want: false,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			_, a, _ := net.ParseCIDR(tc.a)
+			_, b, _ := net.ParseCIDR(tc.b)
+			got := cidr.AreAdjacent(a, b)
+			if got != tc.want {
+				t.Fatalf("want: %v, got: %v", tc.want, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,52 @@
+package cidr
+
+import (
+	"fmt"
+	"math/big"
+	"net"
+)
+
+// NextCIDRAfterSet returns the first block of the desiredMask size above the highest addressed of the
+// usedCIDRs, for append-only allocation where there's no root CIDR, just a log of what's been used. The
+// result is aligned to its size, so it may leave a gap after the highest used CIDR, and gaps lower down
+// are never reused. An ErrNoAvailableCIDR error is returned if the block would run past the top of the
+// address space, and an ErrInvalidInputRanges error if usedCIDRs is empty or mixes address families
+// with the desiredMask.
+func NextCIDRAfterSet(usedCIDRs []*net.IPNet, desiredMask *net.IPMask) (*net.IPNet, error) {
+	if len(usedCIDRs) == 0 {
+		return nil, fmt.Errorf("%w: at least one used CIDR is required", ErrInvalidInputRanges)
+	}
+	_, bits := desiredMask.Size()
+
+	var highest *net.IPNet
+	var highestLast *big.Int
+	for _, used := range usedCIDRs {
+		if v4, ok := toIPv4Net(used); ok {
+			used = v4
+		}
+		if _, usedBits := used.Mask.Size(); usedBits != bits {
+			return nil, fmt.Errorf("%w: used CIDR %s doesn't match the desired mask address family", ErrInvalidInputRanges, used.String())
+		}
+		if _, last := CIDRToIntRange(used); highestLast == nil || last.Cmp(highestLast) > 0 {
+			highest, highestLast = used, last
+		}
+	}
+
+	// round the address after the highest used CIDR up to the next multiple of the block size
+	one := big.NewInt(1)
+	size := BlockSize(desiredMask)
+	first := new(big.Int).Add(highestLast, size)
+	first.Div(first, size)
+	first.Mul(first, size)
+	last := new(big.Int).Add(first, size)
+	last.Sub(last, one)
+
+	if last.BitLen() > bits {
+		return nil, fmt.Errorf("%w: no /%d left above %s in the address space", ErrNoAvailableCIDR, BlockSizePrefix(desiredMask), highest.String())
+	}
+	ip, err := IntToIP(first, bits)
+	if err != nil {
+		return nil, err
+	}
+	return &net.IPNet{IP: ip, Mask: *desiredMask}, nil
+}
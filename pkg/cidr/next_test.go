@@ -0,0 +1,116 @@
+package cidr_test
+
+import (
+	"errors"
+	"net"
+	"testing"
+
+	"github.com/massdriver-cloud/cola/pkg/cidr"
+)
+
+func TestNextCIDRAfterSet(t *testing.T) {
+	type testData struct {
+		name        string
+		usedCIDRs   []string
+		desiredMask net.IPMask
+		want        string
+		wantError   error
+	}
+	tests := []testData{
+		{
+			name:        "Contiguous",
+			usedCIDRs:   []string{"10.0.0.0/24", "10.0.1.0/24"},
+			desiredMask: net.CIDRMask(24, 32),
+			want:        "10.0.2.0/24",
+		},
+		{
+			name:        "Highest isn't last in the list",
+			usedCIDRs:   []string{"10.0.5.0/24", "10.0.0.0/24", "10.0.2.0/24"},
+			desiredMask: net.CIDRMask(24, 32),
+			want:        "10.0.6.0/24",
+		},
+		{
+			name:        "Aligned to the desired size",
+			usedCIDRs:   []string{"10.0.0.0/24", "10.0.1.0/26"},
+			desiredMask: net.CIDRMask(22, 32),
+			want:        "10.0.4.0/22",
+		},
+		{
+			name:        "Smaller than the used CIDRs",
+			usedCIDRs:   []string{"10.0.0.0/16"},
+			desiredMask: net.CIDRMask(28, 32),
+			want:        "10.1.0.0/28",
+		},
+		{
+			name:        "Crosses an octet",
+			usedCIDRs:   []string{"10.0.255.0/24"},
+			desiredMask: net.CIDRMask(24, 32),
+			want:        "10.1.0.0/24",
+		},
+		{
+			name:        "Last block of the address space",
+			usedCIDRs:   []string{"255.255.254.0/24"},
+			desiredMask: net.CIDRMask(24, 32),
+			want:        "255.255.255.0/24",
+		},
+		{
+			name:        "IPv4-mapped used CIDR",
+			usedCIDRs:   []string{"::ffff:10.0.0.0/120"},
+			desiredMask: net.CIDRMask(24, 32),
+			want:        "10.0.1.0/24",
+		},
+		{
+			name:        "IPv6",
+			usedCIDRs:   []string{"2001:db8::/64"},
+			desiredMask: net.CIDRMask(64, 128),
+			want:        "2001:db8:0:1::/64",
+		},
+		{
+			name:        "Error wraps past the ceiling",
+			usedCIDRs:   []string{"255.255.255.0/24"},
+			desiredMask: net.CIDRMask(24, 32),
+			wantError:   cidr.ErrNoAvailableCIDR,
+		},
+		{
+			name:        "Error aligned block past the ceiling",
+			usedCIDRs:   []string{"255.255.255.0/25"},
+			desiredMask: net.CIDRMask(24, 32),
+			wantError:   cidr.ErrNoAvailableCIDR,
+		},
+		{
+			name:        "Error no used CIDRs",
+			usedCIDRs:   []string{},
+			desiredMask: net.CIDRMask(24, 32),
+			wantError:   cidr.ErrInvalidInputRanges,
+		},
+		{
+			name:        "Error mixed families",
+			usedCIDRs:   []string{"10.0.0.0/24", "2001:db8::/64"},
+			desiredMask: net.CIDRMask(24, 32),
+			wantError:   cidr.ErrInvalidInputRanges,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			usedCIDRs := make([]*net.IPNet, len(tc.usedCIDRs))
+			for i, usedCIDR := range tc.usedCIDRs {
+				_, usedCIDR, _ := net.ParseCIDR(usedCIDR)
+				usedCIDRs[i] = usedCIDR
+			}
+			got, err := cidr.NextCIDRAfterSet(usedCIDRs, &tc.desiredMask)
+			if tc.wantError != nil {
+				if !errors.Is(err, tc.wantError) {
+					t.Fatalf("Invalid error, want: %v, got %v,", tc.wantError, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %s,", err.Error())
+			}
+			if got.String() != tc.want {
+				t.Fatalf("want: %v, got: %v", tc.want, got.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,124 @@
+package cidr
+
+import (
+	"fmt"
+	"net"
+)
+
+const (
+	ipv4Bits = 8 * net.IPv4len
+	ipv6Bits = 8 * net.IPv6len
+	// ipv4MappedPrefix is the length of the ::ffff:0:0/96 prefix which IPv4-mapped IPv6 addresses share
+	ipv4MappedPrefix = ipv6Bits - ipv4Bits
+)
+
+// normalizeInputs canonicalizes the root CIDR, desired mask and used CIDRs to the address family of the
+// root CIDR. IPv4 ranges written in their IPv4-mapped IPv6 form (::ffff:10.0.0.0/104) are converted to
+// their 4 byte form (10.0.0.0/8) when the root is IPv4, so that mask comparisons line up. Inputs which
+// genuinely mix IPv4 and IPv6 return ErrInvalidInputRanges.
+func normalizeInputs(rootCIDR *net.IPNet, desiredMask *net.IPMask, usedCIDRs []*net.IPNet) (*net.IPNet, *net.IPMask, []*net.IPNet, error) {
+	root, isIPv4 := toIPv4Net(rootCIDR)
+	if !isIPv4 {
+		root = toIPv6Net(rootCIDR)
+		if root == nil {
+			return nil, nil, nil, fmt.Errorf("%w: root CIDR %s is not a valid CIDR", ErrInvalidInputRanges, rootCIDR.String())
+		}
+	}
+
+	mask, err := normalizeMask(desiredMask, isIPv4)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	used := make([]*net.IPNet, len(usedCIDRs))
+	for i, usedCIDR := range usedCIDRs {
+		if isIPv4 {
+			v4, ok := toIPv4Net(usedCIDR)
+			if !ok {
+				return nil, nil, nil, fmt.Errorf("%w: used CIDR %s is not IPv4 but root CIDR %s is", ErrInvalidInputRanges, usedCIDR.String(), root.String())
+			}
+			used[i] = v4
+			continue
+		}
+		if _, bits := usedCIDR.Mask.Size(); bits != ipv6Bits {
+			return nil, nil, nil, fmt.Errorf("%w: used CIDR %s is not IPv6 but root CIDR %s is", ErrInvalidInputRanges, usedCIDR.String(), root.String())
+		}
+		used[i] = toIPv6Net(usedCIDR)
+	}
+
+	return root, mask, used, nil
+}
+
+// normalizeMask converts the mask to an IPv4 (32 bit) mask or an IPv6 (128 bit) mask.
+func normalizeMask(mask *net.IPMask, isIPv4 bool) (*net.IPMask, error) {
+	ones, bits := mask.Size()
+	switch {
+	case isIPv4 && bits == ipv4Bits, !isIPv4 && bits == ipv6Bits:
+		return mask, nil
+	case isIPv4 && bits == ipv6Bits && ones >= ipv4MappedPrefix:
+		converted := net.CIDRMask(ones-ipv4MappedPrefix, ipv4Bits)
+		return &converted, nil
+	}
+	return nil, fmt.Errorf("%w: desired mask /%d (%d bits) doesn't match the root CIDR address family", ErrInvalidInputRanges, ones, bits)
+}
+
+// toIPv4Net returns n in its 4 byte form, and false if n isn't an IPv4 range.
+func toIPv4Net(n *net.IPNet) (*net.IPNet, bool) {
+	v4 := n.IP.To4()
+	if v4 == nil {
+		return nil, false
+	}
+	ones, bits := n.Mask.Size()
+	switch {
+	case bits == ipv4Bits:
+		return &net.IPNet{IP: v4, Mask: n.Mask}, true
+	case bits == ipv6Bits && ones >= ipv4MappedPrefix:
+		return &net.IPNet{IP: v4, Mask: net.CIDRMask(ones-ipv4MappedPrefix, ipv4Bits)}, true
+	}
+	return nil, false
+}
+
+// toIPv6Net returns n in its 16 byte form, or nil if n doesn't have an IPv6 mask.
+func toIPv6Net(n *net.IPNet) *net.IPNet {
+	if _, bits := n.Mask.Size(); bits != ipv6Bits {
+		return nil
+	}
+	return &net.IPNet{IP: n.IP.To16(), Mask: n.Mask}
+}
+
+// canonicalizeInputs clears the host bits of the root CIDR and used CIDRs if normalize is set, and
+// otherwise returns ErrInvalidInputRanges if the root CIDR has host bits set.
+func canonicalizeInputs(rootCIDR *net.IPNet, usedCIDRs []*net.IPNet, normalize bool) (*net.IPNet, []*net.IPNet, error) {
+	if !normalize {
+		if !IsCanonical(rootCIDR) {
+			return nil, nil, fmt.Errorf("%w: root CIDR %s has host bits set, did you mean %s?", ErrInvalidInputRanges, rootCIDR.String(), canonical(rootCIDR).String())
+		}
+		return rootCIDR, usedCIDRs, nil
+	}
+
+	used := make([]*net.IPNet, len(usedCIDRs))
+	for i, usedCIDR := range usedCIDRs {
+		used[i] = canonical(usedCIDR)
+	}
+	return canonical(rootCIDR), used, nil
+}
+
+// canonical returns n with its host bits cleared.
+func canonical(n *net.IPNet) *net.IPNet {
+	return &net.IPNet{IP: n.IP.Mask(n.Mask), Mask: n.Mask}
+}
+
+// CanonicalKey returns a string which is the same for every representation of the network n: host
+// bits are cleared and IPv4 networks in their IPv4-mapped IPv6 form are converted to IPv4, so
+// 10.0.0.7/24 and ::ffff:10.0.0.0/120 both have the key 10.0.0.0/24.
+func CanonicalKey(n *net.IPNet) string {
+	return canonicalForm(n).String()
+}
+
+// canonicalForm returns n with its host bits cleared, converting IPv4-mapped IPv6 networks to IPv4.
+func canonicalForm(n *net.IPNet) *net.IPNet {
+	if v4, ok := toIPv4Net(n); ok {
+		return canonical(v4)
+	}
+	return canonical(n)
+}
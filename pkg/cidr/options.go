@@ -0,0 +1,284 @@
+package cidr
+
+import (
+	"errors"
+	"fmt"
+	"net"
+)
+
+// FindOption configures optional behavior of FindAvailableCIDR.
+type FindOption func(*findOptions)
+
+type findOptions struct {
+	denyList         []*net.IPNet
+	maxDepth         int
+	keepOut          int
+	stride           int
+	normalizeInput   bool
+	conflictReport   bool
+	conflicts        []*net.IPNet
+	candidateFilters []candidateFilter
+	affinity         *net.IPNet
+	constraints      []Constraints
+	scorer           Scorer
+	candidateLimit   int
+	minLargestBlock  int
+	guardRejections  int
+	subnetsPrefix    int
+	maxSubnets       int
+	poolStrategy     PoolStrategy
+	rejectSpecialUse bool
+	minimizeRoutes   bool
+	preferRegion     *net.IPNet
+	excludeEdges     bool
+	allowList        bool
+	allowedSupernets []*net.IPNet
+	validateUsed     bool
+}
+
+// candidateFilter is called with each CIDR that passes the standard checks, and returns false to
+// reject it and continue searching.
+type candidateFilter func(candidate *net.IPNet) bool
+
+func newFindOptions(opts []FindOption) *findOptions {
+	options := &findOptions{
+		maxDepth: -1,
+		keepOut:  -1,
+		stride:   -1,
+
+		minLargestBlock: -1,
+		subnetsPrefix:   -1,
+	}
+	for _, opt := range opts {
+		opt(options)
+	}
+	return options
+}
+
+// acceptCandidate returns true if the candidate passes every candidate filter, in order.
+func (o *findOptions) acceptCandidate(candidate *net.IPNet) bool {
+	for _, filter := range o.candidateFilters {
+		if !filter(candidate) {
+			return false
+		}
+	}
+	return true
+}
+
+// WithMaxDepth aborts the search with ErrSearchDepthExceeded once the walk descends more than
+// maxDepth levels below the root CIDR. This bounds the cost of searching a large root CIDR for a
+// small mask. By default the search depth is unbounded.
+func WithMaxDepth(maxDepth int) FindOption {
+	return func(o *findOptions) {
+		o.maxDepth = maxDepth
+	}
+}
+
+// WithNormalizeInput clears any host bits set in the root CIDR and used CIDRs, so 10.0.5.0/16 is
+// treated as 10.0.0.0/16, instead of returning ErrInvalidInputRanges.
+func WithNormalizeInput() FindOption {
+	return func(o *findOptions) {
+		o.normalizeInput = true
+	}
+}
+
+// WithConflictReport makes FindAvailableCIDR return a *ConflictError listing the used CIDRs which
+// blocked the candidates it checked when no CIDR is available.
+func WithConflictReport() FindOption {
+	return func(o *findOptions) {
+		o.conflictReport = true
+	}
+}
+
+// recordConflicts records the used CIDRs within the current CIDR, if conflict reporting is enabled.
+func (o *findOptions) recordConflicts(current *net.IPNet, usedCIDRs []*net.IPNet) {
+	if !o.conflictReport {
+		return
+	}
+	for _, used := range usedCIDRs {
+		if !ContainsCIDR(current, used) || MatchesExistingCIDR(used, o.conflicts) {
+			continue
+		}
+		o.conflicts = append(o.conflicts, used)
+	}
+}
+
+// conflictError wraps a failed search in a ConflictError, if conflict reporting is enabled.
+func (o *findOptions) conflictError(err error) error {
+	if !o.conflictReport || !errors.Is(err, ErrNoAvailableCIDR) {
+		return err
+	}
+	return &ConflictError{Blockers: o.conflicts, Err: err}
+}
+
+// WithKeepOut keeps new CIDRs from being placed right next to existing ones by treating each used CIDR
+// as the whole prefixLen sized block containing it when checking for collisions, and rejecting any
+// result directly adjacent to that block. For example, with a keep-out of 22 a used 10.0.3.0/24 blocks
+// all of 10.0.0.0/22, leaving room for it to grow, and a /24 result can't be 10.0.4.0/24 either, so
+// there's always a gap on both sides of the used CIDR. The keep-out only affects where the result is
+// placed, the result is still the desired size. Used CIDRs already larger than prefixLen aren't
+// expanded but still get the adjacency guard, and the keep-out never extends beyond the root CIDR.
+func WithKeepOut(prefixLen int) FindOption {
+	return func(o *findOptions) {
+		o.keepOut = prefixLen
+	}
+}
+
+// applyKeepOut returns the used CIDRs expanded to the keep-out prefix length, limited to the size of
+// the root CIDR, and adds a candidate filter rejecting results adjacent to an expanded block.
+func (o *findOptions) applyKeepOut(rootCIDR *net.IPNet, usedCIDRs []*net.IPNet) []*net.IPNet {
+	if o.keepOut < 0 {
+		return usedCIDRs
+	}
+	expanded := expandCIDRs(rootCIDR, usedCIDRs, o.keepOut)
+	o.candidateFilters = append(o.candidateFilters, func(candidate *net.IPNet) bool {
+		for _, block := range expanded {
+			if AreAdjacent(candidate, block) {
+				return false
+			}
+		}
+		return true
+	})
+	return expanded
+}
+
+// expandCIDRs returns each of the cidrs expanded to the prefix sized block containing it, limited to
+// the size of the root CIDR. CIDRs already larger than prefix are returned as is.
+func expandCIDRs(rootCIDR *net.IPNet, cidrs []*net.IPNet, prefix int) []*net.IPNet {
+	if rootOnes, _ := rootCIDR.Mask.Size(); prefix < rootOnes {
+		prefix = rootOnes
+	}
+	expanded := make([]*net.IPNet, len(cidrs))
+	for i, n := range cidrs {
+		ones, bits := n.Mask.Size()
+		if ones <= prefix {
+			expanded[i] = n
+			continue
+		}
+		mask := net.CIDRMask(prefix, bits)
+		expanded[i] = &net.IPNet{IP: n.IP.Mask(mask), Mask: mask}
+	}
+	return expanded
+}
+
+// WithStride places results on a grid of prefixLen sized blocks, so each result starts a block of
+// its own and sequential allocations are evenly spaced. For example, with a stride of 20 /24s are
+// allocated at 10.0.0.0/24, 10.0.16.0/24 and so on, leaving room for each to grow. Like WithKeepOut,
+// each used CIDR blocks the whole stride block containing it. The stride must be shorter than the
+// desired mask's prefix length, otherwise ErrInvalidInputRanges is returned.
+func WithStride(prefixLen int) FindOption {
+	return func(o *findOptions) {
+		o.stride = prefixLen
+	}
+}
+
+// applyStride returns the used CIDRs expanded to the stride prefix length, and adds a candidate filter
+// rejecting results which aren't at the start of a stride block.
+func (o *findOptions) applyStride(rootCIDR *net.IPNet, desiredMask *net.IPMask, usedCIDRs []*net.IPNet) ([]*net.IPNet, error) {
+	if o.stride < 0 {
+		return usedCIDRs, nil
+	}
+	if BlockSizePrefix(desiredMask) <= o.stride {
+		return nil, fmt.Errorf("%w: stride /%d must be larger than the desired mask /%d", ErrInvalidInputRanges, o.stride, BlockSizePrefix(desiredMask))
+	}
+
+	_, bits := desiredMask.Size()
+	strideMask := net.CIDRMask(o.stride, bits)
+	o.candidateFilters = append(o.candidateFilters, func(candidate *net.IPNet) bool {
+		return candidate.IP.Equal(candidate.IP.Mask(strideMask))
+	})
+	return expandCIDRs(rootCIDR, usedCIDRs, o.stride), nil
+}
+
+// WithMaxSubnetsUnder spreads allocations out by limiting how many used CIDRs can share a supernet of
+// supernetPrefix, keeping the blast radius of any one supernet down. A result is rejected if there are
+// already maxCount used CIDRs within its supernet, so with WithMaxSubnetsUnder(20, 4) the fifth /24
+// goes into the next /20 instead. Results larger than the supernet aren't limited.
+func WithMaxSubnetsUnder(supernetPrefix int, maxCount int) FindOption {
+	return func(o *findOptions) {
+		o.subnetsPrefix = supernetPrefix
+		o.maxSubnets = maxCount
+	}
+}
+
+// applyMaxSubnetsUnder adds a candidate filter rejecting results whose supernet already holds the
+// maximum number of used CIDRs.
+func (o *findOptions) applyMaxSubnetsUnder(usedCIDRs []*net.IPNet) {
+	if o.subnetsPrefix < 0 {
+		return
+	}
+	o.candidateFilters = append(o.candidateFilters, func(candidate *net.IPNet) bool {
+		ones, bits := candidate.Mask.Size()
+		if ones < o.subnetsPrefix {
+			return true
+		}
+		supernetMask := net.CIDRMask(o.subnetsPrefix, bits)
+		supernet := &net.IPNet{IP: candidate.IP.Mask(supernetMask), Mask: supernetMask}
+		count := 0
+		for _, used := range usedCIDRs {
+			if ContainsCIDR(supernet, used) {
+				count++
+			}
+		}
+		return count < o.maxSubnets
+	})
+}
+
+// WithAffinity makes FindAvailableCIDR prefer results close to the hint, keeping related CIDRs together
+// for route summarization. Of the available CIDRs, the one sharing the longest CommonSupernet with the
+// hint is returned, so a free hint is returned itself, then its sibling, and so on outwards. The hint
+// doesn't need to be used or even within the root CIDR, it only changes the order of the search.
+func WithAffinity(hint *net.IPNet) FindOption {
+	return func(o *findOptions) {
+		if v4, ok := toIPv4Net(hint); ok {
+			hint = v4
+		}
+		o.affinity = hint
+	}
+}
+
+// childOrder returns the children in the order to search them, which is the lower child first unless
+// the upper child has a longer common supernet with the affinity hint.
+func (o *findOptions) childOrder(child1 *net.IPNet, child2 *net.IPNet) []*net.IPNet {
+	if o.affinity == nil {
+		return []*net.IPNet{child1, child2}
+	}
+	supernet1, err1 := CommonSupernet(child1, o.affinity)
+	supernet2, err2 := CommonSupernet(child2, o.affinity)
+	if err1 != nil || err2 != nil {
+		return []*net.IPNet{child1, child2}
+	}
+	if BlockSizePrefix(&supernet1.Mask) >= BlockSizePrefix(&supernet2.Mask) {
+		return []*net.IPNet{child1, child2}
+	}
+	return []*net.IPNet{child2, child1}
+}
+
+// WithPreferRegion makes FindAvailableCIDR search the region first, falling back to the whole root CIDR
+// only if nothing fits there. A caller which knows a region was just released can steer the next
+// result back into it without keeping allocator state. The region is searched with the other options
+// as if it were the root CIDR, and is ignored unless it's within the root CIDR.
+func WithPreferRegion(region *net.IPNet) FindOption {
+	return func(o *findOptions) {
+		if v4, ok := toIPv4Net(region); ok {
+			region = v4
+		}
+		o.preferRegion = canonical(region)
+	}
+}
+
+// findInPreferredRegion searches the WithPreferRegion region of the root CIDR, returning false if
+// there's no region smaller than the root within it, or nothing fits in the region.
+func (o *findOptions) findInPreferredRegion(rootCIDR *net.IPNet, desiredMask *net.IPMask, usedCIDRs []*net.IPNet, opts []FindOption) (*net.IPNet, bool) {
+	if o.preferRegion == nil || !SmallerMask(&o.preferRegion.Mask, &rootCIDR.Mask) || !ContainsCIDR(rootCIDR, o.preferRegion) {
+		return nil, false
+	}
+	regionOpts := append(append([]FindOption{}, opts...), func(o *findOptions) {
+		o.preferRegion = nil
+	})
+	result, err := FindAvailableCIDR(o.preferRegion, desiredMask, usedCIDRs, regionOpts...)
+	if err != nil {
+		return nil, false
+	}
+	return result, true
+}
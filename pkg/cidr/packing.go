@@ -0,0 +1,47 @@
+package cidr
+
+import (
+	"math/big"
+	"net"
+
+	"github.com/apparentlymart/go-cidr/cidr"
+)
+
+// PackingEfficiency scores how well the usedCIDRs are laid out within the rootCIDR, rather than how
+// full it is (see Utilization). It's the addresses in the used CIDRs over the span they occupy, from
+// the first address of the lowest to the last address of the highest. Packing the same blocks perfectly,
+// largest first, would leave no gaps between them and consume exactly their addresses, so a perfectly
+// packed layout scores 1.0 and gaps forced by alignment or scattered allocations lower the score. Used
+// CIDRs outside the root are ignored, and a root with nothing used scores 1.0 since nothing is out of
+// place.
+func PackingEfficiency(rootCIDR *net.IPNet, usedCIDRs []*net.IPNet) float64 {
+	within := []*net.IPNet{}
+	for _, used := range usedCIDRs {
+		if used == nil {
+			continue
+		}
+		if ContainsCIDR(used, rootCIDR) {
+			// a used CIDR covering the root is perfectly packed however it's clipped
+			return 1
+		}
+		if ContainsCIDR(rootCIDR, used) {
+			within = append(within, used)
+		}
+	}
+	blocks := Canonicalize(within)
+	if len(blocks) == 0 {
+		return 1
+	}
+
+	usedAddresses := new(big.Int)
+	for _, block := range blocks {
+		usedAddresses.Add(usedAddresses, BlockSize(&block.Mask))
+	}
+	// the canonical blocks are sorted and disjoint, so the span runs from the first to the end of the last
+	_, last := cidr.AddressRange(blocks[len(blocks)-1])
+	span := new(big.Int).Sub(IPToInt(last), IPToInt(blocks[0].IP))
+	span.Add(span, big.NewInt(1))
+
+	efficiency, _ := new(big.Rat).SetFrac(usedAddresses, span).Float64()
+	return efficiency
+}
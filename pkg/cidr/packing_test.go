@@ -0,0 +1,64 @@
+package cidr_test
+
+import (
+	"net"
+	"testing"
+
+	"github.com/massdriver-cloud/cola/pkg/cidr"
+)
+
+func TestPackingEfficiency(t *testing.T) {
+	type testData struct {
+		name string
+		used []string
+		want float64
+	}
+	tests := []testData{
+		{
+			name: "Perfectly packed",
+			used: []string{"10.0.0.0/26", "10.0.0.64/26", "10.0.0.128/27", "10.0.0.160/27"},
+			want: 1,
+		},
+		{
+			name: "Same blocks scattered",
+			used: []string{"10.0.0.0/27", "10.0.0.64/26", "10.0.0.128/26", "10.0.0.224/27"},
+			want: 0.75,
+		},
+		{
+			name: "Gap forced by alignment",
+			used: []string{"10.0.0.0/27", "10.0.0.64/26"},
+			want: 0.75,
+		},
+		{
+			name: "Packed away from the start of the root",
+			used: []string{"10.0.0.128/26", "10.0.0.192/27"},
+			want: 1,
+		},
+		{
+			name: "Used outside the root is ignored",
+			used: []string{"10.0.0.0/26", "10.0.0.64/26", "10.0.5.0/24"},
+			want: 1,
+		},
+		{
+			name: "Nothing used",
+			used: []string{},
+			want: 1,
+		},
+	}
+
+	_, root, _ := net.ParseCIDR("10.0.0.0/24")
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			usedCIDRs := []*net.IPNet{}
+			for _, used := range tc.used {
+				_, usedCIDR, _ := net.ParseCIDR(used)
+				usedCIDRs = append(usedCIDRs, usedCIDR)
+			}
+
+			got := cidr.PackingEfficiency(root, usedCIDRs)
+			if got != tc.want {
+				t.Fatalf("want: %v, got: %v", tc.want, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,31 @@
+package cidr
+
+import (
+	"fmt"
+	"net"
+	"strings"
+	"unicode"
+)
+
+// ParseCIDRList parses a list of CIDRs separated by any mix of commas, whitespace and newlines, as
+// pasted from a spreadsheet, a terminal or a config file. Empty entries are ignored, and like
+// net.ParseCIDR each CIDR is returned as its network, with any host bits cleared. An
+// ErrInvalidInputRanges error naming the first entry which isn't a CIDR is returned.
+func ParseCIDRList(s string) ([]*net.IPNet, error) {
+	cidrs := []*net.IPNet{}
+	for _, entry := range SplitCIDRList(s) {
+		_, n, err := net.ParseCIDR(entry)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %q is not a valid CIDR", ErrInvalidInputRanges, entry)
+		}
+		cidrs = append(cidrs, n)
+	}
+	return cidrs, nil
+}
+
+// SplitCIDRList splits s into its entries the same way as ParseCIDRList, without parsing them.
+func SplitCIDRList(s string) []string {
+	return strings.FieldsFunc(s, func(r rune) bool {
+		return r == ',' || unicode.IsSpace(r)
+	})
+}
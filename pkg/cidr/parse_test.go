@@ -0,0 +1,75 @@
+package cidr_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/massdriver-cloud/cola/pkg/cidr"
+)
+
+func TestParseCIDRList(t *testing.T) {
+	type testData struct {
+		name      string
+		list      string
+		want      []string
+		wantError error
+	}
+	tests := []testData{
+		{
+			name: "Mixed separators",
+			list: "10.0.0.0/24, 10.0.1.0/24 10.0.2.0/24\n10.0.3.0/24\t2001:db8::/64,\r\n10.0.4.0/24",
+			want: []string{"10.0.0.0/24", "10.0.1.0/24", "10.0.2.0/24", "10.0.3.0/24", "2001:db8::/64", "10.0.4.0/24"},
+		},
+		{
+			name: "Empty entries ignored",
+			list: ",,10.0.0.0/24,, \n\n 10.0.1.0/24,",
+			want: []string{"10.0.0.0/24", "10.0.1.0/24"},
+		},
+		{
+			name: "Host bits cleared",
+			list: "10.0.0.7/24",
+			want: []string{"10.0.0.0/24"},
+		},
+		{
+			name: "Empty",
+			list: " \n ",
+			want: []string{},
+		},
+		{
+			name:      "Error invalid entry",
+			list:      "10.0.0.0/24, 10.0.1.0",
+			wantError: cidr.ErrInvalidInputRanges,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := cidr.ParseCIDRList(tc.list)
+			if tc.wantError != nil {
+				if !errors.Is(err, tc.wantError) {
+					t.Fatalf("Invalid error, want: %v, got %v,", tc.wantError, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %s,", err.Error())
+			}
+			if len(got) != len(tc.want) {
+				t.Fatalf("want: %v, got: %v", tc.want, got)
+			}
+			for i := range got {
+				if got[i].String() != tc.want[i] {
+					t.Fatalf("want: %v, got: %v", tc.want, got)
+				}
+			}
+		})
+	}
+}
+
+func TestParseCIDRListNamesEntry(t *testing.T) {
+	_, err := cidr.ParseCIDRList("10.0.0.0/24 10.0.1.0 10.0.2.0/33")
+	want := `input ranges invalid: "10.0.1.0" is not a valid CIDR`
+	if err == nil || err.Error() != want {
+		t.Fatalf("want: %v, got: %v", want, err)
+	}
+}
@@ -0,0 +1,92 @@
+package cidr
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"net"
+	"sort"
+)
+
+// PoolStrategy is how FindAvailableCIDRInPools chooses between root CIDRs, see WithPoolStrategy.
+type PoolStrategy int
+
+const (
+	// FillFirst allocates from the first pool until it's full, then the next, and so on.
+	FillFirst PoolStrategy = iota
+	// SpreadAcross allocates from the pool with the fewest used CIDRs, so starting from empty pools
+	// allocations go round-robin, and a pool which is behind catches up first.
+	SpreadAcross
+	// MostFree allocates from the pool with the most remaining capacity for the desired mask.
+	MostFree
+)
+
+// WithPoolStrategy sets how FindAvailableCIDRInPools chooses a pool. The default is FillFirst. Ties
+// go to the earlier pool. FindAvailableCIDR ignores it.
+func WithPoolStrategy(strategy PoolStrategy) FindOption {
+	return func(o *findOptions) {
+		o.poolStrategy = strategy
+	}
+}
+
+// FindAvailableCIDRInPools is FindAvailableCIDR across several root CIDRs (pools), such as the
+// supernets of a large deployment. The pools are tried in the order of the WithPoolStrategy option,
+// moving on to the next whenever one has no available CIDR, and the result is returned along with
+// the pool it came from. Each pool only sees the used CIDRs overlapping it, so pools can be from
+// different address families, and pools from a different family than the desiredMask are skipped.
+// The other opts apply to every pool.
+func FindAvailableCIDRInPools(roots []*net.IPNet, desiredMask *net.IPMask, usedCIDRs []*net.IPNet, opts ...FindOption) (*net.IPNet, *net.IPNet, error) {
+	if len(roots) == 0 {
+		return nil, nil, fmt.Errorf("%w: at least one pool is required", ErrInvalidInputRanges)
+	}
+	options := newFindOptions(opts)
+
+	type pool struct {
+		root     *net.IPNet
+		used     []*net.IPNet
+		capacity *big.Int
+	}
+	pools := make([]pool, 0, len(roots))
+	for _, root := range roots {
+		if _, isIPv4 := toIPv4Net(root); !isIPv4 && toIPv6Net(root) == nil {
+			return nil, nil, fmt.Errorf("%w: pool %s is not a valid CIDR", ErrInvalidInputRanges, root.String())
+		} else if _, maskErr := normalizeMask(desiredMask, isIPv4); maskErr != nil {
+			continue
+		}
+		p := pool{root: root, used: []*net.IPNet{}, capacity: new(big.Int)}
+		for _, used := range usedCIDRs {
+			if OverlapsCIDR(root, used) {
+				p.used = append(p.used, used)
+			}
+		}
+		if options.poolStrategy == MostFree {
+			// a pool which can't be counted is still tried last, so its error can be returned
+			if capacity, capacityErr := RemainingCapacity(root, desiredMask, p.used); capacityErr == nil {
+				p.capacity = capacity
+			}
+		}
+		pools = append(pools, p)
+	}
+
+	switch options.poolStrategy {
+	case SpreadAcross:
+		sort.SliceStable(pools, func(i, j int) bool {
+			return len(pools[i].used) < len(pools[j].used)
+		})
+	case MostFree:
+		sort.SliceStable(pools, func(i, j int) bool {
+			return pools[i].capacity.Cmp(pools[j].capacity) > 0
+		})
+	}
+
+	for _, p := range pools {
+		result, err := FindAvailableCIDR(p.root, desiredMask, p.used, opts...)
+		if err == nil {
+			return result, p.root, nil
+		}
+		if !errors.Is(err, ErrNoAvailableCIDR) {
+			return nil, nil, err
+		}
+	}
+	return nil, nil, fmt.Errorf("%w: no pool has space for the requested mask", ErrNoAvailableCIDR)
+}
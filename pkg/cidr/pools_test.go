@@ -0,0 +1,110 @@
+package cidr_test
+
+import (
+	"errors"
+	"net"
+	"testing"
+
+	"github.com/massdriver-cloud/cola/pkg/cidr"
+)
+
+func TestFindAvailableCIDRInPools(t *testing.T) {
+	type testData struct {
+		name      string
+		pools     []string
+		usedCIDRs []string
+		strategy  cidr.PoolStrategy
+		want      []string
+		wantError error
+	}
+	tests := []testData{
+		{
+			name:      "Fill first",
+			pools:     []string{"10.0.0.0/23", "10.1.0.0/24"},
+			usedCIDRs: []string{"10.0.0.0/26"},
+			strategy:  cidr.FillFirst,
+			want:      []string{"10.0.0.64/26", "10.0.0.128/26", "10.0.0.192/26"},
+		},
+		{
+			name:      "Fill first moves on when full",
+			pools:     []string{"10.0.0.0/25", "10.1.0.0/24"},
+			usedCIDRs: []string{"10.0.0.0/26"},
+			strategy:  cidr.FillFirst,
+			want:      []string{"10.0.0.64/26", "10.1.0.0/26", "10.1.0.64/26"},
+		},
+		{
+			name:      "Spread across catches up the emptier pool",
+			pools:     []string{"10.0.0.0/23", "10.1.0.0/24"},
+			usedCIDRs: []string{"10.0.0.0/26"},
+			strategy:  cidr.SpreadAcross,
+			want:      []string{"10.1.0.0/26", "10.0.0.64/26", "10.1.0.64/26"},
+		},
+		{
+			name:      "Most free",
+			pools:     []string{"10.0.0.0/24", "10.1.0.0/23"},
+			usedCIDRs: []string{"10.1.0.0/26", "10.1.0.64/26", "10.1.0.128/26", "10.1.0.192/26", "10.1.1.0/26"},
+			strategy:  cidr.MostFree,
+			want:      []string{"10.0.0.0/26", "10.0.0.64/26", "10.1.1.64/26"},
+		},
+		{
+			name:      "Skips pools of another address family",
+			pools:     []string{"2001:db8::/120", "10.0.0.0/25"},
+			usedCIDRs: []string{"2001:db8::/121"},
+			strategy:  cidr.SpreadAcross,
+			want:      []string{"10.0.0.0/26", "10.0.0.64/26"},
+		},
+		{
+			name:      "No pool of the address family",
+			pools:     []string{"2001:db8::/120"},
+			usedCIDRs: []string{},
+			strategy:  cidr.FillFirst,
+			wantError: cidr.ErrNoAvailableCIDR,
+		},
+		{
+			name:      "Every pool full",
+			pools:     []string{"10.0.0.0/26", "10.1.0.0/26"},
+			usedCIDRs: []string{"10.0.0.0/26", "10.1.0.0/26"},
+			strategy:  cidr.SpreadAcross,
+			wantError: cidr.ErrNoAvailableCIDR,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			pools := make([]*net.IPNet, len(tc.pools))
+			for i, pool := range tc.pools {
+				_, pool, _ := net.ParseCIDR(pool)
+				pools[i] = pool
+			}
+			usedCIDRs := make([]*net.IPNet, len(tc.usedCIDRs))
+			for i, usedCIDR := range tc.usedCIDRs {
+				_, usedCIDR, _ := net.ParseCIDR(usedCIDR)
+				usedCIDRs[i] = usedCIDR
+			}
+			mask := net.CIDRMask(26, 32)
+
+			if tc.wantError != nil {
+				_, _, err := cidr.FindAvailableCIDRInPools(pools, &mask, usedCIDRs, cidr.WithPoolStrategy(tc.strategy))
+				if !errors.Is(err, tc.wantError) {
+					t.Fatalf("Invalid error, want: %v, got %v,", tc.wantError, err)
+				}
+				return
+			}
+
+			// allocate several in a row to see how the strategy places them
+			for _, want := range tc.want {
+				got, pool, err := cidr.FindAvailableCIDRInPools(pools, &mask, usedCIDRs, cidr.WithPoolStrategy(tc.strategy))
+				if err != nil {
+					t.Fatalf("Unexpected error: %s,", err.Error())
+				}
+				if got.String() != want {
+					t.Fatalf("want: %v, got: %v", want, got.String())
+				}
+				if !cidr.ContainsCIDR(pool, got) {
+					t.Fatalf("want: %v within %v, got: outside", got.String(), pool.String())
+				}
+				usedCIDRs = append(usedCIDRs, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,97 @@
+package cidr
+
+import (
+	"fmt"
+	"net"
+	"sync"
+)
+
+// PreparedFinder answers repeated finds against the same root CIDR and used CIDRs, such as a server
+// handling many requests of different sizes. The free space is computed once when the finder is built,
+// and again only when the used CIDRs change, so each Find is a single pass over the free blocks rather
+// than a walk of the tree checking every used CIDR. Results are the same as FindAvailableCIDR without
+// any FindOptions. It's safe for concurrent use.
+type PreparedFinder struct {
+	mu      sync.RWMutex
+	root    *net.IPNet
+	isIPv4  bool
+	free    []*net.IPNet
+	rootErr error
+}
+
+// NewPreparedFinder returns a PreparedFinder for the rootCIDR and usedCIDRs. Like FindAvailableCIDR,
+// an ErrInvalidInputRanges error is returned if the root CIDR has host bits set or the used CIDRs are
+// from a different address family.
+func NewPreparedFinder(rootCIDR *net.IPNet, usedCIDRs []*net.IPNet) (*PreparedFinder, error) {
+	root, _, _, err := normalizeInputs(rootCIDR, &rootCIDR.Mask, nil)
+	if err != nil {
+		return nil, err
+	}
+	if _, _, err = canonicalizeInputs(root, nil, false); err != nil {
+		return nil, err
+	}
+	_, isIPv4 := toIPv4Net(root)
+	f := &PreparedFinder{root: root, isIPv4: isIPv4}
+	if err = f.Update(usedCIDRs); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+// Update replaces the used CIDRs and rebuilds the free space.
+func (f *PreparedFinder) Update(usedCIDRs []*net.IPNet) error {
+	_, _, used, err := normalizeInputs(f.root, &f.root.Mask, usedCIDRs)
+	if err != nil {
+		return err
+	}
+
+	var rootErr error
+	for _, u := range used {
+		if ContainsCIDR(u, f.root) {
+			rootErr = ErrRootInsideUsed
+			if EqualMask(&f.root.Mask, &u.Mask) {
+				rootErr = ErrRootFullyUsed
+			}
+			break
+		}
+	}
+	free, err := FreeSpace(f.root, used)
+	if err != nil {
+		return err
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.free = free
+	f.rootErr = rootErr
+	return nil
+}
+
+// Find returns the lowest available CIDR of the desiredMask size.
+func (f *PreparedFinder) Find(desiredMask *net.IPMask) (*net.IPNet, error) {
+	mask, err := normalizeMask(desiredMask, f.isIPv4)
+	if err != nil {
+		return nil, err
+	}
+
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	if f.rootErr != nil {
+		return nil, f.rootErr
+	}
+	if SmallerMask(&f.root.Mask, mask) {
+		return nil, fmt.Errorf("%w: desired mask is larger than the root CIDR range", ErrNoAvailableCIDR)
+	}
+
+	// the free blocks are the largest aligned blocks covering the free space, so any available CIDR is
+	// within one of them, and the first one at least as large starts with the lowest
+	prefix := BlockSizePrefix(mask)
+	for _, block := range f.free {
+		if BlockSizePrefix(&block.Mask) <= prefix {
+			ip := make(net.IP, len(block.IP))
+			copy(ip, block.IP)
+			return &net.IPNet{IP: ip, Mask: *mask}, nil
+		}
+	}
+	return nil, fmt.Errorf("%w: searched all available ranges could not find space for requested mask", ErrNoAvailableCIDR)
+}
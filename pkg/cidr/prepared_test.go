@@ -0,0 +1,218 @@
+package cidr_test
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"testing"
+
+	"github.com/massdriver-cloud/cola/pkg/cidr"
+)
+
+func TestPreparedFinder(t *testing.T) {
+	type testData struct {
+		name      string
+		rootCIDR  string
+		usedCIDRs []string
+	}
+	tests := []testData{
+		{
+			name:      "Documented example",
+			rootCIDR:  "10.0.0.0/16",
+			usedCIDRs: []string{"10.0.0.0/18", "10.0.64.0/20", "10.0.80.0/24"},
+		},
+		{
+			name:      "Scattered",
+			rootCIDR:  "10.0.0.0/20",
+			usedCIDRs: []string{"10.0.0.64/26", "10.0.1.0/24", "10.0.3.128/25", "10.0.8.0/22"},
+		},
+		{
+			name:      "Used outside the root",
+			rootCIDR:  "10.0.0.0/22",
+			usedCIDRs: []string{"10.0.0.0/24", "10.1.0.0/16"},
+		},
+		{
+			name:      "Unused",
+			rootCIDR:  "10.0.0.0/24",
+			usedCIDRs: []string{},
+		},
+		{
+			name:      "IPv6",
+			rootCIDR:  "2001:db8::/48",
+			usedCIDRs: []string{"2001:db8::/64", "2001:db8:0:2::/63"},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			_, rootCIDR, _ := net.ParseCIDR(tc.rootCIDR)
+			usedCIDRs := make([]*net.IPNet, len(tc.usedCIDRs))
+			for i, usedCIDR := range tc.usedCIDRs {
+				_, usedCIDR, _ := net.ParseCIDR(usedCIDR)
+				usedCIDRs[i] = usedCIDR
+			}
+			finder, err := cidr.NewPreparedFinder(rootCIDR, usedCIDRs)
+			if err != nil {
+				t.Fatalf("Unexpected error: %s,", err.Error())
+			}
+
+			// every size gives the same result as FindAvailableCIDR
+			rootOnes, bits := rootCIDR.Mask.Size()
+			for ones := rootOnes; ones <= rootOnes+12 && ones <= bits; ones++ {
+				mask := net.CIDRMask(ones, bits)
+				want, wantErr := cidr.FindAvailableCIDR(rootCIDR, &mask, usedCIDRs)
+				got, gotErr := finder.Find(&mask)
+				if wantErr != nil {
+					if !errors.Is(gotErr, cidr.ErrNoAvailableCIDR) {
+						t.Fatalf("Invalid error, want: %v, got %v,", wantErr, gotErr)
+					}
+					continue
+				}
+				if gotErr != nil {
+					t.Fatalf("Unexpected error: %s,", gotErr.Error())
+				}
+				if got.String() != want.String() {
+					t.Fatalf("want: %v, got: %v", want.String(), got.String())
+				}
+			}
+		})
+	}
+}
+
+func TestPreparedFinderUpdate(t *testing.T) {
+	_, rootCIDR, _ := net.ParseCIDR("10.0.0.0/16")
+	_, usedCIDR, _ := net.ParseCIDR("10.0.0.0/24")
+	mask := net.CIDRMask(24, 32)
+
+	finder, err := cidr.NewPreparedFinder(rootCIDR, []*net.IPNet{})
+	if err != nil {
+		t.Fatalf("Unexpected error: %s,", err.Error())
+	}
+	got, err := finder.Find(&mask)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s,", err.Error())
+	}
+	if got.String() != "10.0.0.0/24" {
+		t.Fatalf("want: %v, got: %v", "10.0.0.0/24", got.String())
+	}
+
+	if err = finder.Update([]*net.IPNet{usedCIDR}); err != nil {
+		t.Fatalf("Unexpected error: %s,", err.Error())
+	}
+	got, err = finder.Find(&mask)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s,", err.Error())
+	}
+	if got.String() != "10.0.1.0/24" {
+		t.Fatalf("want: %v, got: %v", "10.0.1.0/24", got.String())
+	}
+
+	if err = finder.Update([]*net.IPNet{rootCIDR}); err != nil {
+		t.Fatalf("Unexpected error: %s,", err.Error())
+	}
+	if _, err = finder.Find(&mask); !errors.Is(err, cidr.ErrRootFullyUsed) {
+		t.Fatalf("Invalid error, want: %v, got %v,", cidr.ErrRootFullyUsed, err)
+	}
+}
+
+func TestPreparedFinderErrors(t *testing.T) {
+	type testData struct {
+		name      string
+		rootCIDR  string
+		usedCIDRs []string
+		mask      net.IPMask
+		wantError error
+	}
+	tests := []testData{
+		{
+			name:      "Root has host bits set",
+			rootCIDR:  "10.0.5.0/16",
+			usedCIDRs: []string{},
+			mask:      net.CIDRMask(24, 32),
+			wantError: cidr.ErrInvalidInputRanges,
+		},
+		{
+			name:      "Mixed families",
+			rootCIDR:  "10.0.0.0/16",
+			usedCIDRs: []string{"2001:db8::/64"},
+			mask:      net.CIDRMask(24, 32),
+			wantError: cidr.ErrInvalidInputRanges,
+		},
+		{
+			name:      "Mask from another family",
+			rootCIDR:  "10.0.0.0/16",
+			usedCIDRs: []string{},
+			mask:      net.CIDRMask(64, 128),
+			wantError: cidr.ErrInvalidInputRanges,
+		},
+		{
+			name:      "Root inside used",
+			rootCIDR:  "10.0.0.0/16",
+			usedCIDRs: []string{"10.0.0.0/8"},
+			mask:      net.CIDRMask(24, 32),
+			wantError: cidr.ErrRootInsideUsed,
+		},
+		{
+			name:      "Mask larger than root",
+			rootCIDR:  "10.0.0.0/16",
+			usedCIDRs: []string{},
+			mask:      net.CIDRMask(15, 32),
+			wantError: cidr.ErrNoAvailableCIDR,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			rootIP, rootNet, _ := net.ParseCIDR(tc.rootCIDR)
+			rootCIDR := &net.IPNet{IP: rootIP, Mask: rootNet.Mask}
+			usedCIDRs := make([]*net.IPNet, len(tc.usedCIDRs))
+			for i, usedCIDR := range tc.usedCIDRs {
+				_, usedCIDR, _ := net.ParseCIDR(usedCIDR)
+				usedCIDRs[i] = usedCIDR
+			}
+			finder, err := cidr.NewPreparedFinder(rootCIDR, usedCIDRs)
+			if err == nil {
+				_, err = finder.Find(&tc.mask)
+			}
+			if !errors.Is(err, tc.wantError) {
+				t.Fatalf("Invalid error, want: %v, got %v,", tc.wantError, err)
+			}
+		})
+	}
+}
+
+// benchmarkUsed returns a /8 root with every other /24 of its first /14 used
+func benchmarkUsed() (*net.IPNet, []*net.IPNet) {
+	_, rootCIDR, _ := net.ParseCIDR("10.0.0.0/8")
+	usedCIDRs := []*net.IPNet{}
+	for i := 0; i < 1024; i += 2 {
+		_, usedCIDR, _ := net.ParseCIDR(fmt.Sprintf("10.%d.%d.0/24", i/256, i%256))
+		usedCIDRs = append(usedCIDRs, usedCIDR)
+	}
+	return rootCIDR, usedCIDRs
+}
+
+func BenchmarkFindAvailableCIDRRepeated(b *testing.B) {
+	rootCIDR, usedCIDRs := benchmarkUsed()
+	for i := 0; i < b.N; i++ {
+		mask := net.CIDRMask(20+i%9, 32)
+		if _, err := cidr.FindAvailableCIDR(rootCIDR, &mask, usedCIDRs); err != nil {
+			b.Fatalf("Unexpected error: %s,", err.Error())
+		}
+	}
+}
+
+func BenchmarkPreparedFinderRepeated(b *testing.B) {
+	rootCIDR, usedCIDRs := benchmarkUsed()
+	finder, err := cidr.NewPreparedFinder(rootCIDR, usedCIDRs)
+	if err != nil {
+		b.Fatalf("Unexpected error: %s,", err.Error())
+	}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		mask := net.CIDRMask(20+i%9, 32)
+		if _, err = finder.Find(&mask); err != nil {
+			b.Fatalf("Unexpected error: %s,", err.Error())
+		}
+	}
+}
@@ -0,0 +1,79 @@
+package cidr
+
+import "net"
+
+// Scorer rates a candidate CIDR for WithScorer. Lower scores are better.
+type Scorer func(candidate *net.IPNet) int
+
+// WithScorer makes FindAvailableCIDR evaluate every available CIDR with the scorer and return the one
+// with the lowest score, instead of the first one found. Ties go to the candidate found first, in the
+// usual ascending search order. Scoring every candidate of a small mask in a large root is expensive,
+// see WithCandidateLimit to bound it.
+func WithScorer(scorer Scorer) FindOption {
+	return func(o *findOptions) {
+		o.scorer = scorer
+	}
+}
+
+// WithCandidateLimit stops a WithScorer search after limit candidates have been scored, returning the
+// best of those. This trades the quality of the result for bounded latency: a limit of 1 returns the
+// first available CIDR, the same as a search without a scorer. By default every candidate is scored.
+func WithCandidateLimit(limit int) FindOption {
+	return func(o *findOptions) {
+		o.candidateLimit = limit
+	}
+}
+
+// WithMinimizeRoutes makes FindAvailableCIDR prefer the result which leaves the fewest routes to
+// advertise, keeping route tables compact: each available CIDR is scored by the length of Aggregate
+// over the used CIDRs plus the candidate, so a result merging with an existing block beats an isolated
+// one. Like WithScorer, every candidate is scored unless WithCandidateLimit is set, and each score
+// aggregates every used CIDR, so this is best kept to roots with few candidates.
+func WithMinimizeRoutes() FindOption {
+	return func(o *findOptions) {
+		o.minimizeRoutes = true
+	}
+}
+
+// applyMinimizeRoutes sets the scorer to count the routes left by each candidate, if WithMinimizeRoutes
+// is set.
+func (o *findOptions) applyMinimizeRoutes(usedCIDRs []*net.IPNet) {
+	if !o.minimizeRoutes {
+		return
+	}
+	o.scorer = func(candidate *net.IPNet) int {
+		withCandidate := make([]*net.IPNet, 0, len(usedCIDRs)+1)
+		withCandidate = append(withCandidate, usedCIDRs...)
+		withCandidate = append(withCandidate, candidate)
+		// the inputs are canonical CIDRs of one address family, so aggregating them can't fail
+		routes, _ := Aggregate(withCandidate)
+		return len(routes)
+	}
+}
+
+// scoredSearch tracks the best candidate of a WithScorer search.
+type scoredSearch struct {
+	best      *net.IPNet
+	bestScore int
+	scored    int
+}
+
+// applyScorer adds a candidate filter scoring each candidate, and returns the search state to read the
+// best candidate from once the walk is done, or nil if there's no scorer. The filter rejects every
+// candidate to keep the walk going until the candidate limit is reached.
+func (o *findOptions) applyScorer() *scoredSearch {
+	if o.scorer == nil {
+		return nil
+	}
+	search := &scoredSearch{}
+	o.candidateFilters = append(o.candidateFilters, func(candidate *net.IPNet) bool {
+		score := o.scorer(candidate)
+		if search.best == nil || score < search.bestScore {
+			search.best = candidate
+			search.bestScore = score
+		}
+		search.scored++
+		return o.candidateLimit > 0 && search.scored >= o.candidateLimit
+	})
+	return search
+}
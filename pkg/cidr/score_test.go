@@ -0,0 +1,117 @@
+package cidr_test
+
+import (
+	"net"
+	"testing"
+
+	"github.com/massdriver-cloud/cola/pkg/cidr"
+)
+
+func TestFindAvailableCIDRWithScorer(t *testing.T) {
+	type testData struct {
+		name       string
+		limit      int
+		want       string
+		wantScored int
+	}
+	tests := []testData{
+		{
+			name:       "Unlimited",
+			limit:      0,
+			want:       "10.0.255.0/24",
+			wantScored: 255,
+		},
+		{
+			name:       "Limit of one is first-fit",
+			limit:      1,
+			want:       "10.0.1.0/24",
+			wantScored: 1,
+		},
+		{
+			name:       "Best of the first four",
+			limit:      4,
+			want:       "10.0.4.0/24",
+			wantScored: 4,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			_, baseCIDR, _ := net.ParseCIDR("10.0.0.0/16")
+			_, used, _ := net.ParseCIDR("10.0.0.0/24")
+			mask := net.CIDRMask(24, 32)
+
+			// prefer the highest address, the opposite of the default search order
+			scored := 0
+			scorer := func(candidate *net.IPNet) int {
+				scored++
+				return -int(candidate.IP.To4()[2])
+			}
+			got, err := cidr.FindAvailableCIDR(baseCIDR, &mask, []*net.IPNet{used}, cidr.WithScorer(scorer), cidr.WithCandidateLimit(tc.limit))
+			if err != nil {
+				t.Fatalf("Unexpected error: %s,", err.Error())
+			}
+			if got.String() != tc.want {
+				t.Fatalf("want: %v, got: %v", tc.want, got.String())
+			}
+			// the number of candidates scored bounds the cost of the search
+			if scored != tc.wantScored {
+				t.Fatalf("want: %v, got: %v", tc.wantScored, scored)
+			}
+		})
+	}
+}
+
+func TestFindAvailableCIDRWithMinimizeRoutes(t *testing.T) {
+	type testData struct {
+		name      string
+		baseCIDR  string
+		usedCIDRs []string
+		mask      net.IPMask
+		opts      []cidr.FindOption
+		want      string
+	}
+	tests := []testData{
+		{
+			name:      "First fit is isolated",
+			baseCIDR:  "10.0.0.0/22",
+			usedCIDRs: []string{"10.0.3.0/24", "10.0.2.128/25"},
+			mask:      net.CIDRMask(25, 32),
+			want:      "10.0.0.0/25",
+		},
+		{
+			name:      "Prefers merging with an existing block",
+			baseCIDR:  "10.0.0.0/22",
+			usedCIDRs: []string{"10.0.3.0/24", "10.0.2.128/25"},
+			mask:      net.CIDRMask(25, 32),
+			opts:      []cidr.FindOption{cidr.WithMinimizeRoutes()},
+			want:      "10.0.2.0/25",
+		},
+		{
+			name:      "Ties go to the lowest address",
+			baseCIDR:  "10.0.0.0/22",
+			usedCIDRs: []string{"10.0.1.0/24", "10.0.3.0/24"},
+			mask:      net.CIDRMask(24, 32),
+			opts:      []cidr.FindOption{cidr.WithMinimizeRoutes()},
+			want:      "10.0.0.0/24",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			_, baseCIDR, _ := net.ParseCIDR(tc.baseCIDR)
+			usedCIDRs := make([]*net.IPNet, len(tc.usedCIDRs))
+			for i, usedCIDR := range tc.usedCIDRs {
+				_, usedCIDR, _ := net.ParseCIDR(usedCIDR)
+				usedCIDRs[i] = usedCIDR
+			}
+			got, err := cidr.FindAvailableCIDR(baseCIDR, &tc.mask, usedCIDRs, tc.opts...)
+			if err != nil {
+				t.Fatalf("Unexpected error: %s,", err.Error())
+			}
+			if got.String() != tc.want {
+				t.Fatalf("want: %v, got: %v", tc.want, got.String())
+			}
+		})
+	}
+}
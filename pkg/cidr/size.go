@@ -0,0 +1,29 @@
+package cidr
+
+import (
+	"math/big"
+	"net"
+)
+
+// BlockSize returns the number of addresses in a block with the given mask (2^(bits-ones)).
+// A /24 holds 256 addresses, while an IPv6 /64 holds 2^64.
+func BlockSize(mask *net.IPMask) *big.Int {
+	ones, bits := mask.Size()
+	return new(big.Int).Lsh(big.NewInt(1), uint(bits-ones))
+}
+
+// BlockSizePrefix returns the prefix length of the mask, e.g. 24 for 255.255.255.0.
+func BlockSizePrefix(mask *net.IPMask) int {
+	ones, _ := mask.Size()
+	return ones
+}
+
+// MaskDistribution returns the number of CIDRs with each prefix length, so [10.0.0.0/24, 10.0.1.0/24,
+// 10.0.4.0/22] is {24: 2, 22: 1}.
+func MaskDistribution(cidrs []*net.IPNet) map[int]int {
+	distribution := map[int]int{}
+	for _, n := range cidrs {
+		distribution[BlockSizePrefix(&n.Mask)]++
+	}
+	return distribution
+}
@@ -0,0 +1,81 @@
+package cidr_test
+
+import (
+	"net"
+	"testing"
+
+	"github.com/massdriver-cloud/cola/pkg/cidr"
+)
+
+func TestBlockSize(t *testing.T) {
+	type testData struct {
+		name       string
+		mask       net.IPMask
+		want       string
+		wantPrefix int
+	}
+	tests := []testData{
+		{
+			name:       "IPv4 /24",
+			mask:       net.CIDRMask(24, 32),
+			want:       "256",
+			wantPrefix: 24,
+		},
+		{
+			name:       "IPv4 /30",
+			mask:       net.CIDRMask(30, 32),
+			want:       "4",
+			wantPrefix: 30,
+		},
+		{
+			name:       "IPv4 /32",
+			mask:       net.CIDRMask(32, 32),
+			want:       "1",
+			wantPrefix: 32,
+		},
+		{
+			name:       "IPv4 /0",
+			mask:       net.CIDRMask(0, 32),
+			want:       "4294967296",
+			wantPrefix: 0,
+		},
+		{
+			name:       "IPv6 /64",
+			mask:       net.CIDRMask(64, 128),
+			want:       "18446744073709551616",
+			wantPrefix: 64,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got := cidr.BlockSize(&tc.mask)
+			if got.String() != tc.want {
+				t.Fatalf("want: %v, got: %v", tc.want, got.String())
+			}
+			gotPrefix := cidr.BlockSizePrefix(&tc.mask)
+			if gotPrefix != tc.wantPrefix {
+				t.Fatalf("want: %v, got: %v", tc.wantPrefix, gotPrefix)
+			}
+		})
+	}
+}
+
+func TestMaskDistribution(t *testing.T) {
+	cidrs := []*net.IPNet{}
+	for _, value := range []string{"10.0.0.0/24", "10.0.4.0/22", "10.0.1.0/24", "10.0.2.0/28"} {
+		_, n, _ := net.ParseCIDR(value)
+		cidrs = append(cidrs, n)
+	}
+
+	got := cidr.MaskDistribution(cidrs)
+	want := map[int]int{22: 1, 24: 2, 28: 1}
+	if len(got) != len(want) {
+		t.Fatalf("want: %v, got: %v", want, got)
+	}
+	for prefix, count := range want {
+		if got[prefix] != count {
+			t.Fatalf("want: %v, got: %v", want, got)
+		}
+	}
+}
@@ -0,0 +1,77 @@
+package cidr
+
+import (
+	"fmt"
+	"math/big"
+	"math/bits"
+	"net"
+)
+
+// SplitByRatio partitions parent into equal sized, aligned unit blocks shared out by the integer
+// ratios. The parent is divided into the smallest power of two number of units which is at least the
+// sum of the ratios, and each ratio in turn is given that many consecutive units. One group of units
+// is returned per ratio, in ascending order, so for a /16 and ratios [3, 1] the result is the first
+// three /18s for the 3 and the last /18 for the 1. When the ratios don't sum to a power of two the
+// trailing units are left unallocated, so [1, 1, 1] returns three groups of one /18 each.
+func SplitByRatio(parent *net.IPNet, ratios []int) ([][]*net.IPNet, error) {
+	if len(ratios) == 0 {
+		return nil, fmt.Errorf("%w: at least one ratio is required", ErrInvalidInputRanges)
+	}
+	total := uint(0)
+	for _, ratio := range ratios {
+		if ratio < 1 {
+			return nil, fmt.Errorf("%w: ratios must be at least 1, got %d", ErrInvalidInputRanges, ratio)
+		}
+		total += uint(ratio)
+	}
+
+	// the number of bits needed to count total units, rounding up to a power of two
+	extraBits := bits.Len(total - 1)
+	ones, addressBits := parent.Mask.Size()
+	if ones+extraBits > addressBits {
+		return nil, fmt.Errorf("%w: %s is too small to split into %d parts", ErrInvalidInputRanges, parent.String(), total)
+	}
+
+	mask := net.CIDRMask(ones+extraBits, addressBits)
+	unitSize := BlockSize(&mask)
+	start, _ := CIDRToIntRange(parent)
+	groups := make([][]*net.IPNet, len(ratios))
+	unit := uint(0)
+	for r, ratio := range ratios {
+		groups[r] = make([]*net.IPNet, 0, ratio)
+		for i := 0; i < ratio; i++ {
+			first := new(big.Int).Mul(unitSize, new(big.Int).SetUint64(uint64(unit)))
+			first.Add(first, start)
+			ip, err := IntToIP(first, addressBits)
+			if err != nil {
+				return nil, err
+			}
+			groups[r] = append(groups[r], &net.IPNet{IP: ip, Mask: mask})
+			unit++
+		}
+	}
+	return groups, nil
+}
+
+// SplitByPrefix returns every prefix sized block of parent in ascending order, so a /16 split by 18
+// returns four /18s. An error is returned if prefix is shorter than the parent's prefix or longer than
+// the address length.
+func SplitByPrefix(parent *net.IPNet, prefix int) ([]*net.IPNet, error) {
+	ones, addressBits := parent.Mask.Size()
+	if prefix < ones || prefix > addressBits {
+		return nil, fmt.Errorf("%w: /%d blocks don't fit in %s", ErrInvalidInputRanges, prefix, parent.String())
+	}
+
+	mask := net.CIDRMask(prefix, addressBits)
+	unitSize := BlockSize(&mask)
+	current, last := CIDRToIntRange(parent)
+	blocks := []*net.IPNet{}
+	for ; current.Cmp(last) <= 0; current = new(big.Int).Add(current, unitSize) {
+		ip, err := IntToIP(current, addressBits)
+		if err != nil {
+			return nil, err
+		}
+		blocks = append(blocks, &net.IPNet{IP: ip, Mask: mask})
+	}
+	return blocks, nil
+}
@@ -0,0 +1,158 @@
+package cidr_test
+
+import (
+	"errors"
+	"net"
+	"testing"
+
+	"github.com/massdriver-cloud/cola/pkg/cidr"
+)
+
+func TestSplitByRatio(t *testing.T) {
+	type testData struct {
+		name      string
+		parent    string
+		ratios    []int
+		want      [][]string
+		wantError error
+	}
+	tests := []testData{
+		{
+			name:   "Halves",
+			parent: "10.0.0.0/16",
+			ratios: []int{1, 1},
+			want:   [][]string{{"10.0.0.0/17"}, {"10.0.128.0/17"}},
+		},
+		{
+			name:   "Three to one",
+			parent: "10.0.0.0/16",
+			ratios: []int{3, 1},
+			want:   [][]string{{"10.0.0.0/18", "10.0.64.0/18", "10.0.128.0/18"}, {"10.0.192.0/18"}},
+		},
+		{
+			name:   "Quarters",
+			parent: "10.0.0.0/16",
+			ratios: []int{1, 1, 1, 1},
+			want:   [][]string{{"10.0.0.0/18"}, {"10.0.64.0/18"}, {"10.0.128.0/18"}, {"10.0.192.0/18"}},
+		},
+		{
+			name:   "Thirds leave a unit free",
+			parent: "10.0.0.0/16",
+			ratios: []int{1, 1, 1},
+			want:   [][]string{{"10.0.0.0/18"}, {"10.0.64.0/18"}, {"10.0.128.0/18"}},
+		},
+		{
+			name:   "Whole",
+			parent: "10.0.0.0/16",
+			ratios: []int{5},
+			want:   [][]string{{"10.0.0.0/19", "10.0.32.0/19", "10.0.64.0/19", "10.0.96.0/19", "10.0.128.0/19"}},
+		},
+		{
+			name:   "Uneven groups",
+			parent: "10.0.0.0/16",
+			ratios: []int{1, 2, 1},
+			want:   [][]string{{"10.0.0.0/18"}, {"10.0.64.0/18", "10.0.128.0/18"}, {"10.0.192.0/18"}},
+		},
+		{
+			name:      "Error zero ratio",
+			parent:    "10.0.0.0/16",
+			ratios:    []int{1, 0},
+			wantError: cidr.ErrInvalidInputRanges,
+		},
+		{
+			name:      "Error too small",
+			parent:    "10.0.0.0/31",
+			ratios:    []int{1, 1, 1},
+			wantError: cidr.ErrInvalidInputRanges,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			_, parent, _ := net.ParseCIDR(tc.parent)
+			got, err := cidr.SplitByRatio(parent, tc.ratios)
+			if tc.wantError != nil {
+				if !errors.Is(err, tc.wantError) {
+					t.Fatalf("Invalid error, want: %v, got %v,", tc.wantError, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %s,", err.Error())
+			}
+			if len(got) != len(tc.want) {
+				t.Fatalf("want: %v, got: %v", tc.want, got)
+			}
+			for i := range got {
+				if len(got[i]) != len(tc.want[i]) {
+					t.Fatalf("want: %v, got: %v", tc.want[i], got[i])
+				}
+				for j := range got[i] {
+					if got[i][j].String() != tc.want[i][j] {
+						t.Fatalf("want: %v, got: %v", tc.want[i][j], got[i][j].String())
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestSplitByPrefix(t *testing.T) {
+	type testData struct {
+		name      string
+		parent    string
+		prefix    int
+		want      []string
+		wantError error
+	}
+	tests := []testData{
+		{
+			name:   "Quarters",
+			parent: "10.0.0.0/16",
+			prefix: 18,
+			want:   []string{"10.0.0.0/18", "10.0.64.0/18", "10.0.128.0/18", "10.0.192.0/18"},
+		},
+		{
+			name:   "Same size",
+			parent: "10.0.0.0/16",
+			prefix: 16,
+			want:   []string{"10.0.0.0/16"},
+		},
+		{
+			name:   "Top of the address space",
+			parent: "255.255.255.252/30",
+			prefix: 31,
+			want:   []string{"255.255.255.252/31", "255.255.255.254/31"},
+		},
+		{
+			name:      "Error prefix too short",
+			parent:    "10.0.0.0/16",
+			prefix:    8,
+			wantError: cidr.ErrInvalidInputRanges,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			_, parent, _ := net.ParseCIDR(tc.parent)
+			got, err := cidr.SplitByPrefix(parent, tc.prefix)
+			if tc.wantError != nil {
+				if !errors.Is(err, tc.wantError) {
+					t.Fatalf("Invalid error, want: %v, got %v,", tc.wantError, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %s,", err.Error())
+			}
+			if len(got) != len(tc.want) {
+				t.Fatalf("want: %v, got: %v", tc.want, got)
+			}
+			for i := range got {
+				if got[i].String() != tc.want[i] {
+					t.Fatalf("want: %v, got: %v", tc.want[i], got[i].String())
+				}
+			}
+		})
+	}
+}
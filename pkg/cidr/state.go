@@ -0,0 +1,115 @@
+package cidr
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+)
+
+// allocatorState is the JSON state file of an Allocator. Generation counts the saves, so a writer can
+// tell whether the file has changed since it was loaded.
+type allocatorState struct {
+	Generation int64    `json:"generation"`
+	Root       string   `json:"root"`
+	Used       []string `json:"used"`
+}
+
+// LoadAllocator returns an Allocator with the root and used CIDRs of the state file at path, as saved by
+// SaveAtomic. The opts are applied to every reservation, they aren't part of the saved state.
+func LoadAllocator(path string, opts ...FindOption) (*Allocator, error) {
+	state, err := readAllocatorState(path)
+	if err != nil {
+		return nil, err
+	}
+	_, root, err := net.ParseCIDR(state.Root)
+	if err != nil {
+		return nil, fmt.Errorf("%w: invalid root CIDR in %s: %s", ErrInvalidInputRanges, path, err.Error())
+	}
+	used := make([]*net.IPNet, len(state.Used))
+	for i, value := range state.Used {
+		_, n, parseErr := net.ParseCIDR(value)
+		if parseErr != nil {
+			return nil, fmt.Errorf("%w: invalid used CIDR in %s: %s", ErrInvalidInputRanges, path, parseErr.Error())
+		}
+		used[i] = n
+	}
+
+	allocator := NewAllocator(root, used, opts...)
+	allocator.generation = state.Generation
+	return allocator, nil
+}
+
+// SaveAtomic writes the allocator's state to path by writing a temporary file next to it and renaming it
+// into place, so readers never see a partially written file. Each save bumps the generation recorded in
+// the file. If the file's generation no longer matches the one this allocator loaded or last saved,
+// another writer has saved newer state, and an ErrStateConflict error is returned rather than
+// overwriting it. Load the file again to pick up their changes. This detects stale writers, but
+// doesn't lock the file, so two writers saving at the same instant can still race.
+func (a *Allocator) SaveAtomic(path string) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	current, err := readAllocatorState(path)
+	switch {
+	case errors.Is(err, os.ErrNotExist):
+		current = &allocatorState{}
+	case err != nil:
+		return err
+	}
+	if current.Generation != a.generation {
+		return fmt.Errorf("%w: %s is at generation %d, but the allocator has generation %d", ErrStateConflict, path, current.Generation, a.generation)
+	}
+
+	state := allocatorState{Generation: a.generation + 1, Root: a.root.String(), Used: []string{}}
+	for _, used := range a.sortedUsed() {
+		state.Used = append(state.Used, used.String())
+	}
+	data, err := json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err = writeFileAtomic(path, append(data, '\n')); err != nil {
+		return err
+	}
+	a.generation = state.Generation
+	return nil
+}
+
+// readAllocatorState reads the state file at path
+func readAllocatorState(path string) (*allocatorState, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	state := &allocatorState{}
+	if err = json.Unmarshal(data, state); err != nil {
+		return nil, fmt.Errorf("%w: invalid state file %s: %s", ErrInvalidInputRanges, path, err.Error())
+	}
+	return state, nil
+}
+
+// writeFileAtomic writes data to a temporary file in the same directory as path, then renames it over
+// path. The temporary file is removed if anything fails.
+func writeFileAtomic(path string, data []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err = tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err = tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err = tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
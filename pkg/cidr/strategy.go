@@ -0,0 +1,124 @@
+package cidr
+
+import (
+	"fmt"
+	"math/big"
+	"net"
+)
+
+// Strategy is how CompareStrategies places each request within the root CIDR.
+type Strategy int
+
+const (
+	// FirstFit places each request at the lowest available address, the same as FindAvailableCIDR.
+	FirstFit Strategy = iota
+	// BestFit places each request in the smallest free block it fits in, keeping larger blocks intact.
+	BestFit
+	// Spread places each request in the largest free block, spreading allocations across the root.
+	Spread
+)
+
+// String returns the name of the strategy, such as first-fit.
+func (s Strategy) String() string {
+	switch s {
+	case FirstFit:
+		return "first-fit"
+	case BestFit:
+		return "best-fit"
+	case Spread:
+		return "spread"
+	default:
+		return fmt.Sprintf("Strategy(%d)", int(s))
+	}
+}
+
+// StrategyResult is the state of the root CIDR after CompareStrategies replays the requests with a
+// strategy. Fragmentation is 1 minus the size of the largest free block over the free addresses, so 0
+// when the free space is a single block (or there's none) and close to 1 when it's in many small
+// pieces. LargestBlock is nil if the root is fully used.
+type StrategyResult struct {
+	Fragmentation float64
+	LargestBlock  *net.IPNet
+	Failures      int
+}
+
+// CompareStrategies replays the same sequence of requests against the rootCIDR and usedCIDRs with each
+// of the strategies, and reports how each one leaves the root. A request which can't be placed counts
+// as a failure and the replay carries on with the next one.
+func CompareStrategies(rootCIDR *net.IPNet, requests []*net.IPMask, strategies []Strategy, usedCIDRs []*net.IPNet) map[Strategy]StrategyResult {
+	results := make(map[Strategy]StrategyResult, len(strategies))
+	for _, strategy := range strategies {
+		used := append([]*net.IPNet{}, usedCIDRs...)
+		result := StrategyResult{}
+		for _, request := range requests {
+			placed, err := placeWithStrategy(strategy, rootCIDR, request, used)
+			if err != nil {
+				result.Failures++
+				continue
+			}
+			used = append(used, placed)
+		}
+
+		// the used CIDRs are clipped to the root, so FreeSpace can't fail on a valid root, and an invalid
+		// one has already failed every request
+		free, _ := FreeSpace(rootCIDR, used)
+		result.Fragmentation, result.LargestBlock = fragmentation(free)
+		results[strategy] = result
+	}
+	return results
+}
+
+// placeWithStrategy returns where the strategy places a CIDR of the desiredMask size.
+func placeWithStrategy(strategy Strategy, rootCIDR *net.IPNet, desiredMask *net.IPMask, usedCIDRs []*net.IPNet) (*net.IPNet, error) {
+	if strategy == FirstFit {
+		return FindAvailableCIDR(rootCIDR, desiredMask, usedCIDRs)
+	}
+	if strategy != BestFit && strategy != Spread {
+		return nil, fmt.Errorf("%w: unknown strategy %s", ErrInvalidInputRanges, strategy)
+	}
+
+	desiredOnes, desiredBits := desiredMask.Size()
+	if _, bits := rootCIDR.Mask.Size(); desiredBits != bits || (desiredOnes == 0 && desiredBits == 0) {
+		return nil, fmt.Errorf("%w: desired mask doesn't match the address family of the root CIDR", ErrInvalidInputRanges)
+	}
+	free, err := FreeSpace(rootCIDR, usedCIDRs)
+	if err != nil {
+		return nil, err
+	}
+
+	// free blocks are aligned, so the desired CIDR fits at the start of any block at least as large
+	var chosen *net.IPNet
+	chosenOnes := 0
+	for _, block := range free {
+		ones, _ := block.Mask.Size()
+		if ones > desiredOnes {
+			continue
+		}
+		if chosen == nil || (strategy == BestFit && ones > chosenOnes) || (strategy == Spread && ones < chosenOnes) {
+			chosen = block
+			chosenOnes = ones
+		}
+	}
+	if chosen == nil {
+		return nil, fmt.Errorf("%w: no free block is large enough for the requested mask", ErrNoAvailableCIDR)
+	}
+	return &net.IPNet{IP: chosen.IP, Mask: *desiredMask}, nil
+}
+
+// fragmentation returns the fragmentation score of the free blocks and the largest of them, the lowest
+// if there are several of the same size.
+func fragmentation(free []*net.IPNet) (float64, *net.IPNet) {
+	total := new(big.Int)
+	var largest *net.IPNet
+	for _, block := range free {
+		total.Add(total, BlockSize(&block.Mask))
+		if largest == nil || SmallerMask(&largest.Mask, &block.Mask) {
+			largest = block
+		}
+	}
+	if largest == nil {
+		return 0, nil
+	}
+	ratio, _ := new(big.Rat).SetFrac(BlockSize(&largest.Mask), total).Float64()
+	return 1 - ratio, largest
+}
@@ -0,0 +1,77 @@
+package cidr_test
+
+import (
+	"net"
+	"testing"
+
+	"github.com/massdriver-cloud/cola/pkg/cidr"
+)
+
+func TestCompareStrategies(t *testing.T) {
+	type testData struct {
+		name          string
+		strategy      cidr.Strategy
+		fragmentation float64
+		largest       string
+		failures      int
+	}
+	tests := []testData{
+		{
+			name:          "First fit",
+			strategy:      cidr.FirstFit,
+			fragmentation: 0.5,
+			largest:       "10.0.0.32/27",
+			failures:      1,
+		},
+		{
+			name:          "Best fit",
+			strategy:      cidr.BestFit,
+			fragmentation: 0,
+			largest:       "",
+			failures:      0,
+		},
+		{
+			name:          "Spread",
+			strategy:      cidr.Spread,
+			fragmentation: 0.5,
+			largest:       "10.0.0.32/27",
+			failures:      1,
+		},
+	}
+
+	// the free space is 10.0.0.0/26 and 10.0.0.192/27, so the /27 either fills the smaller hole, leaving
+	// the larger one for the /26, or splits the larger one and leaves no room for the /26
+	_, root, _ := net.ParseCIDR("10.0.0.0/24")
+	usedCIDRs := []*net.IPNet{}
+	for _, usedCIDR := range []string{"10.0.0.64/26", "10.0.0.128/26", "10.0.0.224/27"} {
+		_, usedCIDR, _ := net.ParseCIDR(usedCIDR)
+		usedCIDRs = append(usedCIDRs, usedCIDR)
+	}
+	mask27 := net.CIDRMask(27, 32)
+	mask26 := net.CIDRMask(26, 32)
+	requests := []*net.IPMask{&mask27, &mask26}
+
+	results := cidr.CompareStrategies(root, requests, []cidr.Strategy{cidr.FirstFit, cidr.BestFit, cidr.Spread}, usedCIDRs)
+	if results[cidr.BestFit].Fragmentation >= results[cidr.FirstFit].Fragmentation {
+		t.Fatalf("want: best fit less fragmented than first fit, got: %v and %v", results[cidr.BestFit].Fragmentation, results[cidr.FirstFit].Fragmentation)
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got := results[tc.strategy]
+			if got.Fragmentation != tc.fragmentation {
+				t.Fatalf("want: %v, got: %v", tc.fragmentation, got.Fragmentation)
+			}
+			largest := ""
+			if got.LargestBlock != nil {
+				largest = got.LargestBlock.String()
+			}
+			if largest != tc.largest {
+				t.Fatalf("want: %v, got: %v", tc.largest, largest)
+			}
+			if got.Failures != tc.failures {
+				t.Fatalf("want: %v, got: %v", tc.failures, got.Failures)
+			}
+		})
+	}
+}
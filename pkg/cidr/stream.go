@@ -0,0 +1,110 @@
+package cidr
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math/big"
+	"net"
+	"sort"
+	"strings"
+)
+
+// streamEntry is a used CIDR read by ValidateStream, kept as its address range rather than a
+// *net.IPNet to keep memory down for large files.
+type streamEntry struct {
+	first *big.Int
+	last  *big.Int
+	ones  int
+	bits  int
+	line  int
+}
+
+func (e streamEntry) String() string {
+	// the range always fits the address family, so this can't fail
+	ip, _ := IntToIP(e.first, e.bits)
+	return (&net.IPNet{IP: ip, Mask: net.CIDRMask(e.ones, e.bits)}).String()
+}
+
+// ValidateStream is ValidateUsedCIDRsAll for used CIDRs read from r, one per line, for files too large
+// to load as a slice of CIDRs first. Blank lines, lines starting with # and anything after the CIDR
+// following a # or , are ignored. Problems name the line of each CIDR, and lines which aren't valid
+// CIDRs are reported as ErrInvalidInputRanges problems. The error is only set if r can't be read.
+func ValidateStream(rootCIDR *net.IPNet, r io.Reader) ([]error, error) {
+	errs := []error{}
+	if !IsCanonical(rootCIDR) {
+		errs = append(errs, fmt.Errorf("%w: root CIDR %s has host bits set", ErrInvalidInputRanges, rootCIDR.String()))
+	}
+
+	entries := []streamEntry{}
+	scanner := bufio.NewScanner(r)
+	for line := 1; scanner.Scan(); line++ {
+		value := scanner.Text()
+		if i := strings.IndexAny(value, "#,"); i >= 0 {
+			value = value[:i]
+		}
+		value = strings.TrimSpace(value)
+		if value == "" {
+			continue
+		}
+
+		ip, used, err := net.ParseCIDR(value)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%w: line %d: %s is not a valid CIDR", ErrInvalidInputRanges, line, value))
+			continue
+		}
+		if !ip.Equal(used.IP) {
+			errs = append(errs, fmt.Errorf("%w: line %d: used CIDR %s has host bits set", ErrInvalidInputRanges, line, value))
+		}
+		if !ContainsCIDR(rootCIDR, used) {
+			errs = append(errs, fmt.Errorf("%w: line %d: used CIDR %s is not within the root CIDR %s", ErrInvalidInputRanges, line, used.String(), rootCIDR.String()))
+		}
+		first, last := CIDRToIntRange(used)
+		ones, bits := used.Mask.Size()
+		entries = append(entries, streamEntry{first: first, last: last, ones: ones, bits: bits, line: line})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return append(errs, streamOverlaps(entries)...), nil
+}
+
+// streamOverlaps returns an ErrOverlappingCIDRs problem for each pair of overlapping entries. CIDRs
+// either nest or don't overlap at all, so after sorting by address (largest first) each entry overlaps
+// exactly the earlier entries which still contain it.
+func streamOverlaps(entries []streamEntry) []error {
+	sort.Slice(entries, func(i, j int) bool {
+		if entries[i].bits != entries[j].bits {
+			return entries[i].bits < entries[j].bits
+		}
+		if c := entries[i].first.Cmp(entries[j].first); c != 0 {
+			return c < 0
+		}
+		if entries[i].ones != entries[j].ones {
+			return entries[i].ones < entries[j].ones
+		}
+		return entries[i].line < entries[j].line
+	})
+
+	errs := []error{}
+	open := []streamEntry{}
+	for _, entry := range entries {
+		for len(open) > 0 {
+			top := open[len(open)-1]
+			if top.bits == entry.bits && top.last.Cmp(entry.first) >= 0 {
+				break
+			}
+			open = open[:len(open)-1]
+		}
+		for _, container := range open {
+			earlier, later := container, entry
+			if later.line < earlier.line {
+				earlier, later = later, earlier
+			}
+			errs = append(errs, fmt.Errorf("%w: line %d: used CIDR %s overlaps %s on line %d", ErrOverlappingCIDRs, later.line, later.String(), earlier.String(), earlier.line))
+		}
+		open = append(open, entry)
+	}
+	return errs
+}
@@ -0,0 +1,129 @@
+package cidr_test
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"testing"
+
+	"github.com/massdriver-cloud/cola/pkg/cidr"
+)
+
+func TestValidateStream(t *testing.T) {
+	type testData struct {
+		name     string
+		rootCIDR string
+		input    string
+		want     []string
+	}
+	tests := []testData{
+		{
+			name:     "Valid",
+			rootCIDR: "10.0.0.0/16",
+			input:    "# subnets\n10.0.0.0/24\n\n10.0.1.0/24 # web\n10.0.2.0/23,api\n",
+			want:     []string{},
+		},
+		{
+			name:     "Every problem",
+			rootCIDR: "10.0.0.0/16",
+			input:    "10.0.0.0/22\n10.0.1.7/24\n10.1.0.0/24\nnope\n10.0.0.0/24\n",
+			want: []string{
+				"input ranges invalid: line 2: used CIDR 10.0.1.7/24 has host bits set",
+				"input ranges invalid: line 3: used CIDR 10.1.0.0/24 is not within the root CIDR 10.0.0.0/16",
+				"input ranges invalid: line 4: nope is not a valid CIDR",
+				"CIDR ranges overlap: line 5: used CIDR 10.0.0.0/24 overlaps 10.0.0.0/22 on line 1",
+				"CIDR ranges overlap: line 2: used CIDR 10.0.1.0/24 overlaps 10.0.0.0/22 on line 1",
+			},
+		},
+		{
+			name:     "Nested overlaps",
+			rootCIDR: "10.0.0.0/16",
+			input:    "10.0.0.0/26\n10.0.0.0/24\n10.0.0.0/25\n",
+			want: []string{
+				"CIDR ranges overlap: line 3: used CIDR 10.0.0.0/25 overlaps 10.0.0.0/24 on line 2",
+				"CIDR ranges overlap: line 2: used CIDR 10.0.0.0/24 overlaps 10.0.0.0/26 on line 1",
+				"CIDR ranges overlap: line 3: used CIDR 10.0.0.0/25 overlaps 10.0.0.0/26 on line 1",
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			_, rootCIDR, _ := net.ParseCIDR(tc.rootCIDR)
+			got, err := cidr.ValidateStream(rootCIDR, strings.NewReader(tc.input))
+			if err != nil {
+				t.Fatalf("Unexpected error: %s,", err.Error())
+			}
+			if len(got) != len(tc.want) {
+				t.Fatalf("want: %v, got: %v", tc.want, got)
+			}
+			for i := range got {
+				if got[i].Error() != tc.want[i] {
+					t.Fatalf("want: %v, got: %v", tc.want[i], got[i].Error())
+				}
+			}
+		})
+	}
+}
+
+// generatedReader lazily writes count /28 lines followed by the extra lines, recording the largest read
+type generatedReader struct {
+	count   int
+	next    int
+	extra   string
+	pending []byte
+	maxRead int
+	read    int
+}
+
+func (g *generatedReader) Read(p []byte) (int, error) {
+	if len(p) > g.maxRead {
+		g.maxRead = len(p)
+	}
+	for len(g.pending) == 0 {
+		switch {
+		case g.next < g.count:
+			offset := g.next * 16
+			g.pending = []byte(fmt.Sprintf("10.%d.%d.%d/28\n", offset>>16, (offset>>8)&0xff, offset&0xff))
+			g.next++
+		case g.extra != "":
+			g.pending = []byte(g.extra)
+			g.extra = ""
+		default:
+			return 0, io.EOF
+		}
+	}
+	n := copy(p, g.pending)
+	g.pending = g.pending[n:]
+	g.read += n
+	return n, nil
+}
+
+func TestValidateStreamLarge(t *testing.T) {
+	_, rootCIDR, _ := net.ParseCIDR("10.0.0.0/8")
+	reader := &generatedReader{count: 200000, extra: "10.0.1.0/24\n"}
+
+	got, err := cidr.ValidateStream(rootCIDR, reader)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s,", err.Error())
+	}
+	// the /24 overlaps the sixteen /28s within it
+	if len(got) != 16 {
+		t.Fatalf("want: %v, got: %v", 16, len(got))
+	}
+	for _, problem := range got {
+		if !errors.Is(problem, cidr.ErrOverlappingCIDRs) {
+			t.Fatalf("Invalid error, want: %v, got %v,", cidr.ErrOverlappingCIDRs, problem)
+		}
+	}
+
+	// the input is read in small chunks as it's parsed, rather than all at once
+	if reader.read < 3000000 {
+		t.Fatalf("want: whole input read, got: %v bytes", reader.read)
+	}
+	if reader.maxRead > 64*1024 {
+		t.Fatalf("want: reads of at most %v bytes, got: %v", 64*1024, reader.maxRead)
+	}
+}
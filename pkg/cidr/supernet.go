@@ -0,0 +1,101 @@
+package cidr
+
+import (
+	"fmt"
+	"math/big"
+	"net"
+)
+
+// CommonSupernet returns the longest prefix CIDR which contains both a and b, their lowest common
+// ancestor in the CIDR tree. For 10.0.0.0/24 and 10.0.1.0/24 this is 10.0.0.0/23. An error is
+// returned if a and b are from different address families.
+func CommonSupernet(a *net.IPNet, b *net.IPNet) (*net.IPNet, error) {
+	aOnes, aBits := a.Mask.Size()
+	bOnes, bBits := b.Mask.Size()
+	if aBits != bBits {
+		return nil, fmt.Errorf("%w: %s and %s are different address families", ErrInvalidInputRanges, a.String(), b.String())
+	}
+
+	aInt := IPToInt(a.IP)
+	bInt := IPToInt(b.IP)
+
+	// the common prefix can't be longer than either CIDR's prefix, or extend past the first differing bit
+	ones := aOnes
+	if bOnes < ones {
+		ones = bOnes
+	}
+	for i := 0; i < ones; i++ {
+		bit := aBits - 1 - i
+		if aInt.Bit(bit) != bInt.Bit(bit) {
+			ones = i
+			break
+		}
+	}
+
+	mask := net.CIDRMask(ones, aBits)
+	ip := a.IP
+	if aBits == 8*net.IPv4len {
+		ip = ip.To4()
+	}
+	return &net.IPNet{IP: ip.Mask(mask), Mask: mask}, nil
+}
+
+// SupernetExact returns the CIDR the subnets exactly tile, so four /26s starting at 10.0.0.0 are exactly
+// 10.0.0.0/24. Unlike CommonSupernet, which covers whatever lies between its inputs, every address of
+// the result must be in exactly one of the subnets. An error is returned if there are no subnets, if
+// any of them overlap, or if they leave a gap or don't start and end on the boundaries of one CIDR,
+// such as 10.0.1.0/24 and 10.0.2.0/24.
+func SupernetExact(subnets []*net.IPNet) (*net.IPNet, error) {
+	if len(subnets) == 0 {
+		return nil, fmt.Errorf("%w: no subnets to cover", ErrInvalidInputRanges)
+	}
+	covered := new(big.Int)
+	for _, subnet := range subnets {
+		if subnet == nil {
+			return nil, fmt.Errorf("%w: nil subnet", ErrInvalidInputRanges)
+		}
+		covered.Add(covered, BlockSize(&subnet.Mask))
+	}
+
+	aggregated, err := Aggregate(subnets)
+	if err != nil {
+		return nil, err
+	}
+	if len(aggregated) != 1 {
+		return nil, fmt.Errorf("%w: the subnets cover %d separate CIDRs rather than one", ErrInvalidInputRanges, len(aggregated))
+	}
+	// the aggregate covers each address once, so any addresses counted twice were overlaps
+	supernet := aggregated[0]
+	if BlockSize(&supernet.Mask).Cmp(covered) != 0 {
+		return nil, fmt.Errorf("%w: the subnets overlap within %s", ErrOverlappingCIDRs, supernet.String())
+	}
+	return supernet, nil
+}
+
+// ParentCIDR returns the CIDR one prefix length shorter which contains n, so the parent of
+// 10.0.1.0/24 is 10.0.0.0/23. An error is returned if n has a prefix length of 0.
+func ParentCIDR(n *net.IPNet) (*net.IPNet, error) {
+	ones, bits := n.Mask.Size()
+	if ones == 0 {
+		return nil, fmt.Errorf("%w: %s has no parent CIDR", ErrInvalidInputRanges, n.String())
+	}
+	mask := net.CIDRMask(ones-1, bits)
+	return &net.IPNet{IP: n.IP.Mask(mask), Mask: mask}, nil
+}
+
+// SiblingCIDR returns the other child of n's parent CIDR, so the sibling of 10.0.1.0/24 is
+// 10.0.0.0/24. An error is returned if n has a prefix length of 0.
+func SiblingCIDR(n *net.IPNet) (*net.IPNet, error) {
+	parent, err := ParentCIDR(n)
+	if err != nil {
+		return nil, err
+	}
+	child1, child2, err := ChildCIDRs(parent)
+	if err != nil {
+		return nil, err
+	}
+	if EqualCIDRs(child1, canonical(n)) {
+		return child2, nil
+	}
+	return child1, nil
+}
@@ -0,0 +1,224 @@
+package cidr_test
+
+import (
+	"errors"
+	"net"
+	"testing"
+
+	"github.com/massdriver-cloud/cola/pkg/cidr"
+)
+
+func TestCommonSupernet(t *testing.T) {
+	type testData struct {
+		name      string
+		a         string
+		b         string
+		want      string
+		wantError error
+	}
+	tests := []testData{
+		{
+			name: "Siblings",
+			a:    "10.0.0.0/24",
+			b:    "10.0.1.0/24",
+			want: "10.0.0.0/23",
+		},
+		{
+			name: "Distant",
+			a:    "10.0.0.0/24",
+			b:    "10.1.0.0/24",
+			want: "10.0.0.0/15",
+		},
+		{
+			name: "Different prefix lengths",
+			a:    "10.0.0.0/20",
+			b:    "10.0.88.0/21",
+			want: "10.0.0.0/17",
+		},
+		{
+			name: "Nested",
+			a:    "10.0.0.0/16",
+			b:    "10.0.88.0/21",
+			want: "10.0.0.0/16",
+		},
+		{
+			name: "Identical",
+			a:    "10.0.5.0/24",
+			b:    "10.0.5.0/24",
+			want: "10.0.5.0/24",
+		},
+		{
+			name: "Opposite halves",
+			a:    "10.0.0.0/8",
+			b:    "192.168.0.0/16",
+			want: "0.0.0.0/0",
+		},
+		{
+			name: "IPv6",
+			a:    "2001:db8::/64",
+			b:    "2001:db8:0:1::/64",
+			want: "2001:db8::/63",
+		},
+		{
+			name:      "Error mixed families",
+			a:         "10.0.0.0/24",
+			b:         "2001:db8::/64",
+			wantError: cidr.ErrInvalidInputRanges,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			_, a, _ := net.ParseCIDR(tc.a)
+			_, b, _ := net.ParseCIDR(tc.b)
+			got, err := cidr.CommonSupernet(a, b)
+			if tc.wantError != nil {
+				if !errors.Is(err, tc.wantError) {
+					t.Fatalf("Invalid error, want: %v, got %v,", tc.wantError, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %s,", err.Error())
+			}
+			if got.String() != tc.want {
+				t.Fatalf("want: %v, got: %v", tc.want, got.String())
+			}
+		})
+	}
+}
+
+func TestParentAndSiblingCIDR(t *testing.T) {
+	type testData struct {
+		name        string
+		cidr        string
+		wantParent  string
+		wantSibling string
+		wantError   error
+	}
+	tests := []testData{
+		{
+			name:        "First child",
+			cidr:        "10.0.0.0/24",
+			wantParent:  "10.0.0.0/23",
+			wantSibling: "10.0.1.0/24",
+		},
+		{
+			name:        "Second child",
+			cidr:        "10.0.88.0/21",
+			wantParent:  "10.0.80.0/20",
+			wantSibling: "10.0.80.0/21",
+		},
+		{
+			name:        "IPv6",
+			cidr:        "2001:db8:0:1::/64",
+			wantParent:  "2001:db8::/63",
+			wantSibling: "2001:db8::/64",
+		},
+		{
+			name:      "Error no parent",
+			cidr:      "0.0.0.0/0",
+			wantError: cidr.ErrInvalidInputRanges,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			_, n, _ := net.ParseCIDR(tc.cidr)
+			parent, err := cidr.ParentCIDR(n)
+			if tc.wantError != nil {
+				if !errors.Is(err, tc.wantError) {
+					t.Fatalf("Invalid error, want: %v, got %v,", tc.wantError, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %s,", err.Error())
+			}
+			if parent.String() != tc.wantParent {
+				t.Fatalf("want: %v, got: %v", tc.wantParent, parent.String())
+			}
+			sibling, err := cidr.SiblingCIDR(n)
+			if err != nil {
+				t.Fatalf("Unexpected error: %s,", err.Error())
+			}
+			if sibling.String() != tc.wantSibling {
+				t.Fatalf("want: %v, got: %v", tc.wantSibling, sibling.String())
+			}
+		})
+	}
+}
+
+func TestSupernetExact(t *testing.T) {
+	type testData struct {
+		name      string
+		subnets   []string
+		want      string
+		wantError error
+	}
+	tests := []testData{
+		{
+			name:    "Exact tiling",
+			subnets: []string{"10.0.0.0/26", "10.0.0.64/26", "10.0.0.128/26", "10.0.0.192/26"},
+			want:    "10.0.0.0/24",
+		},
+		{
+			name:    "Mixed sizes out of order",
+			subnets: []string{"10.0.0.128/25", "10.0.0.64/26", "10.0.0.0/26"},
+			want:    "10.0.0.0/24",
+		},
+		{
+			name:    "Single subnet",
+			subnets: []string{"10.0.5.0/24"},
+			want:    "10.0.5.0/24",
+		},
+		{
+			name:    "IPv6",
+			subnets: []string{"2001:db8::/64", "2001:db8:0:1::/64"},
+			want:    "2001:db8::/63",
+		},
+		{
+			name:      "Error gap",
+			subnets:   []string{"10.0.0.0/26", "10.0.0.64/26", "10.0.0.192/26"},
+			wantError: cidr.ErrInvalidInputRanges,
+		},
+		{
+			name:      "Error non-aligned start",
+			subnets:   []string{"10.0.1.0/24", "10.0.2.0/24"},
+			wantError: cidr.ErrInvalidInputRanges,
+		},
+		{
+			name:      "Error overlap",
+			subnets:   []string{"10.0.0.0/24", "10.0.0.0/25", "10.0.1.0/24"},
+			wantError: cidr.ErrOverlappingCIDRs,
+		},
+		{
+			name:      "Error no subnets",
+			subnets:   []string{},
+			wantError: cidr.ErrInvalidInputRanges,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			subnets := []*net.IPNet{}
+			for _, subnet := range tc.subnets {
+				_, n, _ := net.ParseCIDR(subnet)
+				subnets = append(subnets, n)
+			}
+			got, err := cidr.SupernetExact(subnets)
+			if tc.wantError != nil {
+				if !errors.Is(err, tc.wantError) {
+					t.Fatalf("Invalid error, want: %v, got %v,", tc.wantError, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %s,", err.Error())
+			}
+			if got.String() != tc.want {
+				t.Fatalf("want: %v, got: %v", tc.want, got.String())
+			}
+		})
+	}
+}
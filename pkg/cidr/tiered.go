@@ -0,0 +1,112 @@
+package cidr
+
+import (
+	"fmt"
+	"net"
+	"sort"
+)
+
+// FindTieredPlan allocates a CIDR for each named tier in requests (a map of tier name to prefix length)
+// from the rootCIDR, avoiding the usedCIDRs and each other. Tiers are packed largest first, with ties
+// broken by name, so the same input always produces the same plan. If any tier can't be placed an
+// error is returned and no plan is produced. The usedCIDRs slice is not modified.
+func FindTieredPlan(rootCIDR *net.IPNet, requests map[string]int, usedCIDRs []*net.IPNet) (map[string]*net.IPNet, error) {
+	names := make([]string, 0, len(requests))
+	for name := range requests {
+		names = append(names, name)
+	}
+	sort.Slice(names, func(i, j int) bool {
+		if requests[names[i]] != requests[names[j]] {
+			return requests[names[i]] < requests[names[j]]
+		}
+		return names[i] < names[j]
+	})
+
+	_, bits := rootCIDR.Mask.Size()
+	used := make([]*net.IPNet, len(usedCIDRs), len(usedCIDRs)+len(names))
+	copy(used, usedCIDRs)
+
+	plan := make(map[string]*net.IPNet, len(names))
+	for _, name := range names {
+		mask := net.CIDRMask(requests[name], bits)
+		if mask == nil {
+			return nil, fmt.Errorf("%w: tier %q has invalid prefix length /%d", ErrInvalidInputRanges, name, requests[name])
+		}
+		tier, err := FindAvailableCIDR(rootCIDR, &mask, used)
+		if err != nil {
+			return nil, fmt.Errorf("unable to place tier %q: %w", name, err)
+		}
+		used = append(used, tier)
+		plan[name] = tier
+	}
+
+	return plan, nil
+}
+
+// FindWithSticky is FindTieredPlan with some tiers pinned to the CIDRs in sticky, for generating a
+// stable plan: feeding a plan back in as sticky reproduces it exactly, and tiers added later are packed
+// around the pinned ones. Each pin must be the requested size, within the rootCIDR, and not overlap the
+// usedCIDRs or another pin, otherwise an error is returned. Pins for tiers which aren't requested are
+// ignored, so removing a tier from requests releases its CIDR.
+func FindWithSticky(rootCIDR *net.IPNet, requests map[string]int, sticky map[string]*net.IPNet, usedCIDRs []*net.IPNet) (map[string]*net.IPNet, error) {
+	names := make([]string, 0, len(sticky))
+	for name := range sticky {
+		if _, requested := requests[name]; requested {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	used := make([]*net.IPNet, len(usedCIDRs), len(usedCIDRs)+len(names))
+	copy(used, usedCIDRs)
+	plan := make(map[string]*net.IPNet, len(requests))
+	for _, name := range names {
+		pin := sticky[name]
+		if ones, _ := pin.Mask.Size(); ones != requests[name] {
+			return nil, fmt.Errorf("%w: tier %q is pinned to %s but requests /%d", ErrInvalidInputRanges, name, pin.String(), requests[name])
+		}
+		if !IsCanonical(pin) {
+			return nil, fmt.Errorf("%w: tier %q is pinned to %s which has host bits set", ErrInvalidInputRanges, name, pin.String())
+		}
+		if !ContainsCIDR(rootCIDR, pin) {
+			return nil, fmt.Errorf("%w: tier %q is pinned to %s which is not within the root CIDR %s", ErrInvalidInputRanges, name, pin.String(), rootCIDR.String())
+		}
+		for _, u := range used {
+			if OverlapsCIDR(pin, u) {
+				return nil, fmt.Errorf("%w: tier %q is pinned to %s which overlaps %s", ErrOverlappingCIDRs, name, pin.String(), u.String())
+			}
+		}
+		used = append(used, pin)
+		plan[name] = pin
+	}
+
+	remaining := make(map[string]int, len(requests)-len(plan))
+	for name, prefix := range requests {
+		if _, pinned := plan[name]; !pinned {
+			remaining[name] = prefix
+		}
+	}
+	placed, err := FindTieredPlan(rootCIDR, remaining, used)
+	if err != nil {
+		return nil, err
+	}
+	for name, n := range placed {
+		plan[name] = n
+	}
+	return plan, nil
+}
+
+// SortRequestsForPacking returns the indexes of masks in the order they should be requested from
+// FindAvailableCIDR to limit fragmentation: largest block first, keeping the original order for
+// masks of the same size. This is the same heuristic FindTieredPlan uses, for callers who run their
+// own allocation loop.
+func SortRequestsForPacking(masks []*net.IPMask) []int {
+	order := make([]int, len(masks))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(i, j int) bool {
+		return BlockSize(masks[order[i]]).Cmp(BlockSize(masks[order[j]])) > 0
+	})
+	return order
+}
@@ -0,0 +1,269 @@
+package cidr_test
+
+import (
+	"errors"
+	"net"
+	"testing"
+
+	"github.com/massdriver-cloud/cola/pkg/cidr"
+)
+
+func TestFindTieredPlan(t *testing.T) {
+	type testData struct {
+		name      string
+		baseCIDR  string
+		requests  map[string]int
+		usedCIDRs []string
+		want      map[string]string
+		wantError error
+	}
+	tests := []testData{
+		{
+			name:      "Largest first",
+			baseCIDR:  "10.0.0.0/16",
+			requests:  map[string]int{"web": 24, "data": 20, "cache": 26},
+			usedCIDRs: []string{},
+			want: map[string]string{
+				"data":  "10.0.0.0/20",
+				"web":   "10.0.16.0/24",
+				"cache": "10.0.17.0/26",
+			},
+		},
+		{
+			name:      "Ties broken by name",
+			baseCIDR:  "10.0.0.0/16",
+			requests:  map[string]int{"public": 24, "private": 24, "database": 24},
+			usedCIDRs: []string{"10.0.0.0/24"},
+			want: map[string]string{
+				"database": "10.0.1.0/24",
+				"private":  "10.0.2.0/24",
+				"public":   "10.0.3.0/24",
+			},
+		},
+		{
+			name:      "Error tier does not fit",
+			baseCIDR:  "10.0.0.0/16",
+			requests:  map[string]int{"web": 17, "data": 17, "cache": 24},
+			usedCIDRs: []string{},
+			wantError: cidr.ErrNoAvailableCIDR,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			_, baseCIDR, _ := net.ParseCIDR(tc.baseCIDR)
+			usedCIDRs := make([]*net.IPNet, len(tc.usedCIDRs))
+			for i, usedCIDR := range tc.usedCIDRs {
+				_, usedCIDR, _ := net.ParseCIDR(usedCIDR)
+				usedCIDRs[i] = usedCIDR
+			}
+			got, err := cidr.FindTieredPlan(baseCIDR, tc.requests, usedCIDRs)
+			if tc.wantError != nil {
+				if !errors.Is(err, tc.wantError) {
+					t.Fatalf("Invalid error, want: %v, got %v,", tc.wantError, err)
+				}
+				if got != nil {
+					t.Fatalf("want no plan on error, got: %v", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %s,", err.Error())
+			}
+			if len(got) != len(tc.want) {
+				t.Fatalf("want: %v, got: %v", tc.want, got)
+			}
+			for name, want := range tc.want {
+				if got[name].String() != want {
+					t.Fatalf("tier %s want: %v, got: %v", name, want, got[name].String())
+				}
+			}
+			for name1, tier1 := range got {
+				for name2, tier2 := range got {
+					if name1 != name2 && cidr.OverlapsCIDR(tier1, tier2) {
+						t.Fatalf("tiers %s and %s overlap", name1, name2)
+					}
+				}
+			}
+
+			again, err := cidr.FindTieredPlan(baseCIDR, tc.requests, usedCIDRs)
+			if err != nil {
+				t.Fatalf("Unexpected error: %s,", err.Error())
+			}
+			for name := range got {
+				if !cidr.EqualCIDRs(got[name], again[name]) {
+					t.Fatalf("tier %s changed between runs: %v, %v", name, got[name].String(), again[name].String())
+				}
+			}
+		})
+	}
+}
+
+func TestFindWithSticky(t *testing.T) {
+	type testData struct {
+		name      string
+		requests  map[string]int
+		sticky    map[string]string
+		usedCIDRs []string
+		want      map[string]string
+		wantError error
+	}
+	tests := []testData{
+		{
+			name:      "Pinned tiers stay put",
+			requests:  map[string]int{"web": 24, "data": 20, "cache": 26},
+			sticky:    map[string]string{"web": "10.0.200.0/24", "cache": "10.0.0.0/26"},
+			usedCIDRs: []string{},
+			want: map[string]string{
+				"web":   "10.0.200.0/24",
+				"cache": "10.0.0.0/26",
+				"data":  "10.0.16.0/20",
+			},
+		},
+		{
+			name:      "Pins for removed tiers ignored",
+			requests:  map[string]int{"web": 24},
+			sticky:    map[string]string{"web": "10.0.1.0/24", "old": "10.0.0.0/24"},
+			usedCIDRs: []string{},
+			want:      map[string]string{"web": "10.0.1.0/24"},
+		},
+		{
+			name:      "Error pin resized",
+			requests:  map[string]int{"web": 23},
+			sticky:    map[string]string{"web": "10.0.1.0/24"},
+			usedCIDRs: []string{},
+			wantError: cidr.ErrInvalidInputRanges,
+		},
+		{
+			name:      "Error pin outside root",
+			requests:  map[string]int{"web": 24},
+			sticky:    map[string]string{"web": "10.1.1.0/24"},
+			usedCIDRs: []string{},
+			wantError: cidr.ErrInvalidInputRanges,
+		},
+		{
+			name:      "Error pin used",
+			requests:  map[string]int{"web": 24},
+			sticky:    map[string]string{"web": "10.0.1.0/24"},
+			usedCIDRs: []string{"10.0.0.0/23"},
+			wantError: cidr.ErrOverlappingCIDRs,
+		},
+		{
+			name:      "Error pins overlap",
+			requests:  map[string]int{"web": 24, "data": 22},
+			sticky:    map[string]string{"web": "10.0.1.0/24", "data": "10.0.0.0/22"},
+			usedCIDRs: []string{},
+			wantError: cidr.ErrOverlappingCIDRs,
+		},
+	}
+
+	_, baseCIDR, _ := net.ParseCIDR("10.0.0.0/16")
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			sticky := make(map[string]*net.IPNet, len(tc.sticky))
+			for name, value := range tc.sticky {
+				_, sticky[name], _ = net.ParseCIDR(value)
+			}
+			usedCIDRs := make([]*net.IPNet, len(tc.usedCIDRs))
+			for i, usedCIDR := range tc.usedCIDRs {
+				_, usedCIDR, _ := net.ParseCIDR(usedCIDR)
+				usedCIDRs[i] = usedCIDR
+			}
+			got, err := cidr.FindWithSticky(baseCIDR, tc.requests, sticky, usedCIDRs)
+			if tc.wantError != nil {
+				if !errors.Is(err, tc.wantError) {
+					t.Fatalf("Invalid error, want: %v, got %v,", tc.wantError, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %s,", err.Error())
+			}
+			if len(got) != len(tc.want) {
+				t.Fatalf("want: %v, got: %v", tc.want, got)
+			}
+			for name, want := range tc.want {
+				if got[name] == nil || got[name].String() != want {
+					t.Fatalf("want: %v, got: %v", want, got[name])
+				}
+			}
+		})
+	}
+}
+
+func TestFindWithStickyReproducesPlan(t *testing.T) {
+	_, baseCIDR, _ := net.ParseCIDR("10.0.0.0/16")
+	_, usedCIDR, _ := net.ParseCIDR("10.0.0.0/24")
+	usedCIDRs := []*net.IPNet{usedCIDR}
+
+	first, err := cidr.FindWithSticky(baseCIDR, map[string]int{"web": 24, "data": 20}, map[string]*net.IPNet{}, usedCIDRs)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s,", err.Error())
+	}
+	// a new tier is packed around the previous plan, which is left as it was
+	second, err := cidr.FindWithSticky(baseCIDR, map[string]int{"web": 24, "data": 20, "big": 18}, first, usedCIDRs)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s,", err.Error())
+	}
+	third, err := cidr.FindWithSticky(baseCIDR, map[string]int{"web": 24, "data": 20, "big": 18}, second, usedCIDRs)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s,", err.Error())
+	}
+	for name, n := range first {
+		if second[name].String() != n.String() {
+			t.Fatalf("want: %v, got: %v", n, second[name])
+		}
+	}
+	if len(third) != len(second) {
+		t.Fatalf("want: %v, got: %v", second, third)
+	}
+	for name, n := range second {
+		if third[name].String() != n.String() {
+			t.Fatalf("want: %v, got: %v", n, third[name])
+		}
+	}
+}
+
+func TestSortRequestsForPacking(t *testing.T) {
+	type testData struct {
+		name     string
+		prefixes []int
+		want     []int
+	}
+	tests := []testData{
+		{
+			name:     "Largest first",
+			prefixes: []int{26, 20, 24},
+			want:     []int{1, 2, 0},
+		},
+		{
+			name:     "Stable ties",
+			prefixes: []int{24, 22, 24, 22, 24},
+			want:     []int{1, 3, 0, 2, 4},
+		},
+		{
+			name:     "Empty",
+			prefixes: []int{},
+			want:     []int{},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			masks := make([]*net.IPMask, len(tc.prefixes))
+			for i, prefix := range tc.prefixes {
+				mask := net.CIDRMask(prefix, 32)
+				masks[i] = &mask
+			}
+			got := cidr.SortRequestsForPacking(masks)
+			if len(got) != len(tc.want) {
+				t.Fatalf("want: %v, got: %v", tc.want, got)
+			}
+			for i := range got {
+				if got[i] != tc.want[i] {
+					t.Fatalf("want: %v, got: %v", tc.want, got)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,75 @@
+package cidr
+
+import (
+	"encoding/json"
+	"net"
+)
+
+// The states of a TreeNode
+const (
+	TreeStateAllocated = "allocated"
+	TreeStateFree      = "free"
+	TreeStatePartial   = "partial"
+)
+
+// TreeNode is a CIDR in the allocation tree built by BuildTree, along with whether it's fully
+// allocated, fully free, or partially allocated. Only partially allocated nodes have children, which
+// are the two halves of the CIDR. It marshals to JSON as {"cidr", "state", "children"}.
+type TreeNode struct {
+	CIDR     *net.IPNet
+	State    string
+	Children []*TreeNode
+}
+
+// MarshalJSON encodes the node with its CIDR in string form.
+func (n *TreeNode) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		CIDR     string      `json:"cidr"`
+		State    string      `json:"state"`
+		Children []*TreeNode `json:"children,omitempty"`
+	}{
+		CIDR:     n.CIDR.String(),
+		State:    n.State,
+		Children: n.Children,
+	})
+}
+
+// BuildTree returns the allocation tree of the root CIDR: the same tree FindAvailableCIDR walks, with
+// each node's state given the used CIDRs. Descending stops at nodes which are fully allocated or fully
+// free, and below maxDepth levels under the root, where a negative maxDepth means no limit.
+func BuildTree(root *net.IPNet, used []*net.IPNet, maxDepth int) *TreeNode {
+	return buildTreeNode(root, used, maxDepth, 0)
+}
+
+func buildTreeNode(current *net.IPNet, used []*net.IPNet, maxDepth int, depth int) *TreeNode {
+	node := &TreeNode{CIDR: current, State: treeState(current, used)}
+	if node.State != TreeStatePartial || (maxDepth >= 0 && depth >= maxDepth) {
+		return node
+	}
+	child1, child2, err := ChildCIDRs(current)
+	if err != nil {
+		// a single address is never partially allocated, so there's always room for children
+		return node
+	}
+	node.Children = []*TreeNode{
+		buildTreeNode(child1, used, maxDepth, depth+1),
+		buildTreeNode(child2, used, maxDepth, depth+1),
+	}
+	return node
+}
+
+// treeState returns the state of current given the used CIDRs
+func treeState(current *net.IPNet, used []*net.IPNet) string {
+	free, err := FreeSpace(current, used)
+	switch {
+	case err != nil:
+		// the free space of a valid CIDR is always within range, so this can't happen
+		return TreeStatePartial
+	case len(free) == 0:
+		return TreeStateAllocated
+	case len(free) == 1 && EqualCIDRs(free[0], current):
+		return TreeStateFree
+	default:
+		return TreeStatePartial
+	}
+}
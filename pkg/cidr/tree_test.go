@@ -0,0 +1,121 @@
+package cidr_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"strings"
+	"testing"
+
+	"github.com/massdriver-cloud/cola/pkg/cidr"
+)
+
+// flattenTree lists the nodes of the tree depth first, indented by their depth
+func flattenTree(node *cidr.TreeNode, depth int) []string {
+	nodes := []string{fmt.Sprintf("%s%s %s", strings.Repeat("  ", depth), node.CIDR.String(), node.State)}
+	for _, child := range node.Children {
+		nodes = append(nodes, flattenTree(child, depth+1)...)
+	}
+	return nodes
+}
+
+func TestBuildTree(t *testing.T) {
+	type testData struct {
+		name      string
+		rootCIDR  string
+		usedCIDRs []string
+		maxDepth  int
+		want      []string
+	}
+	tests := []testData{
+		{
+			name:      "Documented example",
+			rootCIDR:  "10.0.0.0/16",
+			usedCIDRs: []string{"10.0.0.0/18", "10.0.64.0/20", "10.0.80.0/24"},
+			maxDepth:  -1,
+			want: []string{
+				"10.0.0.0/16 partial",
+				"  10.0.0.0/17 partial",
+				"    10.0.0.0/18 allocated",
+				"    10.0.64.0/18 partial",
+				"      10.0.64.0/19 partial",
+				"        10.0.64.0/20 allocated",
+				"        10.0.80.0/20 partial",
+				"          10.0.80.0/21 partial",
+				"            10.0.80.0/22 partial",
+				"              10.0.80.0/23 partial",
+				"                10.0.80.0/24 allocated",
+				"                10.0.81.0/24 free",
+				"              10.0.82.0/23 free",
+				"            10.0.84.0/22 free",
+				"          10.0.88.0/21 free",
+				"      10.0.96.0/19 free",
+				"  10.0.128.0/17 free",
+			},
+		},
+		{
+			name:      "Max depth",
+			rootCIDR:  "10.0.0.0/16",
+			usedCIDRs: []string{"10.0.0.0/18", "10.0.64.0/20", "10.0.80.0/24"},
+			maxDepth:  2,
+			want: []string{
+				"10.0.0.0/16 partial",
+				"  10.0.0.0/17 partial",
+				"    10.0.0.0/18 allocated",
+				"    10.0.64.0/18 partial",
+				"  10.0.128.0/17 free",
+			},
+		},
+		{
+			name:      "Halves used separately",
+			rootCIDR:  "10.0.0.0/24",
+			usedCIDRs: []string{"10.0.0.0/25", "10.0.0.128/25"},
+			maxDepth:  -1,
+			want:      []string{"10.0.0.0/24 allocated"},
+		},
+		{
+			name:      "Within a used CIDR",
+			rootCIDR:  "10.0.1.0/24",
+			usedCIDRs: []string{"10.0.0.0/16"},
+			maxDepth:  -1,
+			want:      []string{"10.0.1.0/24 allocated"},
+		},
+		{
+			name:      "Unused",
+			rootCIDR:  "2001:db8::/48",
+			usedCIDRs: []string{},
+			maxDepth:  -1,
+			want:      []string{"2001:db8::/48 free"},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			_, rootCIDR, _ := net.ParseCIDR(tc.rootCIDR)
+			usedCIDRs := make([]*net.IPNet, len(tc.usedCIDRs))
+			for i, used := range tc.usedCIDRs {
+				_, usedCIDR, _ := net.ParseCIDR(used)
+				usedCIDRs[i] = usedCIDR
+			}
+
+			got := flattenTree(cidr.BuildTree(rootCIDR, usedCIDRs, tc.maxDepth), 0)
+			if strings.Join(got, "\n") != strings.Join(tc.want, "\n") {
+				t.Fatalf("want: %v, got: %v", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestBuildTreeJSON(t *testing.T) {
+	_, rootCIDR, _ := net.ParseCIDR("10.0.0.0/23")
+	_, usedCIDR, _ := net.ParseCIDR("10.0.0.0/24")
+
+	got, err := json.Marshal(cidr.BuildTree(rootCIDR, []*net.IPNet{usedCIDR}, -1))
+	if err != nil {
+		t.Fatalf("Unexpected error: %s,", err.Error())
+	}
+	want := `{"cidr":"10.0.0.0/23","state":"partial","children":[{"cidr":"10.0.0.0/24","state":"allocated"},{"cidr":"10.0.1.0/24","state":"free"}]}`
+	if string(got) != want {
+		t.Fatalf("want: %v, got: %v", want, string(got))
+	}
+}
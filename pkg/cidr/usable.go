@@ -0,0 +1,204 @@
+package cidr
+
+import (
+	"fmt"
+	"math"
+	"math/big"
+	"net"
+)
+
+// reservedPrefixIPv4 is the shortest IPv4 prefix length which doesn't reserve a network and
+// broadcast address. Point-to-point /31 links (RFC 3021) and /32 hosts use every address.
+const reservedPrefixIPv4 = 31
+
+// UsableRange returns the first and last host addresses of n. For IPv4 prefixes shorter than /31
+// the network and broadcast addresses are excluded. IPv6 has no broadcast address, so the full
+// range is returned. IPv4 given in its IPv4-mapped IPv6 form is treated as IPv4, and the addresses
+// are returned in their 4 byte form.
+func UsableRange(n *net.IPNet) (net.IP, net.IP) {
+	if v4, ok := toIPv4Net(n); ok {
+		n = v4
+	}
+	first, last := CIDRToIntRange(n)
+	ones, bits := n.Mask.Size()
+	if bits == 8*net.IPv4len && ones < reservedPrefixIPv4 {
+		first.Add(first, big.NewInt(1))
+		last.Sub(last, big.NewInt(1))
+	}
+
+	// CIDRToIntRange always fits within the address space, so these conversions can't fail
+	firstIP, _ := IntToIP(first, bits)
+	lastIP, _ := IntToIP(last, bits)
+	return firstIP, lastIP
+}
+
+// WithExcludeBaseEdges makes FindAvailableCIDR treat the network and broadcast addresses of an IPv4
+// root CIDR as unusable, rejecting any result which contains either of them, for when the root is a
+// single broadcast domain rather than a range carved into separate subnets. The first and last blocks
+// of the root are skipped, so a /26 from 10.0.0.0/24 can't be 10.0.0.0/26 or 10.0.0.192/26. Like
+// UsableRange, IPv6 roots and IPv4 /31 and /32 roots have no reserved edges and are unaffected.
+func WithExcludeBaseEdges() FindOption {
+	return func(o *findOptions) {
+		o.excludeEdges = true
+	}
+}
+
+// applyExcludeBaseEdges adds a candidate filter rejecting results containing the network or broadcast
+// address of the root CIDR, if WithExcludeBaseEdges is set.
+func (o *findOptions) applyExcludeBaseEdges(rootCIDR *net.IPNet) {
+	ones, bits := rootCIDR.Mask.Size()
+	if !o.excludeEdges || bits != 8*net.IPv4len || ones >= reservedPrefixIPv4 {
+		return
+	}
+	network, broadcast := CIDRToIntRange(rootCIDR)
+	o.candidateFilters = append(o.candidateFilters, func(candidate *net.IPNet) bool {
+		first, last := CIDRToIntRange(candidate)
+		return first.Cmp(network) != 0 && last.Cmp(broadcast) != 0
+	})
+}
+
+// Boundary is the edge addresses of a CIDR, as returned by Boundaries. Broadcast is nil for IPv6, and
+// for IPv4 /31 and /32 prefixes, which don't have a broadcast address.
+type Boundary struct {
+	CIDR        *net.IPNet
+	Network     net.IP
+	FirstUsable net.IP
+	LastUsable  net.IP
+	Broadcast   net.IP
+}
+
+// Boundaries returns the network, first and last usable, and broadcast addresses of each of the cidrs,
+// in the same order, for generating rules which need the exact edges of each allocation. The usable
+// addresses follow UsableRange.
+func Boundaries(cidrs []*net.IPNet) []Boundary {
+	boundaries := make([]Boundary, len(cidrs))
+	for i, n := range cidrs {
+		if v4, ok := toIPv4Net(n); ok {
+			n = v4
+		}
+		first, last := CIDRToIntRange(n)
+		ones, bits := n.Mask.Size()
+
+		// CIDRToIntRange always fits within the address space, so these conversions can't fail
+		network, _ := IntToIP(first, bits)
+		boundaries[i] = Boundary{CIDR: n, Network: network}
+		boundaries[i].FirstUsable, boundaries[i].LastUsable = UsableRange(n)
+		if bits == 8*net.IPv4len && ones < reservedPrefixIPv4 {
+			boundaries[i].Broadcast, _ = IntToIP(last, bits)
+		}
+	}
+	return boundaries
+}
+
+// GatewayAddresses returns the conventional gateway address of each of the subnets, in the same order:
+// the address after the network address, which cloud platforms reserve for the subnet's router. For
+// IPv4 this is the first usable address, so the gateway of 10.0.1.0/24 is 10.0.1.1. Point-to-point
+// prefixes (/31 and /127) and single addresses have no conventional gateway, and get a nil address.
+func GatewayAddresses(subnets []*net.IPNet) []net.IP {
+	gateways := make([]net.IP, len(subnets))
+	for i, n := range subnets {
+		if v4, ok := toIPv4Net(n); ok {
+			n = v4
+		}
+		ones, bits := n.Mask.Size()
+		if ones >= bits-1 {
+			continue
+		}
+		first, _ := CIDRToIntRange(n)
+		// the address after the network address is within the subnet, so this conversion can't fail
+		gateways[i], _ = IntToIP(first.Add(first, big.NewInt(1)), bits)
+	}
+	return gateways
+}
+
+// IsUsableIP returns true if ip is within n and can be assigned to a host, meaning it isn't the
+// network or broadcast address of an IPv4 prefix shorter than /31.
+func IsUsableIP(n *net.IPNet, ip net.IP) bool {
+	if !ContainsIP(n, ip) {
+		return false
+	}
+	first, last := UsableRange(n)
+	value := IPToInt(ip)
+	return value.Cmp(IPToInt(first)) >= 0 && value.Cmp(IPToInt(last)) <= 0
+}
+
+// InferCIDR returns the CIDR whose usable range (see UsableRange) is exactly first to last, so
+// 10.0.0.1 to 10.0.0.254 infers 10.0.0.0/24. An ErrInvalidInputRanges error is returned if no single
+// CIDR has that usable range.
+func InferCIDR(first net.IP, last net.IP) (*net.IPNet, error) {
+	if first == nil || last == nil {
+		return nil, fmt.Errorf("%w: first and last addresses are required", ErrInvalidInputRanges)
+	}
+	bits := 8 * net.IPv6len
+	if first.To4() != nil && last.To4() != nil {
+		bits = 8 * net.IPv4len
+		first, last = first.To4(), last.To4()
+	} else if first.To4() != nil || last.To4() != nil {
+		return nil, fmt.Errorf("%w: range mixes IPv4 and IPv6 addresses", ErrInvalidInputRanges)
+	}
+
+	for ones := bits; ones >= 0; ones-- {
+		mask := net.CIDRMask(ones, bits)
+		candidate := &net.IPNet{IP: first.Mask(mask), Mask: mask}
+		usableFirst, usableLast := UsableRange(candidate)
+		if usableFirst.Equal(first) && usableLast.Equal(last) {
+			return candidate, nil
+		}
+	}
+	return nil, fmt.Errorf("%w: no CIDR has the usable range %s to %s", ErrInvalidInputRanges, first.String(), last.String())
+}
+
+// PrefixForHosts returns the longest prefix length (the smallest block) in an address space of the
+// given bit length (32 for IPv4, 128 for IPv6) with at least hosts usable addresses. The network
+// and broadcast addresses of IPv4 prefixes shorter than /31 aren't usable, so 254 hosts fit in a /24
+// while 255 hosts need a /23.
+func PrefixForHosts(hosts int, bits int) (int, error) {
+	if hosts < 1 {
+		return 0, fmt.Errorf("%w: host count must be at least 1, got %d", ErrInvalidInputRanges, hosts)
+	}
+	want := big.NewInt(int64(hosts))
+	for ones := bits; ones >= 0; ones-- {
+		if usableHostCount(ones, bits).Cmp(want) >= 0 {
+			return ones, nil
+		}
+	}
+	return 0, fmt.Errorf("%w: %d hosts don't fit in a %d bit address space", ErrNoAvailableCIDR, hosts, bits)
+}
+
+// UsableHosts returns the number of usable host addresses in n, as defined by UsableRange. A /24 has
+// 254, a point-to-point /31 has 2 and a /32 has 1.
+func UsableHosts(n *net.IPNet) *big.Int {
+	if v4, ok := toIPv4Net(n); ok {
+		n = v4
+	}
+	ones, bits := n.Mask.Size()
+	return usableHostCount(ones, bits)
+}
+
+// Waste returns how many usable addresses of the block are left over after requestedHosts, and that
+// as a fraction of the block's usable addresses. Rounding a request up to a power of two always
+// leaves some waste, e.g. 300 hosts need a /23 with 510 usable addresses, wasting 210 (41%). The
+// count saturates at math.MaxInt64 for IPv6 blocks too large to count in an int, and a block too
+// small for the request wastes nothing.
+func Waste(requestedHosts int, block *net.IPNet) (int, float64) {
+	usable := UsableHosts(block)
+	unused := new(big.Int).Sub(usable, big.NewInt(int64(requestedHosts)))
+	if unused.Sign() <= 0 {
+		return 0, 0
+	}
+	fraction, _ := new(big.Float).Quo(new(big.Float).SetInt(unused), new(big.Float).SetInt(usable)).Float64()
+	if !unused.IsInt64() {
+		return math.MaxInt64, fraction
+	}
+	return int(unused.Int64()), fraction
+}
+
+// usableHostCount returns the number of usable host addresses in a block with the given prefix length
+func usableHostCount(ones int, bits int) *big.Int {
+	mask := net.CIDRMask(ones, bits)
+	count := BlockSize(&mask)
+	if bits == 8*net.IPv4len && ones < reservedPrefixIPv4 {
+		count.Sub(count, big.NewInt(2))
+	}
+	return count
+}
@@ -0,0 +1,501 @@
+package cidr_test
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"net"
+	"testing"
+
+	"github.com/massdriver-cloud/cola/pkg/cidr"
+)
+
+func TestUsableRange(t *testing.T) {
+	type testData struct {
+		name      string
+		cidr      string
+		wantFirst string
+		wantLast  string
+	}
+	tests := []testData{
+		{
+			name:      "IPv4 /24",
+			cidr:      "10.0.0.0/24",
+			wantFirst: "10.0.0.1",
+			wantLast:  "10.0.0.254",
+		},
+		{
+			name:      "IPv4 /30",
+			cidr:      "10.0.0.0/30",
+			wantFirst: "10.0.0.1",
+			wantLast:  "10.0.0.2",
+		},
+		{
+			name:      "IPv4-mapped /120",
+			cidr:      "::ffff:10.0.0.0/120",
+			wantFirst: "10.0.0.1",
+			wantLast:  "10.0.0.254",
+		},
+		{
+			name:      "IPv6 /64",
+			cidr:      "2001:db8::/64",
+			wantFirst: "2001:db8::",
+			wantLast:  "2001:db8::ffff:ffff:ffff:ffff",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			_, n, _ := net.ParseCIDR(tc.cidr)
+			first, last := cidr.UsableRange(n)
+
+			if first.String() != tc.wantFirst {
+				t.Fatalf("want: %v, got: %v", tc.wantFirst, first.String())
+			}
+			if last.String() != tc.wantLast {
+				t.Fatalf("want: %v, got: %v", tc.wantLast, last.String())
+			}
+		})
+	}
+}
+
+func TestIsUsableIP(t *testing.T) {
+	type testData struct {
+		name string
+		cidr string
+		ip   string
+		want bool
+	}
+	tests := []testData{
+		{
+			name: "Network address",
+			cidr: "10.0.0.0/24",
+			ip:   "10.0.0.0",
+			want: false,
+		},
+		{
+			name: "Broadcast address",
+			cidr: "10.0.0.0/24",
+			ip:   "10.0.0.255",
+			want: false,
+		},
+		{
+			name: "First host",
+			cidr: "10.0.0.0/24",
+			ip:   "10.0.0.1",
+			want: true,
+		},
+		{
+			name: "Last host",
+			cidr: "10.0.0.0/24",
+			ip:   "10.0.0.254",
+			want: true,
+		},
+		{
+			name: "IPv4-mapped broadcast address",
+			cidr: "::ffff:10.0.0.0/120",
+			ip:   "10.0.0.255",
+			want: false,
+		},
+		{
+			name: "IPv4-mapped host",
+			cidr: "::ffff:10.0.0.0/120",
+			ip:   "10.0.0.1",
+			want: true,
+		},
+		{
+			name: "Outside range",
+			cidr: "10.0.0.0/24",
+			ip:   "10.0.1.1",
+			want: false,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			_, n, _ := net.ParseCIDR(tc.cidr)
+			got := cidr.IsUsableIP(n, net.ParseIP(tc.ip))
+
+			if got != tc.want {
+				t.Fatalf("want: %v, got: %v", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestInferCIDR(t *testing.T) {
+	type testData struct {
+		name      string
+		first     string
+		last      string
+		want      string
+		wantError error
+	}
+	tests := []testData{
+		{
+			name:  "IPv4 /24",
+			first: "10.0.0.1",
+			last:  "10.0.0.254",
+			want:  "10.0.0.0/24",
+		},
+		{
+			name:  "IPv4 /31",
+			first: "10.0.0.0",
+			last:  "10.0.0.1",
+			want:  "10.0.0.0/31",
+		},
+		{
+			name:  "IPv4 /32",
+			first: "10.0.0.7",
+			last:  "10.0.0.7",
+			want:  "10.0.0.7/32",
+		},
+		{
+			name:  "IPv6 /64",
+			first: "2001:db8::",
+			last:  "2001:db8::ffff:ffff:ffff:ffff",
+			want:  "2001:db8::/64",
+		},
+		{
+			name:      "Error range includes network address",
+			first:     "10.0.0.0",
+			last:      "10.0.0.254",
+			wantError: cidr.ErrInvalidInputRanges,
+		},
+		{
+			name:      "Error unaligned range",
+			first:     "10.0.0.1",
+			last:      "10.0.0.100",
+			wantError: cidr.ErrInvalidInputRanges,
+		},
+		{
+			name:      "Error mixed families",
+			first:     "10.0.0.1",
+			last:      "2001:db8::1",
+			wantError: cidr.ErrInvalidInputRanges,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := cidr.InferCIDR(net.ParseIP(tc.first), net.ParseIP(tc.last))
+			if tc.wantError != nil {
+				if !errors.Is(err, tc.wantError) {
+					t.Fatalf("Invalid error, want: %v, got %v,", tc.wantError, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %s,", err.Error())
+			}
+			if got.String() != tc.want {
+				t.Fatalf("want: %v, got: %v", tc.want, got.String())
+			}
+		})
+	}
+}
+
+func TestPrefixForHosts(t *testing.T) {
+	type testData struct {
+		name      string
+		hosts     int
+		bits      int
+		want      int
+		wantError error
+	}
+	tests := []testData{
+		{name: "Single host", hosts: 1, bits: 32, want: 32},
+		{name: "Point to point", hosts: 2, bits: 32, want: 31},
+		{name: "Three hosts", hosts: 3, bits: 32, want: 29},
+		{name: "Full /24", hosts: 254, bits: 32, want: 24},
+		{name: "Just over /24", hosts: 255, bits: 32, want: 23},
+		{name: "VPC sized", hosts: 60000, bits: 32, want: 16},
+		{name: "Full /16", hosts: 65534, bits: 32, want: 16},
+		{name: "Just over /16", hosts: 65535, bits: 32, want: 15},
+		{name: "IPv6 has no reserved addresses", hosts: 256, bits: 128, want: 120},
+		{name: "Error zero hosts", hosts: 0, bits: 32, wantError: cidr.ErrInvalidInputRanges},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := cidr.PrefixForHosts(tc.hosts, tc.bits)
+			if tc.wantError != nil {
+				if !errors.Is(err, tc.wantError) {
+					t.Fatalf("Invalid error, want: %v, got %v,", tc.wantError, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %s,", err.Error())
+			}
+			if got != tc.want {
+				t.Fatalf("want: %v, got: %v", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestSmallIPv4Prefixes(t *testing.T) {
+	type testData struct {
+		name       string
+		cidr       string
+		wantFirst  string
+		wantLast   string
+		wantHosts  []string
+		wantUnused []string
+	}
+	tests := []testData{
+		{
+			name:       "/30 reserves network and broadcast",
+			cidr:       "10.0.0.4/30",
+			wantFirst:  "10.0.0.5",
+			wantLast:   "10.0.0.6",
+			wantHosts:  []string{"10.0.0.5", "10.0.0.6"},
+			wantUnused: []string{"10.0.0.4", "10.0.0.7"},
+		},
+		{
+			name:      "/31 point to point uses both addresses",
+			cidr:      "10.0.0.4/31",
+			wantFirst: "10.0.0.4",
+			wantLast:  "10.0.0.5",
+			wantHosts: []string{"10.0.0.4", "10.0.0.5"},
+		},
+		{
+			name:      "/32 is a single host",
+			cidr:      "10.0.0.4/32",
+			wantFirst: "10.0.0.4",
+			wantLast:  "10.0.0.4",
+			wantHosts: []string{"10.0.0.4"},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			_, n, _ := net.ParseCIDR(tc.cidr)
+
+			first, last := cidr.UsableRange(n)
+			if first.String() != tc.wantFirst || last.String() != tc.wantLast {
+				t.Fatalf("want: %v-%v, got: %v-%v", tc.wantFirst, tc.wantLast, first, last)
+			}
+			if count := cidr.UsableHosts(n); count.Int64() != int64(len(tc.wantHosts)) {
+				t.Fatalf("want: %v, got: %v", len(tc.wantHosts), count)
+			}
+			hosts, err := cidr.Hosts(n)
+			if err != nil {
+				t.Fatalf("Unexpected error: %s,", err.Error())
+			}
+			if len(hosts) != len(tc.wantHosts) {
+				t.Fatalf("want: %v, got: %v", tc.wantHosts, hosts)
+			}
+			for i := range hosts {
+				if hosts[i].String() != tc.wantHosts[i] {
+					t.Fatalf("want: %v, got: %v", tc.wantHosts[i], hosts[i].String())
+				}
+				if !cidr.IsUsableIP(n, hosts[i]) {
+					t.Fatalf("want: %v usable, got: unusable", hosts[i])
+				}
+			}
+			for _, unused := range tc.wantUnused {
+				if cidr.IsUsableIP(n, net.ParseIP(unused)) {
+					t.Fatalf("want: %v unusable, got: usable", unused)
+				}
+			}
+
+			// the block can also be found in a larger root next to a used neighbour
+			ones, bits := n.Mask.Size()
+			_, root, _ := net.ParseCIDR("10.0.0.0/29")
+			_, neighbour, _ := net.ParseCIDR("10.0.0.0/30")
+			mask := net.CIDRMask(ones, bits)
+			found, err := cidr.FindAvailableCIDR(root, &mask, []*net.IPNet{neighbour})
+			if err != nil {
+				t.Fatalf("Unexpected error: %s,", err.Error())
+			}
+			if found.String() != n.String() {
+				t.Fatalf("want: %v, got: %v", n.String(), found.String())
+			}
+		})
+	}
+}
+
+func TestWaste(t *testing.T) {
+	type testData struct {
+		name         string
+		hosts        int
+		block        string
+		wantUnused   int
+		wantFraction string
+	}
+	tests := []testData{
+		{name: "300 hosts in a /23", hosts: 300, block: "10.0.0.0/23", wantUnused: 210, wantFraction: "0.41"},
+		{name: "Exact fit", hosts: 254, block: "10.0.0.0/24", wantUnused: 0, wantFraction: "0.00"},
+		{name: "Just over a /24", hosts: 255, block: "10.0.0.0/23", wantUnused: 255, wantFraction: "0.50"},
+		{name: "Single host in a /30", hosts: 1, block: "10.0.0.0/30", wantUnused: 1, wantFraction: "0.50"},
+		{name: "Point to point", hosts: 2, block: "10.0.0.0/31", wantUnused: 0, wantFraction: "0.00"},
+		{name: "Block too small", hosts: 300, block: "10.0.0.0/24", wantUnused: 0, wantFraction: "0.00"},
+		{name: "IPv6 /120", hosts: 200, block: "2001:db8::/120", wantUnused: 56, wantFraction: "0.22"},
+		{name: "IPv6 /64", hosts: 200, block: "2001:db8::/64", wantUnused: math.MaxInt64, wantFraction: "1.00"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			_, block, _ := net.ParseCIDR(tc.block)
+			unused, fraction := cidr.Waste(tc.hosts, block)
+			if unused != tc.wantUnused {
+				t.Fatalf("want: %v, got: %v", tc.wantUnused, unused)
+			}
+			if got := fmt.Sprintf("%.2f", fraction); got != tc.wantFraction {
+				t.Fatalf("want: %v, got: %v", tc.wantFraction, got)
+			}
+		})
+	}
+}
+
+func TestBoundaries(t *testing.T) {
+	type testData struct {
+		cidr            string
+		wantNetwork     string
+		wantFirstUsable string
+		wantLastUsable  string
+		wantBroadcast   string
+	}
+	tests := []testData{
+		{cidr: "10.0.1.0/24", wantNetwork: "10.0.1.0", wantFirstUsable: "10.0.1.1", wantLastUsable: "10.0.1.254", wantBroadcast: "10.0.1.255"},
+		{cidr: "10.0.0.4/30", wantNetwork: "10.0.0.4", wantFirstUsable: "10.0.0.5", wantLastUsable: "10.0.0.6", wantBroadcast: "10.0.0.7"},
+		{cidr: "10.0.0.4/31", wantNetwork: "10.0.0.4", wantFirstUsable: "10.0.0.4", wantLastUsable: "10.0.0.5", wantBroadcast: "<nil>"},
+		{cidr: "10.0.0.4/32", wantNetwork: "10.0.0.4", wantFirstUsable: "10.0.0.4", wantLastUsable: "10.0.0.4", wantBroadcast: "<nil>"},
+		{cidr: "::ffff:10.0.1.0/120", wantNetwork: "10.0.1.0", wantFirstUsable: "10.0.1.1", wantLastUsable: "10.0.1.254", wantBroadcast: "10.0.1.255"},
+		{cidr: "2001:db8::/64", wantNetwork: "2001:db8::", wantFirstUsable: "2001:db8::", wantLastUsable: "2001:db8::ffff:ffff:ffff:ffff", wantBroadcast: "<nil>"},
+	}
+
+	cidrs := make([]*net.IPNet, len(tests))
+	for i, tc := range tests {
+		_, n, _ := net.ParseCIDR(tc.cidr)
+		cidrs[i] = n
+	}
+	got := cidr.Boundaries(cidrs)
+	if len(got) != len(tests) {
+		t.Fatalf("want: %v, got: %v", len(tests), len(got))
+	}
+	for i, tc := range tests {
+		t.Run(tc.cidr, func(t *testing.T) {
+			b := got[i]
+			if b.Network.String() != tc.wantNetwork {
+				t.Fatalf("want: %v, got: %v", tc.wantNetwork, b.Network)
+			}
+			if b.FirstUsable.String() != tc.wantFirstUsable {
+				t.Fatalf("want: %v, got: %v", tc.wantFirstUsable, b.FirstUsable)
+			}
+			if b.LastUsable.String() != tc.wantLastUsable {
+				t.Fatalf("want: %v, got: %v", tc.wantLastUsable, b.LastUsable)
+			}
+			if b.Broadcast.String() != tc.wantBroadcast {
+				t.Fatalf("want: %v, got: %v", tc.wantBroadcast, b.Broadcast)
+			}
+		})
+	}
+}
+
+func TestGatewayAddresses(t *testing.T) {
+	type testData struct {
+		cidr string
+		want string
+	}
+	tests := []testData{
+		{cidr: "10.0.1.0/24", want: "10.0.1.1"},
+		{cidr: "10.0.0.4/30", want: "10.0.0.5"},
+		{cidr: "10.0.0.4/31", want: "<nil>"},
+		{cidr: "10.0.0.4/32", want: "<nil>"},
+		{cidr: "::ffff:10.0.2.0/120", want: "10.0.2.1"},
+		{cidr: "2001:db8::/64", want: "2001:db8::1"},
+		{cidr: "2001:db8::/127", want: "<nil>"},
+	}
+
+	subnets := make([]*net.IPNet, len(tests))
+	for i, tc := range tests {
+		_, n, _ := net.ParseCIDR(tc.cidr)
+		subnets[i] = n
+	}
+	got := cidr.GatewayAddresses(subnets)
+	if len(got) != len(tests) {
+		t.Fatalf("want: %v, got: %v", len(tests), len(got))
+	}
+	for i, tc := range tests {
+		t.Run(tc.cidr, func(t *testing.T) {
+			if got[i].String() != tc.want {
+				t.Fatalf("want: %v, got: %v", tc.want, got[i])
+			}
+		})
+	}
+}
+
+func TestFindAvailableCIDRWithExcludeBaseEdges(t *testing.T) {
+	type testData struct {
+		name        string
+		baseCIDR    string
+		usedCIDRs   []string
+		desiredMask net.IPMask
+		want        string
+		wantError   error
+	}
+	tests := []testData{
+		{
+			name:        "First block skipped",
+			baseCIDR:    "10.0.0.0/24",
+			usedCIDRs:   []string{},
+			desiredMask: net.CIDRMask(26, 32),
+			want:        "10.0.0.64/26",
+		},
+		{
+			name:        "Only free slots hold the edges",
+			baseCIDR:    "10.0.0.0/24",
+			usedCIDRs:   []string{"10.0.0.64/26", "10.0.0.128/26"},
+			desiredMask: net.CIDRMask(26, 32),
+			wantError:   cidr.ErrNoAvailableCIDR,
+		},
+		{
+			name:        "Only free slot holds the broadcast",
+			baseCIDR:    "10.0.0.0/24",
+			usedCIDRs:   []string{"10.0.0.0/25", "10.0.0.128/26"},
+			desiredMask: net.CIDRMask(26, 32),
+			wantError:   cidr.ErrNoAvailableCIDR,
+		},
+		{
+			name:        "Smaller block fits between the edges",
+			baseCIDR:    "10.0.0.0/24",
+			usedCIDRs:   []string{"10.0.0.0/25", "10.0.0.128/26"},
+			desiredMask: net.CIDRMask(27, 32),
+			want:        "10.0.0.192/27",
+		},
+		{
+			name:        "IPv6 unaffected",
+			baseCIDR:    "2001:db8::/64",
+			usedCIDRs:   []string{},
+			desiredMask: net.CIDRMask(66, 128),
+			want:        "2001:db8::/66",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			_, baseCIDR, _ := net.ParseCIDR(tc.baseCIDR)
+			usedCIDRs := make([]*net.IPNet, len(tc.usedCIDRs))
+			for i, usedCIDR := range tc.usedCIDRs {
+				_, usedCIDR, _ := net.ParseCIDR(usedCIDR)
+				usedCIDRs[i] = usedCIDR
+			}
+			got, err := cidr.FindAvailableCIDR(baseCIDR, &tc.desiredMask, usedCIDRs, cidr.WithExcludeBaseEdges())
+			if tc.wantError != nil {
+				if !errors.Is(err, tc.wantError) {
+					t.Fatalf("Invalid error, want: %v, got %v,", tc.wantError, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %s,", err.Error())
+			}
+			if got.String() != tc.want {
+				t.Fatalf("want: %v, got: %v", tc.want, got.String())
+			}
+		})
+	}
+}
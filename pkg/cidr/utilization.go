@@ -0,0 +1,39 @@
+package cidr
+
+import (
+	"fmt"
+	"math/big"
+	"net"
+)
+
+// Stats is how full a root CIDR is. Used and Free always add up to Total, and Percent is the
+// percentage of Total which is Used.
+type Stats struct {
+	Total   *big.Int
+	Used    *big.Int
+	Free    *big.Int
+	Percent float64
+}
+
+// Utilization returns how many addresses of the rootCIDR are within the usedCIDRs. Overlapping used
+// CIDRs are only counted once, and only the part of a used CIDR within the root is counted. Used CIDRs
+// from a different address family are ignored. See PackingEfficiency for how well the used CIDRs are
+// laid out rather than how much they use.
+func Utilization(rootCIDR *net.IPNet, usedCIDRs []*net.IPNet) (Stats, error) {
+	if _, bits := rootCIDR.Mask.Size(); bits == 0 {
+		return Stats{}, fmt.Errorf("%w: root CIDR %s has an invalid mask", ErrInvalidInputRanges, rootCIDR.String())
+	}
+	free, err := FreeSpace(rootCIDR, usedCIDRs)
+	if err != nil {
+		return Stats{}, err
+	}
+
+	stats := Stats{Total: BlockSize(&rootCIDR.Mask), Free: new(big.Int)}
+	for _, block := range free {
+		stats.Free.Add(stats.Free, BlockSize(&block.Mask))
+	}
+	stats.Used = new(big.Int).Sub(stats.Total, stats.Free)
+	percent, _ := new(big.Rat).SetFrac(new(big.Int).Mul(stats.Used, big.NewInt(100)), stats.Total).Float64()
+	stats.Percent = percent
+	return stats, nil
+}
@@ -0,0 +1,98 @@
+package cidr_test
+
+import (
+	"errors"
+	"net"
+	"testing"
+
+	"github.com/massdriver-cloud/cola/pkg/cidr"
+)
+
+func TestUtilization(t *testing.T) {
+	type testData struct {
+		name      string
+		baseCIDR  string
+		usedCIDRs []string
+		wantUsed  int64
+		wantFree  int64
+		percent   float64
+		wantError error
+	}
+	tests := []testData{
+		{
+			name:      "Empty",
+			baseCIDR:  "10.0.0.0/24",
+			usedCIDRs: []string{},
+			wantUsed:  0,
+			wantFree:  256,
+			percent:   0,
+		},
+		{
+			name:      "Overlapping used counted once",
+			baseCIDR:  "10.0.0.0/24",
+			usedCIDRs: []string{"10.0.0.0/25", "10.0.0.0/26", "10.0.0.64/27", "10.0.0.64/27"},
+			wantUsed:  128,
+			wantFree:  128,
+			percent:   50,
+		},
+		{
+			name:      "Used partly outside the root",
+			baseCIDR:  "10.0.0.0/24",
+			usedCIDRs: []string{"10.0.0.192/26", "10.0.1.0/24", "10.0.0.0/23"},
+			wantUsed:  256,
+			wantFree:  0,
+			percent:   100,
+		},
+		{
+			name:      "Fully saturated",
+			baseCIDR:  "10.0.0.0/24",
+			usedCIDRs: []string{"10.0.0.0/25", "10.0.0.128/26", "10.0.0.192/26"},
+			wantUsed:  256,
+			wantFree:  0,
+			percent:   100,
+		},
+		{
+			name:      "Other family ignored",
+			baseCIDR:  "10.0.0.0/24",
+			usedCIDRs: []string{"10.0.0.0/26", "2001:db8::/64"},
+			wantUsed:  64,
+			wantFree:  192,
+			percent:   25,
+		},
+		{
+			name:      "Error invalid root",
+			usedCIDRs: []string{},
+			wantError: cidr.ErrInvalidInputRanges,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			baseCIDR := &net.IPNet{}
+			if tc.baseCIDR != "" {
+				_, baseCIDR, _ = net.ParseCIDR(tc.baseCIDR)
+			}
+			usedCIDRs := make([]*net.IPNet, len(tc.usedCIDRs))
+			for i, usedCIDR := range tc.usedCIDRs {
+				_, usedCIDR, _ := net.ParseCIDR(usedCIDR)
+				usedCIDRs[i] = usedCIDR
+			}
+			got, err := cidr.Utilization(baseCIDR, usedCIDRs)
+			if tc.wantError != nil {
+				if !errors.Is(err, tc.wantError) {
+					t.Fatalf("Invalid error, want: %v, got %v,", tc.wantError, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %s,", err.Error())
+			}
+			if got.Total.Int64() != 256 || got.Used.Int64() != tc.wantUsed || got.Free.Int64() != tc.wantFree {
+				t.Fatalf("want: %v, got: %v", []int64{256, tc.wantUsed, tc.wantFree}, []int64{got.Total.Int64(), got.Used.Int64(), got.Free.Int64()})
+			}
+			if got.Percent != tc.percent {
+				t.Fatalf("want: %v, got: %v", tc.percent, got.Percent)
+			}
+		})
+	}
+}
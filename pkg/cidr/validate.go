@@ -0,0 +1,111 @@
+package cidr
+
+import (
+	"fmt"
+	"net"
+)
+
+// ValidateUsedCIDRs checks the usedCIDRs against the rootCIDR, returning the first problem found.
+// See ValidateUsedCIDRsAll for the checks performed.
+func ValidateUsedCIDRs(rootCIDR *net.IPNet, usedCIDRs []*net.IPNet) error {
+	if errs := ValidateUsedCIDRsAll(rootCIDR, usedCIDRs); len(errs) > 0 {
+		return errs[0]
+	}
+	return nil
+}
+
+// ValidateUsedCIDRsAll checks the usedCIDRs against the rootCIDR and returns every problem found:
+//   - a root CIDR with host bits set (ErrInvalidInputRanges)
+//   - used CIDRs with host bits set (ErrInvalidInputRanges)
+//   - used CIDRs which aren't within the root CIDR (ErrInvalidInputRanges)
+//   - each pair of used CIDRs which overlap each other (ErrOverlappingCIDRs)
+//
+// An empty slice means the usedCIDRs are valid.
+func ValidateUsedCIDRsAll(rootCIDR *net.IPNet, usedCIDRs []*net.IPNet) []error {
+	errs := []error{}
+
+	if !IsCanonical(rootCIDR) {
+		errs = append(errs, fmt.Errorf("%w: root CIDR %s has host bits set", ErrInvalidInputRanges, rootCIDR.String()))
+	}
+
+	for _, used := range usedCIDRs {
+		if !IsCanonical(used) {
+			errs = append(errs, fmt.Errorf("%w: used CIDR %s has host bits set", ErrInvalidInputRanges, used.String()))
+		}
+	}
+
+	for _, used := range usedCIDRs {
+		if !ContainsCIDR(rootCIDR, used) {
+			errs = append(errs, fmt.Errorf("%w: used CIDR %s is not within the root CIDR %s", ErrInvalidInputRanges, used.String(), rootCIDR.String()))
+		}
+	}
+
+	for _, pair := range FindOverlaps(usedCIDRs) {
+		errs = append(errs, overlapError(pair))
+	}
+
+	return errs
+}
+
+// FindOverlaps returns every pair of the cidrs which share any addresses, whether one contains the
+// other or they're identical, in the order they're given with the earlier CIDR of each pair first.
+// Every pair is compared, so this is quadratic in the number of cidrs.
+func FindOverlaps(cidrs []*net.IPNet) [][2]*net.IPNet {
+	overlaps := [][2]*net.IPNet{}
+	for i := range cidrs {
+		for j := i + 1; j < len(cidrs); j++ {
+			if OverlapsCIDR(cidrs[i], cidrs[j]) {
+				overlaps = append(overlaps, [2]*net.IPNet{cidrs[i], cidrs[j]})
+			}
+		}
+	}
+	return overlaps
+}
+
+// overlapError returns the ErrOverlappingCIDRs error for a pair of used CIDRs found by FindOverlaps
+func overlapError(pair [2]*net.IPNet) error {
+	return fmt.Errorf("%w: used CIDR %s overlaps %s", ErrOverlappingCIDRs, pair[0].String(), pair[1].String())
+}
+
+// WithValidateUsed makes FindAvailableCIDR check the used CIDRs for overlaps before searching, and
+// return an ErrOverlappingCIDRs error naming the first overlapping pair, rather than quietly searching
+// around the overlap. Overlapping used CIDRs usually mean the inventory they came from is inconsistent.
+func WithValidateUsed() FindOption {
+	return func(o *findOptions) {
+		o.validateUsed = true
+	}
+}
+
+// checkUsed returns an error for the first pair of usedCIDRs which overlap, if WithValidateUsed is set.
+func (o *findOptions) checkUsed(usedCIDRs []*net.IPNet) error {
+	if !o.validateUsed {
+		return nil
+	}
+	if overlaps := FindOverlaps(usedCIDRs); len(overlaps) > 0 {
+		return overlapError(overlaps[0])
+	}
+	return nil
+}
+
+// IsCanonical returns true if n has no host bits set, meaning its IP is the network address.
+func IsCanonical(n *net.IPNet) bool {
+	return n.IP.Equal(n.IP.Mask(n.Mask))
+}
+
+// EnforceMinSize returns the cidrs smaller than the minimum size policy, meaning those with a prefix
+// length longer than minPrefix, in the order given. With a minPrefix of 27, a /28 is returned while a
+// /27 or a /24 isn't. IPv4 CIDRs in their IPv4-mapped IPv6 form are measured as IPv4. Prefix lengths
+// of IPv4 and IPv6 aren't comparable, so the cidrs should all be of one address family.
+func EnforceMinSize(cidrs []*net.IPNet, minPrefix int) []*net.IPNet {
+	violations := []*net.IPNet{}
+	for _, n := range cidrs {
+		measured := n
+		if v4, ok := toIPv4Net(n); ok {
+			measured = v4
+		}
+		if ones, _ := measured.Mask.Size(); ones > minPrefix {
+			violations = append(violations, n)
+		}
+	}
+	return violations
+}
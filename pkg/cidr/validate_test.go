@@ -0,0 +1,232 @@
+package cidr_test
+
+import (
+	"errors"
+	"net"
+	"testing"
+
+	"github.com/massdriver-cloud/cola/pkg/cidr"
+)
+
+func TestValidateUsedCIDRsAll(t *testing.T) {
+	type testData struct {
+		name      string
+		baseCIDR  string
+		usedCIDRs []net.IPNet
+		want      []error
+	}
+	tests := []testData{
+		{
+			name:     "Valid",
+			baseCIDR: "10.0.0.0/16",
+			usedCIDRs: []net.IPNet{
+				{IP: net.IPv4(10, 0, 0, 0).To4(), Mask: net.CIDRMask(24, 32)},
+				{IP: net.IPv4(10, 0, 1, 0).To4(), Mask: net.CIDRMask(24, 32)},
+			},
+			want: []error{},
+		},
+		{
+			name:     "Multiple problems",
+			baseCIDR: "10.0.0.0/16",
+			usedCIDRs: []net.IPNet{
+				{IP: net.IPv4(10, 0, 0, 0).To4(), Mask: net.CIDRMask(20, 32)},
+				{IP: net.IPv4(10, 0, 1, 0).To4(), Mask: net.CIDRMask(24, 32)},
+				{IP: net.IPv4(10, 1, 0, 0).To4(), Mask: net.CIDRMask(24, 32)},
+				{IP: net.IPv4(10, 0, 32, 5).To4(), Mask: net.CIDRMask(24, 32)},
+				{IP: net.IPv4(10, 0, 2, 0).To4(), Mask: net.CIDRMask(24, 32)},
+			},
+			want: []error{
+				cidr.ErrInvalidInputRanges, // 10.0.32.5/24 has host bits set
+				cidr.ErrInvalidInputRanges, // 10.1.0.0/24 is outside the root
+				cidr.ErrOverlappingCIDRs,   // 10.0.0.0/20 overlaps 10.0.1.0/24
+				cidr.ErrOverlappingCIDRs,   // 10.0.0.0/20 overlaps 10.0.2.0/24
+			},
+		},
+		{
+			name:     "Root with host bits set",
+			baseCIDR: "10.0.5.0/16",
+			usedCIDRs: []net.IPNet{
+				{IP: net.IPv4(10, 0, 0, 0).To4(), Mask: net.CIDRMask(24, 32)},
+			},
+			want: []error{
+				cidr.ErrInvalidInputRanges,
+			},
+		},
+		{
+			name:     "Duplicates",
+			baseCIDR: "10.0.0.0/16",
+			usedCIDRs: []net.IPNet{
+				{IP: net.IPv4(10, 0, 0, 0).To4(), Mask: net.CIDRMask(24, 32)},
+				{IP: net.IPv4(10, 0, 0, 0).To4(), Mask: net.CIDRMask(24, 32)},
+			},
+			want: []error{
+				cidr.ErrOverlappingCIDRs,
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			// keep any host bits set in the base
+			baseIP, baseCIDR, _ := net.ParseCIDR(tc.baseCIDR)
+			baseCIDR.IP = baseIP.To4()
+			usedCIDRs := make([]*net.IPNet, len(tc.usedCIDRs))
+			for i := range tc.usedCIDRs {
+				usedCIDRs[i] = &tc.usedCIDRs[i]
+			}
+			got := cidr.ValidateUsedCIDRsAll(baseCIDR, usedCIDRs)
+
+			if len(got) != len(tc.want) {
+				t.Fatalf("want: %v, got: %v", tc.want, got)
+			}
+			for i := range got {
+				if !errors.Is(got[i], tc.want[i]) {
+					t.Fatalf("Invalid error, want: %v, got %v,", tc.want[i], got[i])
+				}
+			}
+		})
+	}
+}
+
+func TestValidateUsedCIDRs(t *testing.T) {
+	_, baseCIDR, _ := net.ParseCIDR("10.0.0.0/16")
+	_, used1, _ := net.ParseCIDR("10.0.0.0/24")
+	_, used2, _ := net.ParseCIDR("10.0.0.0/25")
+
+	if err := cidr.ValidateUsedCIDRs(baseCIDR, []*net.IPNet{used1}); err != nil {
+		t.Fatalf("Unexpected error: %s,", err.Error())
+	}
+	if err := cidr.ValidateUsedCIDRs(baseCIDR, []*net.IPNet{used1, used2}); !errors.Is(err, cidr.ErrOverlappingCIDRs) {
+		t.Fatalf("Invalid error, want: %v, got %v,", cidr.ErrOverlappingCIDRs, err)
+	}
+}
+
+func TestEnforceMinSize(t *testing.T) {
+	type testData struct {
+		name      string
+		cidrs     []string
+		minPrefix int
+		want      []string
+	}
+	tests := []testData{
+		{
+			name:      "Mixed sizes",
+			cidrs:     []string{"10.0.0.0/24", "10.0.1.0/28", "10.0.1.32/27", "10.0.2.0/32", "10.0.4.0/22"},
+			minPrefix: 27,
+			want:      []string{"10.0.1.0/28", "10.0.2.0/32"},
+		},
+		{
+			name:      "All compliant",
+			cidrs:     []string{"10.0.0.0/24", "10.0.1.0/27"},
+			minPrefix: 27,
+			want:      []string{},
+		},
+		{
+			name:      "IPv4-mapped measured as IPv4",
+			cidrs:     []string{"::ffff:10.0.0.0/120", "::ffff:10.0.1.0/124"},
+			minPrefix: 27,
+			want:      []string{"10.0.1.0/28"},
+		},
+		{
+			name:      "IPv6",
+			cidrs:     []string{"2001:db8::/64", "2001:db8:0:1::/80"},
+			minPrefix: 64,
+			want:      []string{"2001:db8:0:1::/80"},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			cidrs := make([]*net.IPNet, len(tc.cidrs))
+			for i, value := range tc.cidrs {
+				_, n, _ := net.ParseCIDR(value)
+				cidrs[i] = n
+			}
+			got := cidr.EnforceMinSize(cidrs, tc.minPrefix)
+			if len(got) != len(tc.want) {
+				t.Fatalf("want: %v, got: %v", tc.want, got)
+			}
+			for i := range got {
+				if cidr.CanonicalKey(got[i]) != tc.want[i] {
+					t.Fatalf("want: %v, got: %v", tc.want, got)
+				}
+			}
+		})
+	}
+}
+
+func TestFindOverlaps(t *testing.T) {
+	type testData struct {
+		name  string
+		cidrs []string
+		want  []string
+	}
+	tests := []testData{
+		{
+			name:  "Disjoint",
+			cidrs: []string{"10.0.0.0/24", "10.0.1.0/24", "10.0.2.0/23"},
+			want:  []string{},
+		},
+		{
+			name:  "Nested either way",
+			cidrs: []string{"10.0.0.0/25", "10.0.0.0/16", "10.0.5.0/24"},
+			want:  []string{"10.0.0.0/25 10.0.0.0/16", "10.0.0.0/16 10.0.5.0/24"},
+		},
+		{
+			name:  "Identical duplicates",
+			cidrs: []string{"10.0.1.0/24", "10.0.2.0/24", "10.0.1.0/24"},
+			want:  []string{"10.0.1.0/24 10.0.1.0/24"},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			cidrs := []*net.IPNet{}
+			for _, value := range tc.cidrs {
+				_, n, _ := net.ParseCIDR(value)
+				cidrs = append(cidrs, n)
+			}
+			got := cidr.FindOverlaps(cidrs)
+			if len(got) != len(tc.want) {
+				t.Fatalf("want: %v, got: %v", tc.want, got)
+			}
+			for i, pair := range got {
+				if pair[0].String()+" "+pair[1].String() != tc.want[i] {
+					t.Fatalf("want: %v, got: %v", tc.want[i], pair)
+				}
+			}
+		})
+	}
+}
+
+func TestFindAvailableCIDRWithValidateUsed(t *testing.T) {
+	_, root, _ := net.ParseCIDR("10.0.0.0/16")
+	mask := net.CIDRMask(24, 32)
+	usedCIDRs := []*net.IPNet{}
+	for _, value := range []string{"10.0.0.0/24", "10.0.1.0/24", "10.0.0.128/25"} {
+		_, n, _ := net.ParseCIDR(value)
+		usedCIDRs = append(usedCIDRs, n)
+	}
+
+	// without the option the overlap is searched around
+	got, err := cidr.FindAvailableCIDR(root, &mask, usedCIDRs)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s,", err.Error())
+	}
+	if got.String() != "10.0.2.0/24" {
+		t.Fatalf("want: %v, got: %v", "10.0.2.0/24", got.String())
+	}
+
+	_, err = cidr.FindAvailableCIDR(root, &mask, usedCIDRs, cidr.WithValidateUsed())
+	if !errors.Is(err, cidr.ErrOverlappingCIDRs) {
+		t.Fatalf("Invalid error, want: %v, got %v,", cidr.ErrOverlappingCIDRs, err)
+	}
+
+	got, err = cidr.FindAvailableCIDR(root, &mask, usedCIDRs[:2], cidr.WithValidateUsed())
+	if err != nil {
+		t.Fatalf("Unexpected error: %s,", err.Error())
+	}
+	if got.String() != "10.0.2.0/24" {
+		t.Fatalf("want: %v, got: %v", "10.0.2.0/24", got.String())
+	}
+}
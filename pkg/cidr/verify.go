@@ -0,0 +1,33 @@
+package cidr
+
+import (
+	"fmt"
+	"net"
+)
+
+// VerifyAllocation checks that result is a valid allocation from rootCIDR given the usedCIDRs: it must
+// be a canonical CIDR within the root, and must not match, contain or be contained by any used CIDR.
+// This is the same set of conditions FindAvailableCIDR guarantees for the CIDRs it returns.
+func VerifyAllocation(rootCIDR *net.IPNet, result *net.IPNet, usedCIDRs []*net.IPNet) error {
+	if !IsCanonical(result) {
+		return fmt.Errorf("%w: %s has host bits set", ErrInvalidInputRanges, result.String())
+	}
+	if !ContainsCIDR(rootCIDR, result) {
+		return fmt.Errorf("%w: %s is not within the root CIDR %s", ErrInvalidInputRanges, result.String(), rootCIDR.String())
+	}
+	for _, used := range usedCIDRs {
+		if !EqualCIDRs(result, used) && ContainsCIDR(result, used) {
+			return fmt.Errorf("%w: %s contains used CIDR %s", ErrOverlappingCIDRs, result.String(), used.String())
+		}
+		if OverlapsCIDR(result, used) {
+			return fmt.Errorf("%w: %s overlaps used CIDR %s", ErrOverlappingCIDRs, result.String(), used.String())
+		}
+	}
+	return nil
+}
+
+// IsAvailable returns true if candidate could be allocated from rootCIDR without colliding with any of
+// the usedCIDRs.
+func IsAvailable(rootCIDR *net.IPNet, candidate *net.IPNet, usedCIDRs []*net.IPNet) bool {
+	return VerifyAllocation(rootCIDR, candidate, usedCIDRs) == nil
+}
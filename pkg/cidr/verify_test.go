@@ -0,0 +1,128 @@
+package cidr_test
+
+import (
+	"errors"
+	"math/rand"
+	"net"
+	"testing"
+
+	"github.com/massdriver-cloud/cola/pkg/cidr"
+)
+
+func TestVerifyAllocation(t *testing.T) {
+	type testData struct {
+		name      string
+		baseCIDR  string
+		result    net.IPNet
+		usedCIDRs []string
+		wantError error
+	}
+	tests := []testData{
+		{
+			name:      "Valid",
+			baseCIDR:  "10.0.0.0/16",
+			result:    net.IPNet{IP: net.IPv4(10, 0, 1, 0).To4(), Mask: net.CIDRMask(24, 32)},
+			usedCIDRs: []string{"10.0.0.0/24"},
+			wantError: nil,
+		},
+		{
+			name:      "Host bits set",
+			baseCIDR:  "10.0.0.0/16",
+			result:    net.IPNet{IP: net.IPv4(10, 0, 1, 1).To4(), Mask: net.CIDRMask(24, 32)},
+			usedCIDRs: []string{},
+			wantError: cidr.ErrInvalidInputRanges,
+		},
+		{
+			name:      "Outside root",
+			baseCIDR:  "10.0.0.0/16",
+			result:    net.IPNet{IP: net.IPv4(10, 1, 0, 0).To4(), Mask: net.CIDRMask(24, 32)},
+			usedCIDRs: []string{},
+			wantError: cidr.ErrInvalidInputRanges,
+		},
+		{
+			name:      "Matches used",
+			baseCIDR:  "10.0.0.0/16",
+			result:    net.IPNet{IP: net.IPv4(10, 0, 0, 0).To4(), Mask: net.CIDRMask(24, 32)},
+			usedCIDRs: []string{"10.0.0.0/24"},
+			wantError: cidr.ErrOverlappingCIDRs,
+		},
+		{
+			name:      "Contains used",
+			baseCIDR:  "10.0.0.0/16",
+			result:    net.IPNet{IP: net.IPv4(10, 0, 0, 0).To4(), Mask: net.CIDRMask(20, 32)},
+			usedCIDRs: []string{"10.0.5.0/24"},
+			wantError: cidr.ErrOverlappingCIDRs,
+		},
+		{
+			name:      "Within used",
+			baseCIDR:  "10.0.0.0/16",
+			result:    net.IPNet{IP: net.IPv4(10, 0, 5, 0).To4(), Mask: net.CIDRMask(24, 32)},
+			usedCIDRs: []string{"10.0.0.0/20"},
+			wantError: cidr.ErrOverlappingCIDRs,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			_, baseCIDR, _ := net.ParseCIDR(tc.baseCIDR)
+			usedCIDRs := make([]*net.IPNet, len(tc.usedCIDRs))
+			for i, usedCIDR := range tc.usedCIDRs {
+				_, usedCIDR, _ := net.ParseCIDR(usedCIDR)
+				usedCIDRs[i] = usedCIDR
+			}
+			err := cidr.VerifyAllocation(baseCIDR, &tc.result, usedCIDRs)
+			if tc.wantError == nil && err != nil {
+				t.Fatalf("Unexpected error: %s,", err.Error())
+			}
+			if !errors.Is(err, tc.wantError) {
+				t.Fatalf("Invalid error, want: %v, got %v,", tc.wantError, err)
+			}
+			if got := cidr.IsAvailable(baseCIDR, &tc.result, usedCIDRs); got != (tc.wantError == nil) {
+				t.Fatalf("want: %v, got: %v", tc.wantError == nil, got)
+			}
+		})
+	}
+}
+
+// TestFindAvailableCIDRIdempotent randomly generates used sets and checks that every result from
+// FindAvailableCIDR is a valid allocation which is never returned again once it is used.
+func TestFindAvailableCIDRIdempotent(t *testing.T) {
+	random := rand.New(rand.NewSource(1)) //nolint:gosec // deterministic test data
+
+	_, baseCIDR, _ := net.ParseCIDR("10.0.0.0/16")
+	for iteration := 0; iteration < 200; iteration++ {
+		// scatter some random blocks (which may overlap each other) through the base
+		usedCIDRs := []*net.IPNet{}
+		for i := random.Intn(20); i > 0; i-- {
+			ones := 17 + random.Intn(12)
+			offset := random.Intn(1<<(ones-16)) << (32 - ones)
+			usedCIDRs = append(usedCIDRs, &net.IPNet{
+				IP:   net.IPv4(10, 0, byte(offset>>8), byte(offset)).To4(),
+				Mask: net.CIDRMask(ones, 32),
+			})
+		}
+		desiredMask := net.CIDRMask(17+random.Intn(12), 32)
+
+		for allocation := 0; allocation < 10; allocation++ {
+			got, err := cidr.FindAvailableCIDR(baseCIDR, &desiredMask, usedCIDRs)
+			if errors.Is(err, cidr.ErrNoAvailableCIDR) {
+				break
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %s, used: %v", err.Error(), usedCIDRs)
+			}
+			if verifyErr := cidr.VerifyAllocation(baseCIDR, got, usedCIDRs); verifyErr != nil {
+				t.Fatalf("Invalid allocation: %s, used: %v", verifyErr.Error(), usedCIDRs)
+			}
+
+			usedCIDRs = append(usedCIDRs, got)
+			if cidr.IsAvailable(baseCIDR, got, usedCIDRs) {
+				t.Fatalf("%v is still available after being used", got.String())
+			}
+			again, err := cidr.FindAvailableCIDR(baseCIDR, &desiredMask, usedCIDRs)
+			if err == nil && cidr.EqualCIDRs(again, got) {
+				t.Fatalf("%v was returned twice, used: %v", got.String(), usedCIDRs)
+			}
+		}
+	}
+}
@@ -0,0 +1,66 @@
+package cidr
+
+import (
+	"errors"
+	"fmt"
+	"net"
+)
+
+// WalkAvailable calls fn with every available CIDR of the desiredMask size within the rootCIDR, in
+// ascending address order, using the same rules (and FindOptions) as FindAvailableCIDR. The walk
+// stops early if fn returns false. Running out of available CIDRs is not an error, but invalid
+// inputs (such as a root CIDR within a used CIDR) are.
+func WalkAvailable(rootCIDR *net.IPNet, desiredMask *net.IPMask, usedCIDRs []*net.IPNet, fn func(*net.IPNet) bool, opts ...FindOption) error {
+	// the visitor runs as the final candidate filter, rejecting every candidate to keep the walk
+	// going until fn asks to stop
+	visit := func(o *findOptions) {
+		o.candidateFilters = append(o.candidateFilters, func(candidate *net.IPNet) bool {
+			return !fn(candidate)
+		})
+	}
+	walkOpts := make([]FindOption, 0, len(opts)+1)
+	walkOpts = append(walkOpts, opts...)
+	walkOpts = append(walkOpts, visit)
+
+	_, err := FindAvailableCIDR(rootCIDR, desiredMask, usedCIDRs, walkOpts...)
+	if err != nil && !errors.Is(err, ErrNoAvailableCIDR) {
+		return err
+	}
+	return nil
+}
+
+// FindCandidates returns up to limit available CIDRs of the desiredMask size within the rootCIDR, in
+// ascending address order, so the first is the CIDR FindAvailableCIDR returns with the same options.
+// An ErrNoAvailableCIDR error is returned if there are none.
+func FindCandidates(rootCIDR *net.IPNet, desiredMask *net.IPMask, usedCIDRs []*net.IPNet, limit int, opts ...FindOption) ([]*net.IPNet, error) {
+	if limit < 1 {
+		return nil, fmt.Errorf("%w: candidate limit must be at least 1, got %d", ErrInvalidInputRanges, limit)
+	}
+	candidates := []*net.IPNet{}
+	err := WalkAvailable(rootCIDR, desiredMask, usedCIDRs, func(candidate *net.IPNet) bool {
+		candidates = append(candidates, candidate)
+		return len(candidates) < limit
+	}, opts...)
+	if err != nil {
+		return nil, err
+	}
+	if len(candidates) == 0 {
+		return nil, fmt.Errorf("%w: searched all available ranges could not find space for requested mask", ErrNoAvailableCIDR)
+	}
+	return candidates, nil
+}
+
+// ListAvailableCIDRs returns every available CIDR of the desiredMask size within the rootCIDR, in
+// ascending address order, for capacity planning. A fully used root returns an empty list. Small masks
+// in a large root have a lot of available CIDRs, see RemainingCapacity to count them without listing.
+func ListAvailableCIDRs(rootCIDR *net.IPNet, desiredMask *net.IPMask, usedCIDRs []*net.IPNet) ([]*net.IPNet, error) {
+	available := []*net.IPNet{}
+	err := WalkAvailable(rootCIDR, desiredMask, usedCIDRs, func(candidate *net.IPNet) bool {
+		available = append(available, candidate)
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+	return available, nil
+}
@@ -0,0 +1,177 @@
+package cidr_test
+
+import (
+	"errors"
+	"net"
+	"testing"
+
+	"github.com/massdriver-cloud/cola/pkg/cidr"
+)
+
+func TestWalkAvailable(t *testing.T) {
+	type testData struct {
+		name        string
+		baseCIDR    string
+		usedCIDRs   []string
+		desiredMask net.IPMask
+		limit       int
+		want        []string
+	}
+	tests := []testData{
+		{
+			name:     "All free blocks",
+			baseCIDR: "10.0.0.0/22",
+			usedCIDRs: []string{
+				"10.0.0.0/24",
+				"10.0.2.128/25",
+			},
+			desiredMask: net.CIDRMask(24, 32),
+			want:        []string{"10.0.1.0/24", "10.0.3.0/24"},
+		},
+		{
+			name:        "Stops early",
+			baseCIDR:    "10.0.0.0/16",
+			usedCIDRs:   []string{"10.0.1.0/24"},
+			desiredMask: net.CIDRMask(24, 32),
+			limit:       3,
+			want:        []string{"10.0.0.0/24", "10.0.2.0/24", "10.0.3.0/24"},
+		},
+		{
+			name:        "Fully used",
+			baseCIDR:    "10.0.0.0/16",
+			usedCIDRs:   []string{"10.0.0.0/16"},
+			desiredMask: net.CIDRMask(24, 32),
+			want:        []string{},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			_, baseCIDR, _ := net.ParseCIDR(tc.baseCIDR)
+			usedCIDRs := make([]*net.IPNet, len(tc.usedCIDRs))
+			for i, usedCIDR := range tc.usedCIDRs {
+				_, usedCIDR, _ := net.ParseCIDR(usedCIDR)
+				usedCIDRs[i] = usedCIDR
+			}
+			got := []string{}
+			err := cidr.WalkAvailable(baseCIDR, &tc.desiredMask, usedCIDRs, func(available *net.IPNet) bool {
+				got = append(got, available.String())
+				return tc.limit == 0 || len(got) < tc.limit
+			})
+			if err != nil {
+				t.Fatalf("Unexpected error: %s,", err.Error())
+			}
+			if len(got) != len(tc.want) {
+				t.Fatalf("want: %v, got: %v", tc.want, got)
+			}
+			for i := range got {
+				if got[i] != tc.want[i] {
+					t.Fatalf("want: %v, got: %v", tc.want[i], got[i])
+				}
+			}
+		})
+	}
+}
+
+func TestFindCandidates(t *testing.T) {
+	_, baseCIDR, _ := net.ParseCIDR("10.0.0.0/22")
+	_, usedCIDR, _ := net.ParseCIDR("10.0.1.0/24")
+	mask := net.CIDRMask(24, 32)
+
+	got, err := cidr.FindCandidates(baseCIDR, &mask, []*net.IPNet{usedCIDR}, 2)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s,", err.Error())
+	}
+	want := []string{"10.0.0.0/24", "10.0.2.0/24"}
+	if len(got) != len(want) {
+		t.Fatalf("want: %v, got: %v", want, got)
+	}
+	for i := range got {
+		if got[i].String() != want[i] {
+			t.Fatalf("want: %v, got: %v", want[i], got[i])
+		}
+	}
+
+	_, err = cidr.FindCandidates(baseCIDR, &mask, []*net.IPNet{baseCIDR}, 2)
+	if !errors.Is(err, cidr.ErrNoAvailableCIDR) {
+		t.Fatalf("Invalid error, want: %v, got %v,", cidr.ErrNoAvailableCIDR, err)
+	}
+	_, err = cidr.FindCandidates(baseCIDR, &mask, []*net.IPNet{}, 0)
+	if !errors.Is(err, cidr.ErrInvalidInputRanges) {
+		t.Fatalf("Invalid error, want: %v, got %v,", cidr.ErrInvalidInputRanges, err)
+	}
+}
+
+func TestListAvailableCIDRs(t *testing.T) {
+	type testData struct {
+		name      string
+		baseCIDR  string
+		usedCIDRs []string
+		mask      int
+		want      []string
+	}
+	tests := []testData{
+		{
+			name:      "Used ranges remove slots",
+			baseCIDR:  "10.0.0.0/21",
+			usedCIDRs: []string{"10.0.0.0/23", "10.0.4.128/25", "10.0.6.0/24"},
+			mask:      24,
+			want:      []string{"10.0.2.0/24", "10.0.3.0/24", "10.0.5.0/24", "10.0.7.0/24"},
+		},
+		{
+			name:      "Fully used",
+			baseCIDR:  "10.0.0.0/22",
+			usedCIDRs: []string{"10.0.0.0/22"},
+			mask:      24,
+			want:      []string{},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			_, baseCIDR, _ := net.ParseCIDR(tc.baseCIDR)
+			usedCIDRs := make([]*net.IPNet, len(tc.usedCIDRs))
+			for i, usedCIDR := range tc.usedCIDRs {
+				_, usedCIDR, _ := net.ParseCIDR(usedCIDR)
+				usedCIDRs[i] = usedCIDR
+			}
+			mask := net.CIDRMask(tc.mask, 32)
+			got, err := cidr.ListAvailableCIDRs(baseCIDR, &mask, usedCIDRs)
+			if err != nil {
+				t.Fatalf("Unexpected error: %s,", err.Error())
+			}
+			if len(got) != len(tc.want) {
+				t.Fatalf("want: %v, got: %v", tc.want, got)
+			}
+			for i := range got {
+				if got[i].String() != tc.want[i] {
+					t.Fatalf("want: %v, got: %v", tc.want[i], got[i].String())
+				}
+			}
+		})
+	}
+}
+
+func TestListAvailableCIDRsMatchesCapacity(t *testing.T) {
+	_, baseCIDR, _ := net.ParseCIDR("10.0.0.0/16")
+	usedCIDRs := []*net.IPNet{}
+	for _, usedCIDR := range []string{"10.0.0.0/18", "10.0.64.0/20", "10.0.80.0/24", "10.0.200.7/32"} {
+		_, usedCIDR, _ := net.ParseCIDR(usedCIDR)
+		usedCIDRs = append(usedCIDRs, usedCIDR)
+	}
+
+	for _, prefix := range []int{16, 17, 20, 24, 26} {
+		mask := net.CIDRMask(prefix, 32)
+		got, err := cidr.ListAvailableCIDRs(baseCIDR, &mask, usedCIDRs)
+		if err != nil {
+			t.Fatalf("Unexpected error: %s,", err.Error())
+		}
+		want, err := cidr.RemainingCapacity(baseCIDR, &mask, usedCIDRs)
+		if err != nil {
+			t.Fatalf("Unexpected error: %s,", err.Error())
+		}
+		if int64(len(got)) != want.Int64() {
+			t.Fatalf("want: %v, got: %v", want, len(got))
+		}
+	}
+}
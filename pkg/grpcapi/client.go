@@ -0,0 +1,68 @@
+package grpcapi
+
+import (
+	"context"
+	"errors"
+	"io"
+
+	"google.golang.org/grpc"
+)
+
+// Client calls the Allocator service over a gRPC connection using the cola-json codec.
+type Client struct {
+	cc grpc.ClientConnInterface
+}
+
+// NewClient creates a Client for the connection.
+func NewClient(cc grpc.ClientConnInterface) *Client {
+	return &Client{cc: cc}
+}
+
+// Allocate reserves the first available CIDR with the prefix length.
+func (c *Client) Allocate(ctx context.Context, prefix int) (string, error) {
+	resp := new(AllocateResponse)
+	if err := c.cc.Invoke(ctx, methodName("Allocate"), &AllocateRequest{Prefix: prefix}, resp, grpc.ForceCodec(jsonCodec{})); err != nil {
+		return "", err
+	}
+	return resp.CIDR, nil
+}
+
+// Release marks a previously allocated CIDR as available again.
+func (c *Client) Release(ctx context.Context, cidr string) error {
+	return c.cc.Invoke(ctx, methodName("Release"), &ReleaseRequest{CIDR: cidr}, new(ReleaseResponse), grpc.ForceCodec(jsonCodec{}))
+}
+
+// FreeSpace returns the available CIDRs of the root CIDR, reading the whole stream.
+func (c *Client) FreeSpace(ctx context.Context) ([]string, error) {
+	stream, err := c.cc.NewStream(ctx, &serviceDesc.Streams[0], methodName("FreeSpace"), grpc.ForceCodec(jsonCodec{}))
+	if err != nil {
+		return nil, err
+	}
+	if sendErr := stream.SendMsg(&FreeSpaceRequest{}); sendErr != nil {
+		return nil, sendErr
+	}
+	if closeErr := stream.CloseSend(); closeErr != nil {
+		return nil, closeErr
+	}
+
+	free := []string{}
+	for {
+		resp := new(FreeSpaceResponse)
+		if recvErr := stream.RecvMsg(resp); recvErr != nil {
+			if errors.Is(recvErr, io.EOF) {
+				return free, nil
+			}
+			return nil, recvErr
+		}
+		free = append(free, resp.CIDR)
+	}
+}
+
+// Validate checks the used CIDRs against the root CIDR and returns the problems found.
+func (c *Client) Validate(ctx context.Context, used []string) ([]string, error) {
+	resp := new(ValidateResponse)
+	if err := c.cc.Invoke(ctx, methodName("Validate"), &ValidateRequest{Used: used}, resp, grpc.ForceCodec(jsonCodec{})); err != nil {
+		return nil, err
+	}
+	return resp.Problems, nil
+}
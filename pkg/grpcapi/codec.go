@@ -0,0 +1,26 @@
+package grpcapi
+
+import (
+	"encoding/json"
+)
+
+// codecName is the content-subtype the service is served with, so requests are sent with the
+// application/grpc+cola-json content type. It's specific to cola so registering the codec doesn't
+// replace a general purpose "json" codec used elsewhere in the same program.
+const codecName = "cola-json"
+
+// jsonCodec marshals the service messages as JSON. The messages are the plain Go structs in
+// messages.go, with CIDRs carried as strings like "10.0.0.0/16".
+type jsonCodec struct{}
+
+func (jsonCodec) Marshal(v interface{}) ([]byte, error) {
+	return json.Marshal(v)
+}
+
+func (jsonCodec) Unmarshal(data []byte, v interface{}) error {
+	return json.Unmarshal(data, v)
+}
+
+func (jsonCodec) Name() string {
+	return codecName
+}
@@ -0,0 +1,148 @@
+package grpcapi_test
+
+import (
+	"context"
+	"net"
+	"reflect"
+	"testing"
+
+	"github.com/massdriver-cloud/cola/pkg/cidr"
+	"github.com/massdriver-cloud/cola/pkg/grpcapi"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/encoding"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+func dialAllocator(t *testing.T, root string, used ...string) *grpcapi.Client {
+	t.Helper()
+	_, rootCIDR, _ := net.ParseCIDR(root)
+	usedCIDRs := []*net.IPNet{}
+	for _, value := range used {
+		_, n, _ := net.ParseCIDR(value)
+		usedCIDRs = append(usedCIDRs, n)
+	}
+
+	listener := bufconn.Listen(1024 * 1024)
+	server := grpc.NewServer()
+	grpcapi.Register(server, grpcapi.NewServer(cidr.NewAllocator(rootCIDR, usedCIDRs)))
+	go func() {
+		_ = server.Serve(listener)
+	}()
+	t.Cleanup(server.Stop)
+
+	conn, err := grpc.DialContext(context.Background(), "bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return listener.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s,", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return grpcapi.NewClient(conn)
+}
+
+func TestAllocateThenFreeSpace(t *testing.T) {
+	client := dialAllocator(t, "10.0.0.0/22", "10.0.0.0/24")
+	ctx := context.Background()
+
+	got, err := client.Allocate(ctx, 24)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s,", err)
+	}
+	if got != "10.0.1.0/24" {
+		t.Fatalf("want: %v, got: %v", "10.0.1.0/24", got)
+	}
+
+	free, err := client.FreeSpace(ctx)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s,", err)
+	}
+	want := []string{"10.0.2.0/23"}
+	if !reflect.DeepEqual(free, want) {
+		t.Fatalf("want: %v, got: %v", want, free)
+	}
+
+	err = client.Release(ctx, "10.0.1.0/24")
+	if err != nil {
+		t.Fatalf("Unexpected error: %s,", err)
+	}
+	free, err = client.FreeSpace(ctx)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s,", err)
+	}
+	want = []string{"10.0.1.0/24", "10.0.2.0/23"}
+	if !reflect.DeepEqual(free, want) {
+		t.Fatalf("want: %v, got: %v", want, free)
+	}
+}
+
+func TestErrorCodes(t *testing.T) {
+	type testData struct {
+		name string
+		call func(*grpcapi.Client) error
+		want codes.Code
+	}
+	tests := []testData{
+		{
+			name: "No space left",
+			call: func(c *grpcapi.Client) error {
+				_, err := c.Allocate(context.Background(), 23)
+				return err
+			},
+			want: codes.ResourceExhausted,
+		},
+		{
+			name: "Invalid prefix",
+			call: func(c *grpcapi.Client) error {
+				_, err := c.Allocate(context.Background(), 33)
+				return err
+			},
+			want: codes.InvalidArgument,
+		},
+		{
+			name: "Release unallocated",
+			call: func(c *grpcapi.Client) error {
+				return c.Release(context.Background(), "10.0.1.0/24")
+			},
+			want: codes.InvalidArgument,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			client := dialAllocator(t, "10.0.0.0/23", "10.0.0.0/24")
+			got := status.Code(test.call(client))
+			if got != test.want {
+				t.Fatalf("want: %v, got: %v", test.want, got)
+			}
+		})
+	}
+}
+
+func TestCodecRegistration(t *testing.T) {
+	dialAllocator(t, "10.0.0.0/16")
+
+	if encoding.GetCodec("cola-json") == nil {
+		t.Fatalf("want: cola-json codec registered, got: nil")
+	}
+	if encoding.GetCodec("json") != nil {
+		t.Fatalf("want: no json codec registered, got: %v", encoding.GetCodec("json"))
+	}
+}
+
+func TestValidate(t *testing.T) {
+	client := dialAllocator(t, "10.0.0.0/16")
+
+	problems, err := client.Validate(context.Background(), []string{"10.0.0.0/24", "10.0.0.0/25", "10.1.0.0/24"})
+	if err != nil {
+		t.Fatalf("Unexpected error: %s,", err)
+	}
+	if len(problems) != 2 {
+		t.Fatalf("want: %v, got: %v", 2, problems)
+	}
+}
@@ -0,0 +1,37 @@
+package grpcapi
+
+// AllocateRequest asks for the first available CIDR with the given prefix length.
+type AllocateRequest struct {
+	Prefix int `json:"prefix"`
+}
+
+// AllocateResponse carries the allocated CIDR.
+type AllocateResponse struct {
+	CIDR string `json:"cidr"`
+}
+
+// ReleaseRequest names a previously allocated CIDR to release.
+type ReleaseRequest struct {
+	CIDR string `json:"cidr"`
+}
+
+// ReleaseResponse is empty; a nil error means the CIDR was released.
+type ReleaseResponse struct{}
+
+// FreeSpaceRequest asks for the available CIDRs of the root CIDR.
+type FreeSpaceRequest struct{}
+
+// FreeSpaceResponse carries one available CIDR of the stream.
+type FreeSpaceResponse struct {
+	CIDR string `json:"cidr"`
+}
+
+// ValidateRequest carries the used CIDRs to check against the root CIDR.
+type ValidateRequest struct {
+	Used []string `json:"used"`
+}
+
+// ValidateResponse lists every problem found, empty when the used CIDRs are valid.
+type ValidateResponse struct {
+	Problems []string `json:"problems"`
+}
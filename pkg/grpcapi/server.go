@@ -0,0 +1,173 @@
+package grpcapi
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+
+	"github.com/massdriver-cloud/cola/pkg/cidr"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/encoding"
+	"google.golang.org/grpc/status"
+)
+
+// ServiceName is the fully qualified name of the Allocator service in allocator.proto.
+const ServiceName = "cola.v1.Allocator"
+
+// Server implements the Allocator service against a shared cidr.Allocator.
+type Server struct {
+	allocator *cidr.Allocator
+}
+
+// NewServer creates a Server backed by the allocator.
+func NewServer(allocator *cidr.Allocator) *Server {
+	return &Server{allocator: allocator}
+}
+
+// Register registers the Allocator service on the gRPC server, along with the cola-json codec its
+// messages are encoded with. Like any gRPC registration it must be done before the server starts.
+func Register(s *grpc.Server, srv *Server) {
+	encoding.RegisterCodec(jsonCodec{})
+	s.RegisterService(&serviceDesc, srv)
+}
+
+// Allocate reserves the first available CIDR with the requested prefix length.
+func (s *Server) Allocate(ctx context.Context, req *AllocateRequest) (*AllocateResponse, error) {
+	_, bits := s.allocator.Root().Mask.Size()
+	mask := net.CIDRMask(req.Prefix, bits)
+	if mask == nil {
+		return nil, status.Errorf(codes.InvalidArgument, "invalid prefix length /%d", req.Prefix)
+	}
+	result, err := s.allocator.Reserve(&mask)
+	if err != nil {
+		return nil, statusError(err)
+	}
+	return &AllocateResponse{CIDR: result.String()}, nil
+}
+
+// Release marks a previously allocated CIDR as available again.
+func (s *Server) Release(ctx context.Context, req *ReleaseRequest) (*ReleaseResponse, error) {
+	_, n, err := net.ParseCIDR(req.CIDR)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	if releaseErr := s.allocator.Release(n); releaseErr != nil {
+		return nil, statusError(releaseErr)
+	}
+	return &ReleaseResponse{}, nil
+}
+
+// FreeSpace streams the available CIDRs of the root CIDR in ascending address order.
+func (s *Server) FreeSpace(req *FreeSpaceRequest, stream grpc.ServerStream) error {
+	free, err := cidr.FreeSpace(s.allocator.Root(), s.allocator.Used())
+	if err != nil {
+		return statusError(err)
+	}
+	for _, block := range free {
+		if sendErr := stream.SendMsg(&FreeSpaceResponse{CIDR: block.String()}); sendErr != nil {
+			return sendErr
+		}
+	}
+	return nil
+}
+
+// Validate checks the used CIDRs against the root CIDR. Problems with the CIDRs are reported in the
+// response rather than as an error, so the caller gets all of them at once.
+func (s *Server) Validate(ctx context.Context, req *ValidateRequest) (*ValidateResponse, error) {
+	used := make([]*net.IPNet, 0, len(req.Used))
+	for _, value := range req.Used {
+		_, n, err := net.ParseCIDR(value)
+		if err != nil {
+			return nil, status.Error(codes.InvalidArgument, err.Error())
+		}
+		used = append(used, n)
+	}
+	problems := []string{}
+	for _, problem := range cidr.ValidateUsedCIDRsAll(s.allocator.Root(), used) {
+		problems = append(problems, problem.Error())
+	}
+	return &ValidateResponse{Problems: problems}, nil
+}
+
+// statusError maps the cidr package sentinel errors to gRPC status codes.
+func statusError(err error) error {
+	switch {
+	case errors.Is(err, cidr.ErrNoAvailableCIDR):
+		return status.Error(codes.ResourceExhausted, err.Error())
+	case errors.Is(err, cidr.ErrInvalidInputRanges), errors.Is(err, cidr.ErrOverlappingCIDRs):
+		return status.Error(codes.InvalidArgument, err.Error())
+	default:
+		return status.Error(codes.Internal, err.Error())
+	}
+}
+
+var serviceDesc = grpc.ServiceDesc{
+	ServiceName: ServiceName,
+	HandlerType: (*interface{})(nil),
+	Methods: []grpc.MethodDesc{
+		{MethodName: "Allocate", Handler: allocateHandler},
+		{MethodName: "Release", Handler: releaseHandler},
+		{MethodName: "Validate", Handler: validateHandler},
+	},
+	Streams: []grpc.StreamDesc{
+		{StreamName: "FreeSpace", Handler: freeSpaceHandler, ServerStreams: true},
+	},
+	Metadata: "allocator.proto",
+}
+
+func allocateHandler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	req := new(AllocateRequest)
+	if err := dec(req); err != nil {
+		return nil, err
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(*Server).Allocate(ctx, req.(*AllocateRequest))
+	}
+	if interceptor == nil {
+		return handler(ctx, req)
+	}
+	return interceptor(ctx, req, &grpc.UnaryServerInfo{Server: srv, FullMethod: methodName("Allocate")}, handler)
+}
+
+func releaseHandler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	req := new(ReleaseRequest)
+	if err := dec(req); err != nil {
+		return nil, err
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(*Server).Release(ctx, req.(*ReleaseRequest))
+	}
+	if interceptor == nil {
+		return handler(ctx, req)
+	}
+	return interceptor(ctx, req, &grpc.UnaryServerInfo{Server: srv, FullMethod: methodName("Release")}, handler)
+}
+
+func validateHandler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	req := new(ValidateRequest)
+	if err := dec(req); err != nil {
+		return nil, err
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(*Server).Validate(ctx, req.(*ValidateRequest))
+	}
+	if interceptor == nil {
+		return handler(ctx, req)
+	}
+	return interceptor(ctx, req, &grpc.UnaryServerInfo{Server: srv, FullMethod: methodName("Validate")}, handler)
+}
+
+func freeSpaceHandler(srv interface{}, stream grpc.ServerStream) error {
+	req := new(FreeSpaceRequest)
+	if err := stream.RecvMsg(req); err != nil {
+		return err
+	}
+	return srv.(*Server).FreeSpace(req, stream)
+}
+
+// methodName returns the full gRPC method name of a method of the service.
+func methodName(method string) string {
+	return fmt.Sprintf("/%s/%s", ServiceName, method)
+}
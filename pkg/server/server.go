@@ -0,0 +1,197 @@
+package server
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net"
+	"net/http"
+	"strconv"
+
+	"github.com/massdriver-cloud/cola/pkg/cidr"
+)
+
+const (
+	defaultLimit = 100
+	maxLimit     = 1000
+)
+
+// Server answers CIDR queries over HTTP for the root CIDR and used CIDRs of an allocator.
+type Server struct {
+	allocator *cidr.Allocator
+}
+
+// New creates a Server for the root CIDR and its used CIDRs.
+func New(root *net.IPNet, used []*net.IPNet) *Server {
+	return NewWithAllocator(cidr.NewAllocator(root, used))
+}
+
+// NewWithAllocator creates a Server which answers queries against the current state of the
+// allocator, so it reflects reservations made through other APIs sharing it.
+func NewWithAllocator(allocator *cidr.Allocator) *Server {
+	return &Server{allocator: allocator}
+}
+
+// Handler returns the http.Handler serving the API.
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/free", s.handleFree)
+	mux.HandleFunc("/find", s.handleFind)
+	return mux
+}
+
+// FreeResponse is the body returned by GET /free.
+type FreeResponse struct {
+	Blocks     []string `json:"blocks"`
+	NextCursor string   `json:"next_cursor,omitempty"`
+}
+
+// FindResponse is the body returned by GET /find. Primary is the recommended CIDR, the one find would
+// return, and Alternatives are other available CIDRs of the same size a client can offer instead.
+type FindResponse struct {
+	Primary      string   `json:"primary"`
+	Alternatives []string `json:"alternatives"`
+}
+
+// ErrorResponse is the body returned for any failed request.
+type ErrorResponse struct {
+	Error string `json:"error"`
+}
+
+// handleFree lists the available blocks of ?mask=N size, paginated with ?limit and ?cursor. The
+// cursor encodes the network address of the last block returned. Each page walks the tree from the
+// start and skips blocks up to the cursor, trading some repeated work for a stateless server.
+func (s *Server) handleFree(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, fmt.Errorf("method %s not allowed", r.Method))
+		return
+	}
+
+	query := r.URL.Query()
+	mask, err := s.parseMask(query.Get("mask"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	limit := defaultLimit
+	if value := query.Get("limit"); value != "" {
+		limit, err = strconv.Atoi(value)
+		if err != nil || limit < 1 || limit > maxLimit {
+			writeError(w, http.StatusBadRequest, fmt.Errorf("limit must be between 1 and %d", maxLimit))
+			return
+		}
+	}
+	var after *big.Int
+	if value := query.Get("cursor"); value != "" {
+		after, err = decodeCursor(value)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+	}
+
+	response := FreeResponse{Blocks: []string{}}
+	var last *net.IPNet
+	err = cidr.WalkAvailable(s.allocator.Root(), &mask, s.allocator.Used(), func(block *net.IPNet) bool {
+		if after != nil && cidr.IPToInt(block.IP).Cmp(after) <= 0 {
+			return true
+		}
+		if len(response.Blocks) == limit {
+			// there is at least one more block, so hand out a cursor for the next page
+			response.NextCursor = encodeCursor(last)
+			return false
+		}
+		response.Blocks = append(response.Blocks, block.String())
+		last = block
+		return true
+	})
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, response)
+}
+
+// handleFind recommends an available block of ?mask=N size, along with up to ?alternatives=N other
+// available blocks of the same size in ascending order. Nothing is reserved. If no block is available
+// the response is 409 Conflict.
+func (s *Server) handleFind(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, fmt.Errorf("method %s not allowed", r.Method))
+		return
+	}
+
+	query := r.URL.Query()
+	mask, err := s.parseMask(query.Get("mask"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	alternatives := 0
+	if value := query.Get("alternatives"); value != "" {
+		alternatives, err = strconv.Atoi(value)
+		if err != nil || alternatives < 0 || alternatives > maxLimit {
+			writeError(w, http.StatusBadRequest, fmt.Errorf("alternatives must be between 0 and %d", maxLimit))
+			return
+		}
+	}
+
+	candidates, err := cidr.FindCandidates(s.allocator.Root(), &mask, s.allocator.Used(), alternatives+1)
+	if errors.Is(err, cidr.ErrNoAvailableCIDR) {
+		writeError(w, http.StatusConflict, err)
+		return
+	}
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	response := FindResponse{Primary: candidates[0].String(), Alternatives: []string{}}
+	for _, candidate := range candidates[1:] {
+		response.Alternatives = append(response.Alternatives, candidate.String())
+	}
+	writeJSON(w, http.StatusOK, response)
+}
+
+// parseMask parses a prefix length for the root CIDR's address family
+func (s *Server) parseMask(value string) (net.IPMask, error) {
+	ones, err := strconv.Atoi(value)
+	if err != nil {
+		return nil, fmt.Errorf("invalid mask %q", value)
+	}
+	_, bits := s.allocator.Root().Mask.Size()
+	mask := net.CIDRMask(ones, bits)
+	if mask == nil {
+		return nil, fmt.Errorf("invalid mask %q", value)
+	}
+	return mask, nil
+}
+
+func encodeCursor(block *net.IPNet) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(block.IP.String()))
+}
+
+func decodeCursor(cursor string) (*big.Int, error) {
+	decoded, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return nil, fmt.Errorf("invalid cursor %q", cursor)
+	}
+	ip := net.ParseIP(string(decoded))
+	if ip == nil {
+		return nil, fmt.Errorf("invalid cursor %q", cursor)
+	}
+	return cidr.IPToInt(ip), nil
+}
+
+func writeError(w http.ResponseWriter, status int, err error) {
+	writeJSON(w, status, ErrorResponse{Error: err.Error()})
+}
+
+func writeJSON(w http.ResponseWriter, status int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(body)
+}
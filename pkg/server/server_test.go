@@ -0,0 +1,162 @@
+package server_test
+
+import (
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/massdriver-cloud/cola/pkg/cidr"
+	"github.com/massdriver-cloud/cola/pkg/server"
+)
+
+func TestFreePagination(t *testing.T) {
+	_, root, _ := net.ParseCIDR("10.0.0.0/20")
+	usedCIDRs := []*net.IPNet{}
+	for _, used := range []string{"10.0.0.0/24", "10.0.5.0/24", "10.0.9.128/25"} {
+		_, usedCIDR, _ := net.ParseCIDR(used)
+		usedCIDRs = append(usedCIDRs, usedCIDR)
+	}
+
+	ts := httptest.NewServer(server.New(root, usedCIDRs).Handler())
+	defer ts.Close()
+
+	want := []string{}
+	mask := net.CIDRMask(24, 32)
+	_ = cidr.WalkAvailable(root, &mask, usedCIDRs, func(block *net.IPNet) bool {
+		want = append(want, block.String())
+		return true
+	})
+
+	got := []string{}
+	pages := 0
+	cursor := ""
+	for {
+		url := ts.URL + "/free?mask=24&limit=5"
+		if cursor != "" {
+			url += "&cursor=" + cursor
+		}
+		resp, err := http.Get(url) //nolint:gosec // test server URL
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err.Error())
+		}
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("want status: %v, got: %v", http.StatusOK, resp.StatusCode)
+		}
+		page := server.FreeResponse{}
+		err = json.NewDecoder(resp.Body).Decode(&page)
+		resp.Body.Close()
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err.Error())
+		}
+
+		pages++
+		got = append(got, page.Blocks...)
+		if page.NextCursor == "" {
+			break
+		}
+		if len(page.Blocks) != 5 {
+			t.Fatalf("want full page of 5 blocks before the last page, got: %v", page.Blocks)
+		}
+		cursor = page.NextCursor
+	}
+
+	if len(want) != 13 {
+		t.Fatalf("want 13 free blocks, got: %v", want)
+	}
+	if pages != 3 {
+		t.Fatalf("want: 3 pages, got: %v", pages)
+	}
+	if len(got) != len(want) {
+		t.Fatalf("want: %v, got: %v", want, got)
+	}
+	for i := range got {
+		if got[i] != want[i] {
+			t.Fatalf("want: %v, got: %v", want[i], got[i])
+		}
+	}
+}
+
+func TestFreeInvalidRequest(t *testing.T) {
+	_, root, _ := net.ParseCIDR("10.0.0.0/16")
+	ts := httptest.NewServer(server.New(root, []*net.IPNet{}).Handler())
+	defer ts.Close()
+
+	for _, query := range []string{"", "?mask=abc", "?mask=24&limit=0", "?mask=24&cursor=%21"} {
+		resp, err := http.Get(ts.URL + "/free" + query) //nolint:gosec // test server URL
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err.Error())
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusBadRequest {
+			t.Fatalf("query %q want status: %v, got: %v", query, http.StatusBadRequest, resp.StatusCode)
+		}
+	}
+}
+
+func TestFindAlternatives(t *testing.T) {
+	_, root, _ := net.ParseCIDR("10.0.0.0/20")
+	_, usedCIDR, _ := net.ParseCIDR("10.0.0.0/24")
+	ts := httptest.NewServer(server.New(root, []*net.IPNet{usedCIDR}).Handler())
+	defer ts.Close()
+
+	resp, err := http.Get(ts.URL + "/find?mask=24&alternatives=3") //nolint:gosec // test server URL
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err.Error())
+	}
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("want status: %v, got: %v", http.StatusOK, resp.StatusCode)
+	}
+	got := server.FindResponse{}
+	err = json.NewDecoder(resp.Body).Decode(&got)
+	resp.Body.Close()
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err.Error())
+	}
+
+	mask := net.CIDRMask(24, 32)
+	primary, err := cidr.FindAvailableCIDR(root, &mask, []*net.IPNet{usedCIDR})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err.Error())
+	}
+	if got.Primary != primary.String() {
+		t.Fatalf("want: %v, got: %v", primary, got.Primary)
+	}
+	if len(got.Alternatives) != 3 {
+		t.Fatalf("want 3 alternatives, got: %v", got.Alternatives)
+	}
+	seen := map[string]bool{got.Primary: true}
+	for _, alternative := range got.Alternatives {
+		if seen[alternative] {
+			t.Fatalf("want distinct blocks, got: %v and %v", got.Primary, got.Alternatives)
+		}
+		seen[alternative] = true
+		_, block, parseErr := net.ParseCIDR(alternative)
+		if parseErr != nil || cidr.OverlapsCIDR(block, usedCIDR) {
+			t.Fatalf("want an available /24, got: %v", alternative)
+		}
+	}
+}
+
+func TestFindStatus(t *testing.T) {
+	_, root, _ := net.ParseCIDR("10.0.0.0/24")
+	ts := httptest.NewServer(server.New(root, []*net.IPNet{root}).Handler())
+	defer ts.Close()
+
+	for query, want := range map[string]int{
+		"?mask=26":                  http.StatusConflict,
+		"?mask=abc":                 http.StatusBadRequest,
+		"?mask=26&alternatives=-1":  http.StatusBadRequest,
+		"?mask=26&alternatives=abc": http.StatusBadRequest,
+	} {
+		resp, err := http.Get(ts.URL + "/find" + query) //nolint:gosec // test server URL
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err.Error())
+		}
+		resp.Body.Close()
+		if resp.StatusCode != want {
+			t.Fatalf("query %q want status: %v, got: %v", query, want, resp.StatusCode)
+		}
+	}
+}